@@ -0,0 +1,367 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxUsernameLength is the maximum number of characters of a username that is accepted by
+// NormalizeUsername
+const MaxUsernameLength = 128
+
+var (
+	// ErrAccountInvalid is returned if an account can not be normalized, i. e. because it is an email
+	// address with an empty local part or an invalid domain
+	ErrAccountInvalid = errors.New("not a valid account")
+
+	// ErrPhoneNumberInvalid is returned if a phone number is not a valid number in international
+	// E.164 format
+	ErrPhoneNumberInvalid = errors.New("not a valid E.164 phone number")
+
+	// ErrUsernameInvalid is returned if a username can not be looked up in the HIBP API
+	ErrUsernameInvalid = errors.New("not a valid username")
+)
+
+// accountOpts holds the options for NormalizeAccount
+type accountOpts struct {
+	canonicalize bool // Apply the provider-specific canonicalization of email addresses
+}
+
+// AccountOption is a function that sets options for NormalizeAccount
+type AccountOption func(*accountOpts)
+
+// WithCanonicalization applies provider-specific canonicalization to email addresses, so that the
+// different spellings of the same mailbox normalize to the same account. For Gmail, the dots of the
+// local part and the plus tag are removed and googlemail.com is replaced with gmail.com. For
+// Outlook, iCloud, Fastmail and Proton, the plus tag is removed. The local part of canonicalized
+// addresses is lower cased. Other email addresses are not changed.
+//
+// Canonicalized accounts are meant to collapse duplicate lookups, the HIBP API itself does not
+// canonicalize accounts, so a breach might be listed for only one of the spellings.
+func WithCanonicalization() AccountOption {
+	return func(o *accountOpts) {
+		o.canonicalize = true
+	}
+}
+
+// accountProvider describes the canonicalization rules of an email provider
+type accountProvider struct {
+	domain     string // Canonical domain of the provider
+	removeDots bool   // Whether the dots of the local part are ignored by the provider
+}
+
+// accountProviders maps the domains of email providers with plus addressing to their
+// canonicalization rules
+var accountProviders = map[string]accountProvider{
+	"gmail.com":      {"gmail.com", true},
+	"googlemail.com": {"gmail.com", true},
+	"outlook.com":    {"outlook.com", false},
+	"hotmail.com":    {"hotmail.com", false},
+	"live.com":       {"live.com", false},
+	"icloud.com":     {"icloud.com", false},
+	"me.com":         {"me.com", false},
+	"mac.com":        {"mac.com", false},
+	"fastmail.com":   {"fastmail.com", false},
+	"protonmail.com": {"protonmail.com", false},
+	"proton.me":      {"proton.me", false},
+}
+
+// NormalizeAccount normalizes the given account for a lookup in the HIBP API. Spaces around the
+// account are trimmed. If the account is an email address, the domain is lower cased and
+// internationalized domain names are converted to punycode. The local part is kept as it is,
+// unless WithCanonicalization is set. An "@" in the local part is only accepted if the local part
+// is quoted. Accounts without an "@", like usernames, are only trimmed.
+func NormalizeAccount(account string, options ...AccountOption) (string, error) {
+	var opts accountOpts
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+		option(&opts)
+	}
+
+	account = strings.TrimSpace(account)
+	if account == "" {
+		return "", ErrNoAccountID
+	}
+	if !utf8.ValidString(account) {
+		return "", fmt.Errorf("%w: invalid UTF-8", ErrAccountInvalid)
+	}
+	for _, r := range account {
+		if unicode.IsControl(r) {
+			return "", fmt.Errorf("%w: control character in account", ErrAccountInvalid)
+		}
+	}
+	idx := strings.LastIndex(account, "@")
+	if idx < 0 {
+		return account, nil
+	}
+
+	local, domain := account[:idx], account[idx+1:]
+	if local == "" || len(local) > 64 || strings.ContainsFunc(local, unicode.IsSpace) {
+		return "", fmt.Errorf("%w: invalid local part %q", ErrAccountInvalid, local)
+	}
+	// An "@" is only allowed in a quoted local part, i. e. "toni@home"@domain.tld
+	quoted := len(local) >= 2 && strings.HasPrefix(local, `"`) && strings.HasSuffix(local, `"`)
+	if !quoted && strings.Contains(local, "@") {
+		return "", fmt.Errorf("%w: invalid local part %q", ErrAccountInvalid, local)
+	}
+	domain, err := normalizeDomain(domain)
+	if err != nil {
+		return "", err
+	}
+	if opts.canonicalize {
+		local, domain = canonicalizeAccount(local, domain)
+	}
+	return local + "@" + domain, nil
+}
+
+// canonicalizeAccount applies the canonicalization rules of the provider of the given domain to
+// the given local part
+func canonicalizeAccount(local, domain string) (string, string) {
+	provider, ok := accountProviders[domain]
+	if !ok {
+		return local, domain
+	}
+	if idx := strings.Index(local, "+"); idx > 0 {
+		local = local[:idx]
+	}
+	if provider.removeDots {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	return strings.ToLower(local), provider.domain
+}
+
+// escapeAccount returns the given account escaped for use as path parameter. Plus signs are escaped
+// as well, so that they can not be mistaken for encoded spaces
+func escapeAccount(account string) string {
+	return strings.ReplaceAll(url.PathEscape(account), "+", "%2B")
+}
+
+// normalizeDomain lower cases the given domain name and converts internationalized labels to
+// punycode. It fails if a label is empty, too long or contains characters that are not allowed in
+// host names
+func normalizeDomain(domain string) (string, error) {
+	// The ideographic full stops are label separators of internationalized domain names
+	domain = strings.NewReplacer("。", ".", "．", ".", "｡", ".").Replace(domain)
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if domain == "" {
+		return "", fmt.Errorf("%w: empty domain", ErrAccountInvalid)
+	}
+
+	labels := strings.Split(domain, ".")
+	for i, label := range labels {
+		if !isASCII(label) {
+			encoded, err := punycodeEncode(label)
+			if err != nil {
+				return "", fmt.Errorf("%w: %s", ErrAccountInvalid, err)
+			}
+			label = "xn--" + encoded
+			labels[i] = label
+		}
+		if !validLabel(label) {
+			return "", fmt.Errorf("%w: invalid domain label %q", ErrAccountInvalid, label)
+		}
+	}
+	domain = strings.Join(labels, ".")
+	if len(domain) > 253 {
+		return "", fmt.Errorf("%w: domain too long", ErrAccountInvalid)
+	}
+	return domain, nil
+}
+
+// isASCII checks if the given string only consists of ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// validLabel checks if the given domain label is a valid host name label: 1 to 63 letters, digits
+// and hyphens, not starting or ending with a hyphen
+func validLabel(label string) bool {
+	if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(label); i++ {
+		c := label[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// Parameters of the punycode algorithm as defined in RFC 3492, section 5
+const (
+	punycodeBase        = 36
+	punycodeTMin        = 1
+	punycodeTMax        = 26
+	punycodeSkew        = 38
+	punycodeDamp        = 700
+	punycodeInitialBias = 72
+	punycodeInitialN    = 128
+	punycodeMaxInt      = 1<<31 - 1
+)
+
+// punycodeEncode encodes the given label with the punycode algorithm of RFC 3492, section 6.3
+func punycodeEncode(label string) (string, error) {
+	runes := []rune(label)
+	out := make([]byte, 0, len(label)+8)
+	for _, r := range runes {
+		if r < utf8.RuneSelf {
+			out = append(out, byte(r))
+		}
+	}
+	basic := len(out)
+	handled := basic
+	if basic > 0 {
+		out = append(out, '-')
+	}
+
+	n, delta, bias := punycodeInitialN, 0, punycodeInitialBias
+	for handled < len(runes) {
+		next := punycodeMaxInt
+		for _, r := range runes {
+			if int(r) >= n && int(r) < next {
+				next = int(r)
+			}
+		}
+		if next-n > (punycodeMaxInt-delta)/(handled+1) {
+			return "", errors.New("punycode overflow")
+		}
+		delta += (next - n) * (handled + 1)
+		n = next
+
+		for _, r := range runes {
+			if int(r) < n {
+				delta++
+				if delta == punycodeMaxInt {
+					return "", errors.New("punycode overflow")
+				}
+			}
+			if int(r) != n {
+				continue
+			}
+			q := delta
+			for k := punycodeBase; ; k += punycodeBase {
+				t := k - bias
+				if t < punycodeTMin {
+					t = punycodeTMin
+				} else if t > punycodeTMax {
+					t = punycodeTMax
+				}
+				if q < t {
+					break
+				}
+				out = append(out, punycodeDigit(t+(q-t)%(punycodeBase-t)))
+				q = (q - t) / (punycodeBase - t)
+			}
+			out = append(out, punycodeDigit(q))
+			bias = punycodeAdapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return string(out), nil
+}
+
+// punycodeAdapt is the bias adaptation function of RFC 3492, section 6.1
+func punycodeAdapt(delta, points int, first bool) int {
+	if first {
+		delta /= punycodeDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / points
+	k := 0
+	for delta > ((punycodeBase-punycodeTMin)*punycodeTMax)/2 {
+		delta /= punycodeBase - punycodeTMin
+		k += punycodeBase
+	}
+	return k + (punycodeBase-punycodeTMin+1)*delta/(delta+punycodeSkew)
+}
+
+// punycodeDigit returns the lower case basic code point for the given punycode digit
+func punycodeDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
+
+// NormalizePhoneNumber normalizes the given phone number in international E.164 format to the
+// format the HIBP API indexes phone numbers with: the country code and the subscriber number as
+// digits only, without the leading "+". Spaces, hyphens, dots and parentheses are removed and the
+// "00" international call prefix is accepted instead of "+". Numbers in national format, without
+// a country code, are not supported, since the country can not be determined.
+func NormalizePhoneNumber(number string) (string, error) {
+	number = strings.TrimSpace(number)
+	if number == "" {
+		return "", ErrNoAccountID
+	}
+	switch {
+	case strings.HasPrefix(number, "+"):
+		number = number[1:]
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	default:
+		return "", fmt.Errorf("%w: number without country code", ErrPhoneNumberInvalid)
+	}
+
+	digits := make([]byte, 0, len(number))
+	for _, r := range number {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = append(digits, byte(r))
+		case r == ' ', r == '-', r == '.', r == '(', r == ')':
+		default:
+			return "", fmt.Errorf("%w: invalid character %q", ErrPhoneNumberInvalid, r)
+		}
+	}
+	if len(digits) < 8 || len(digits) > 15 {
+		return "", fmt.Errorf("%w: %d digits, expected 8 to 15", ErrPhoneNumberInvalid, len(digits))
+	}
+	if digits[0] == '0' {
+		return "", fmt.Errorf("%w: country code starts with 0", ErrPhoneNumberInvalid)
+	}
+	return string(digits), nil
+}
+
+// NormalizeUsername validates the given username for a lookup in the HIBP API and trims the spaces
+// around it. Usernames must not contain an "@", since they would be looked up as email address,
+// whitespace or control characters and are limited to MaxUsernameLength characters
+func NormalizeUsername(username string) (string, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return "", ErrNoAccountID
+	}
+	if !utf8.ValidString(username) {
+		return "", fmt.Errorf("%w: invalid UTF-8", ErrUsernameInvalid)
+	}
+	if utf8.RuneCountInString(username) > MaxUsernameLength {
+		return "", fmt.Errorf("%w: longer than %d characters", ErrUsernameInvalid, MaxUsernameLength)
+	}
+	for _, r := range username {
+		switch {
+		case r == '@':
+			return "", fmt.Errorf("%w: username looks like an email address", ErrUsernameInvalid)
+		case unicode.IsSpace(r), unicode.IsControl(r):
+			return "", fmt.Errorf("%w: whitespace or control character", ErrUsernameInvalid)
+		}
+	}
+	return username, nil
+}
@@ -0,0 +1,245 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeAccount(t *testing.T) {
+	tests := []struct {
+		name    string
+		account string
+		options []AccountOption
+		want    string
+		wantErr error
+	}{
+		{"spaces are trimmed", "  toni.tester@domain.tld \n", nil, "toni.tester@domain.tld", nil},
+		{"domain is lower cased", "Toni.Tester@Domain.TLD", nil, "Toni.Tester@domain.tld", nil},
+		{"trailing dot of domain is removed", "toni@domain.tld.", nil, "toni@domain.tld", nil},
+		{"IDN is converted to punycode", "toni@Bücher.example", nil, "toni@xn--bcher-kva.example", nil},
+		{"IDN with ideographic full stop", "toni@例え。テスト", nil, "toni@xn--r8jz45g.xn--zckzah", nil},
+		{"non-ASCII local part is kept", "tøni@domain.tld", nil, "tøni@domain.tld", nil},
+		{"local part with @ uses last @", `"toni@home"@domain.tld`, nil, `"toni@home"@domain.tld`, nil},
+		{"username is only trimmed", " Toni_Tester ", nil, "Toni_Tester", nil},
+		{"plus tag is kept without canonicalization", "toni+hibp@gmail.com", nil, "toni+hibp@gmail.com", nil},
+		{"empty account", "   ", nil, "", ErrNoAccountID},
+		{"empty local part", "@domain.tld", nil, "", ErrAccountInvalid},
+		{"empty domain", "toni@", nil, "", ErrAccountInvalid},
+		{"empty domain label", "toni@domain..tld", nil, "", ErrAccountInvalid},
+		{"invalid domain character", "toni@dom_ain.tld", nil, "", ErrAccountInvalid},
+		{"domain label with leading hyphen", "toni@-domain.tld", nil, "", ErrAccountInvalid},
+		{"domain label too long", "toni@" + strings.Repeat("a", 64) + ".tld", nil, "", ErrAccountInvalid},
+		{"space in local part", "toni tester@domain.tld", nil, "", ErrAccountInvalid},
+		{"unquoted local part with @", "a@b@c.com", nil, "", ErrAccountInvalid},
+		{"partly quoted local part with @", `"a"@b@c.com`, nil, "", ErrAccountInvalid},
+		{"control character", "toni\x00@domain.tld", nil, "", ErrAccountInvalid},
+		{"invalid UTF-8", "toni\xff@domain.tld", nil, "", ErrAccountInvalid},
+		{
+			"Gmail canonicalization", "Toni.Tester+hibp@GoogleMail.com", []AccountOption{WithCanonicalization()},
+			"tonitester@gmail.com", nil,
+		},
+		{
+			"Outlook canonicalization keeps dots", "Toni.Tester+hibp@outlook.com",
+			[]AccountOption{WithCanonicalization()}, "toni.tester@outlook.com", nil,
+		},
+		{
+			"other providers are not canonicalized", "Toni.Tester+hibp@domain.tld",
+			[]AccountOption{WithCanonicalization(), nil}, "Toni.Tester+hibp@domain.tld", nil,
+		},
+		{
+			"leading plus is not a tag", "+toni@gmail.com", []AccountOption{WithCanonicalization()},
+			"+toni@gmail.com", nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeAccount(tt.account, tt.options...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error to be %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected account to be %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPunycodeEncode(t *testing.T) {
+	// Sample strings of RFC 3492, section 7.1
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Arabic (Egyptian)", "ليهمابتكلموشعربي؟", "egbpdaj6bu4bxfgehfvwxn"},
+		{"Chinese (simplified)", "他们为什么不说中文", "ihqwcrb4cv8a8dqg056pqjye"},
+		{"Czech", "Pročprostěnemluvíčesky", "Proprostnemluvesky-uyb24dma41a"},
+		{"Japanese", "3年B組金八先生", "3B-ww4c5e180e575a65lsy2b"},
+		{"Russian", "почемужеонинеговорятпорусски", "b1abfaaepdrnnbgefbadotcwatmq2g4l"},
+		{"German", "bücher", "bcher-kva"},
+		{"ASCII only", "example", "example-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := punycodeEncode(tt.input)
+			if err != nil {
+				t.Fatalf("failed to encode punycode: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected punycode %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAccount_path_escaping(t *testing.T) {
+	var requestURI string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI = r.RequestURI
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	buf := bytes.Buffer{}
+	hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey),
+		WithStructuredLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+
+	tests := []struct {
+		name string
+		call func(account string) error
+		path string
+	}{
+		{"BreachedAccount", func(account string) error {
+			_, _, err := hc.BreachAPI.BreachedAccount(account)
+			return err
+		}, "/api/v3/breachedaccount/"},
+		{"PastedAccount", func(account string) error {
+			_, _, err := hc.PasteAPI.PastedAccount(account)
+			return err
+		}, "/api/v3/pasteaccount/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			if err := tt.call(" toni+hibp/x%y@Bücher.example "); err != nil {
+				t.Fatalf("lookup failed: %s", err)
+			}
+			want := tt.path + "toni%2Bhibp%2Fx%25y@xn--bcher-kva.example"
+			if !strings.HasPrefix(requestURI, want) {
+				t.Errorf("expected request URI to start with %q, got %q", want, requestURI)
+			}
+			if strings.Contains(buf.String(), "toni") {
+				t.Errorf("expected account to be redacted in log, got: %s", buf.String())
+			}
+			if !strings.Contains(buf.String(), "{account}") {
+				t.Errorf("expected endpoint template in log, got: %s", buf.String())
+			}
+			if err := tt.call("@domain.tld"); !errors.Is(err, ErrAccountInvalid) {
+				t.Errorf("expected error to be %s, got %v", ErrAccountInvalid, err)
+			}
+		})
+	}
+}
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		number  string
+		want    string
+		wantErr error
+	}{
+		{"E.164", "+61412345678", "61412345678", nil},
+		{"with separators", " +1 (555) 123-4567 ", "15551234567", nil},
+		{"with dots", "+49.30.1234567", "49301234567", nil},
+		{"international call prefix", "0031 6 12345678", "31612345678", nil},
+		{"empty number", " ", "", ErrNoAccountID},
+		{"national format", "0612345678", "", ErrPhoneNumberInvalid},
+		{"letters", "+1 555 CALL NOW", "", ErrPhoneNumberInvalid},
+		{"too short", "+1234567", "", ErrPhoneNumberInvalid},
+		{"too long", "+1234567890123456", "", ErrPhoneNumberInvalid},
+		{"country code starts with zero", "+0612345678", "", ErrPhoneNumberInvalid},
+		{"email address", "toni@domain.tld", "", ErrPhoneNumberInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizePhoneNumber(tt.number)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error to be %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected phone number to be %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeUsername(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		want     string
+		wantErr  error
+	}{
+		{"username", "toni_tester", "toni_tester", nil},
+		{"spaces are trimmed", "  Toni.Tester ", "Toni.Tester", nil},
+		{"non-ASCII username", "tøni", "tøni", nil},
+		{"maximum length", strings.Repeat("a", MaxUsernameLength), strings.Repeat("a", MaxUsernameLength), nil},
+		{"empty username", "", "", ErrNoAccountID},
+		{"email address", "toni@domain.tld", "", ErrUsernameInvalid},
+		{"whitespace", "toni tester", "", ErrUsernameInvalid},
+		{"control character", "toni\ttester", "", ErrUsernameInvalid},
+		{"too long", strings.Repeat("a", MaxUsernameLength+1), "", ErrUsernameInvalid},
+		{"invalid UTF-8", "toni\xff", "", ErrUsernameInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeUsername(tt.username)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error to be %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected username to be %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBreachAPI_BreachedPhoneNumber_BreachedUsername(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		_, _ = w.Write([]byte(`[{"Name":"Facebook"}]`))
+	}))
+	defer server.Close()
+	hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+
+	breaches, _, err := hc.BreachAPI.BreachedPhoneNumber("+61 412 345 678")
+	if err != nil {
+		t.Fatalf("failed to look up phone number: %s", err)
+	}
+	if len(breaches) != 1 || path != "/api/v3/breachedaccount/61412345678" {
+		t.Errorf("unexpected phone number lookup: %d breaches, path %q", len(breaches), path)
+	}
+	if _, _, err = hc.BreachAPI.BreachedPhoneNumber("0412 345 678"); !errors.Is(err, ErrPhoneNumberInvalid) {
+		t.Errorf("expected error to be %s, got %v", ErrPhoneNumberInvalid, err)
+	}
+
+	breaches, _, err = hc.BreachAPI.BreachedUsername(" toni/tester ")
+	if err != nil {
+		t.Fatalf("failed to look up username: %s", err)
+	}
+	if len(breaches) != 1 || path != "/api/v3/breachedaccount/toni%2Ftester" {
+		t.Errorf("unexpected username lookup: %d breaches, path %q", len(breaches), path)
+	}
+	if _, _, err = hc.BreachAPI.BreachedUsername("toni@domain.tld"); !errors.Is(err, ErrUsernameInvalid) {
+		t.Errorf("expected error to be %s, got %v", ErrUsernameInvalid, err)
+	}
+}
@@ -0,0 +1,200 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package audit
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/wneessen/go-hibp"
+)
+
+// DefaultBatchSize is the default number of candidates that are looked up at once
+const DefaultBatchSize = 1000
+
+// CandidateFormat represents the format of a list of password candidates
+type CandidateFormat int
+
+const (
+	// CandidateWordlist represents a plain wordlist with one password candidate per line
+	CandidateWordlist CandidateFormat = iota
+	// CandidateHashcatPot represents a hashcat potfile: "hash:password". The password is everything
+	// after the first colon, so salted hashes with colons in the hash are not supported
+	CandidateHashcatPot
+	// CandidateJohnPot represents a John the Ripper pot file: "ciphertext:password". The password is
+	// everything after the first colon
+	CandidateJohnPot
+)
+
+// hexPrefix is the prefix hashcat and John the Ripper use for hex encoded passwords, i. e.
+// "$HEX[70617373776f7264]"
+const hexPrefix = "$HEX["
+
+// CandidateRecord is the audit result of a single password candidate in a single hash mode
+type CandidateRecord struct {
+	// Candidate is the password candidate
+	Candidate string
+
+	// Line is the line number of the candidate in the input, starting at 1
+	Line int
+
+	// Mode is the hash mode the candidate was looked up with
+	Mode hibp.HashMode
+
+	// Hash is the lower case, hex encoded hash of the candidate in the hash mode
+	Hash string
+
+	// Count is the prevalence count of the hash in the Pwned Passwords database. It is 0 if the hash
+	// was not found
+	Count int64
+}
+
+// IsBreached indicates whether the candidate was found in the Pwned Passwords database
+func (r CandidateRecord) IsBreached() bool {
+	return r.Count > 0
+}
+
+// candidateOpts holds the options for AuditCandidates
+type candidateOpts struct {
+	modes     []hibp.HashMode
+	batchSize int
+}
+
+// CandidateOption is a function that sets options for AuditCandidates
+type CandidateOption func(*candidateOpts)
+
+// WithHashModes sets the hash modes the candidates are looked up with. A CandidateRecord is emitted
+// for each candidate and hash mode. The default is hibp.HashModeSHA1. Without hash modes, the option
+// is ignored
+func WithHashModes(modes ...hibp.HashMode) CandidateOption {
+	return func(o *candidateOpts) {
+		if len(modes) > 0 {
+			o.modes = modes
+		}
+	}
+}
+
+// WithBatchSize sets the number of candidates that are looked up in the Source at once. Hash
+// prefixes are de-duplicated within a batch, so larger batches save API requests at the cost of
+// memory. Values below 1 are ignored
+func WithBatchSize(size int) CandidateOption {
+	return func(o *candidateOpts) {
+		if size > 0 {
+			o.batchSize = size
+		}
+	}
+}
+
+// AuditCandidates reads the password candidates in the given CandidateFormat from the given
+// io.Reader, hashes them and looks them up in the given Source. The given function is called for
+// each candidate and hash mode with the CandidateRecord, in the order of the input. If the function
+// returns an error, the audit stops and the error is returned.
+//
+// The candidates are read and looked up in batches, so the input is never held in memory as a
+// whole. Hex encoded passwords of the "$HEX[...]" form are decoded. Empty lines of wordlists and
+// lines of pot files without a separator are skipped.
+func AuditCandidates(r io.Reader, format CandidateFormat, src Source, fn func(CandidateRecord) error,
+	options ...CandidateOption,
+) error {
+	if src == nil {
+		return ErrNoSource
+	}
+	opts := candidateOpts{modes: []hibp.HashMode{hibp.HashModeSHA1}, batchSize: DefaultBatchSize}
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+		option(&opts)
+	}
+
+	var batch []CandidateRecord
+	so := bufio.NewScanner(r)
+	line := 0
+	for so.Scan() {
+		line++
+		candidate, ok := parseCandidate(strings.TrimSuffix(so.Text(), "\r"), format)
+		if !ok {
+			continue
+		}
+		for _, mode := range opts.modes {
+			h, err := hibp.HashPassword([]byte(candidate), mode)
+			if err != nil {
+				return err
+			}
+			batch = append(batch, CandidateRecord{Candidate: candidate, Line: line, Mode: mode, Hash: h})
+		}
+		if len(batch) >= opts.batchSize*len(opts.modes) {
+			if err := lookupCandidates(batch, src, fn); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := so.Err(); err != nil {
+		return fmt.Errorf("failed to read candidates: %w", err)
+	}
+	return lookupCandidates(batch, src, fn)
+}
+
+// lookupCandidates looks up the hashes of the given batch of CandidateRecord in the given Source
+// and calls the given function for each of them
+func lookupCandidates(batch []CandidateRecord, src Source, fn func(CandidateRecord) error) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(batch))
+	hashes := make([]string, 0, len(batch))
+	for _, record := range batch {
+		if !seen[record.Hash] {
+			seen[record.Hash] = true
+			hashes = append(hashes, record.Hash)
+		}
+	}
+	counts, err := src.Lookup(hashes)
+	if err != nil {
+		return fmt.Errorf("failed to look up candidate hashes: %w", err)
+	}
+	for _, record := range batch {
+		record.Count = counts[record.Hash]
+		if err = fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseCandidate returns the password candidate of the given line in the given CandidateFormat
+func parseCandidate(text string, format CandidateFormat) (string, bool) {
+	switch format {
+	case CandidateWordlist:
+		if text == "" {
+			return "", false
+		}
+	case CandidateHashcatPot, CandidateJohnPot:
+		i := strings.Index(text, ":")
+		if i < 0 {
+			return "", false
+		}
+		text = text[i+1:]
+	default:
+		return "", false
+	}
+	return decodeHexCandidate(text), true
+}
+
+// decodeHexCandidate decodes a "$HEX[...]" encoded password. Other passwords are returned unchanged
+func decodeHexCandidate(text string) string {
+	if !strings.HasPrefix(text, hexPrefix) || !strings.HasSuffix(text, "]") {
+		return text
+	}
+	decoded, err := hex.DecodeString(text[len(hexPrefix) : len(text)-1])
+	if err != nil {
+		return text
+	}
+	return string(decoded)
+}
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package audit
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wneessen/go-hibp"
+)
+
+// testHashSHA1Password is the SHA-1 hash of the password "password"
+const testHashSHA1Password = "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8"
+
+func TestAuditCandidates(t *testing.T) {
+	src := testMapSource{testNTHashPassword: 3000, testHashSHA1Password: 3000, testNTHashTest: 1200}
+	tests := []struct {
+		name   string
+		format CandidateFormat
+		input  string
+	}{
+		{"wordlist", CandidateWordlist, "password\r\n\nunknown-candidate\ntest\n"},
+		{
+			"hashcat potfile", CandidateHashcatPot,
+			"8846f7eaee8fb117ad06bdd830b7586c:password\nnot-a-pot-line\n" +
+				"a8a4f10bd1d2a8b0f7e0b3c8d6a8c8e1:unknown-candidate\n0cb6948805f797bf2a82807973b89537:$HEX[74657374]\n",
+		},
+		{
+			"John pot file", CandidateJohnPot,
+			"$NT$8846f7eaee8fb117ad06bdd830b7586c:password\n\n" +
+				"$NT$a8a4f10bd1d2a8b0f7e0b3c8d6a8c8e1:unknown-candidate\n$NT$0cb6948805f797bf2a82807973b89537:test\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var records []CandidateRecord
+			err := AuditCandidates(strings.NewReader(tt.input), tt.format, src, func(r CandidateRecord) error {
+				records = append(records, r)
+				return nil
+			}, WithHashModes(hibp.HashModeNTLM))
+			if err != nil {
+				t.Fatalf("audit failed: %s", err)
+			}
+			if len(records) != 3 {
+				t.Fatalf("expected %d records, got %d", 3, len(records))
+			}
+			expected := []struct {
+				candidate string
+				count     int64
+			}{{"password", 3000}, {"unknown-candidate", 0}, {"test", 1200}}
+			for i, e := range expected {
+				if records[i].Candidate != e.candidate || records[i].Count != e.count {
+					t.Errorf("expected record %d to be %q with count %d, got %+v", i, e.candidate, e.count,
+						records[i])
+				}
+				if records[i].Mode != hibp.HashModeNTLM {
+					t.Errorf("expected record %d to use NTLM hash mode, got %d", i, records[i].Mode)
+				}
+			}
+			if records[0].Hash != testNTHashPassword || !records[0].IsBreached() {
+				t.Errorf("unexpected record: %+v", records[0])
+			}
+			if records[1].IsBreached() {
+				t.Errorf("expected %q not to be breached", records[1].Candidate)
+			}
+		})
+	}
+	t.Run("candidates are looked up in all hash modes and batches", func(t *testing.T) {
+		lookups := 0
+		counting := testLookupFunc(func(hashes []string) (map[string]int64, error) {
+			lookups++
+			return src.Lookup(hashes)
+		})
+		var records []CandidateRecord
+		err := AuditCandidates(strings.NewReader("password\npassword\ntest\n"), CandidateWordlist, counting,
+			func(r CandidateRecord) error {
+				records = append(records, r)
+				return nil
+			}, WithHashModes(hibp.HashModeSHA1, hibp.HashModeNTLM), WithHashModes(), WithBatchSize(2), WithBatchSize(0), nil)
+		if err != nil {
+			t.Fatalf("audit failed: %s", err)
+		}
+		if len(records) != 6 {
+			t.Fatalf("expected %d records, got %d", 6, len(records))
+		}
+		if records[0].Hash != testHashSHA1Password || records[1].Hash != testNTHashPassword {
+			t.Errorf("expected SHA-1 and NTLM records for each candidate, got %+v", records[:2])
+		}
+		if records[4].Line != 3 || records[5].Count != 1200 {
+			t.Errorf("unexpected record: %+v", records[5])
+		}
+		if lookups != 2 {
+			t.Errorf("expected %d lookups, got %d", 2, lookups)
+		}
+	})
+	t.Run("audit stops on callback errors", func(t *testing.T) {
+		errStop := errors.New("stop")
+		calls := 0
+		err := AuditCandidates(strings.NewReader("password\ntest\n"), CandidateWordlist, src,
+			func(CandidateRecord) error {
+				calls++
+				return errStop
+			})
+		if !errors.Is(err, errStop) {
+			t.Errorf("expected error to be %s, got %v", errStop, err)
+		}
+		if calls != 1 {
+			t.Errorf("expected callback to be called %d time, got %d", 1, calls)
+		}
+	})
+	t.Run("audit fails on unsupported hash mode", func(t *testing.T) {
+		err := AuditCandidates(strings.NewReader("password\n"), CandidateWordlist, src,
+			func(CandidateRecord) error { return nil }, WithHashModes(99))
+		if !errors.Is(err, hibp.ErrUnsupportedHashMode) {
+			t.Errorf("expected error to be %s, got %v", hibp.ErrUnsupportedHashMode, err)
+		}
+	})
+	t.Run("audit fails on lookup errors", func(t *testing.T) {
+		err := AuditCandidates(strings.NewReader("password\n"), CandidateWordlist, testErrSource{},
+			func(CandidateRecord) error { return nil })
+		if err == nil {
+			t.Error("expected audit to fail")
+		}
+	})
+	t.Run("audit fails without source", func(t *testing.T) {
+		err := AuditCandidates(strings.NewReader("password\n"), CandidateWordlist, nil,
+			func(CandidateRecord) error { return nil })
+		if !errors.Is(err, ErrNoSource) {
+			t.Errorf("expected error to be %s, got %v", ErrNoSource, err)
+		}
+	})
+	t.Run("invalid hex candidates are not decoded", func(t *testing.T) {
+		if got := decodeHexCandidate("$HEX[zz]"); got != "$HEX[zz]" {
+			t.Errorf("expected invalid hex candidate to be unchanged, got %q", got)
+		}
+	})
+}
+
+// testLookupFunc is a function that satisfies the Source interface
+type testLookupFunc func(hashes []string) (map[string]int64, error)
+
+// Lookup satisfies the Source interface for the testLookupFunc type
+func (f testLookupFunc) Lookup(hashes []string) (map[string]int64, error) {
+	return f(hashes)
+}
@@ -0,0 +1,317 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+// Package audit provides tools to audit credentials in bulk against the Pwned Passwords
+// database of the "Have I Been Pwned" API.
+package audit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EmptyNTHash is the NT hash of an empty password
+const EmptyNTHash = "31d6cfe0d16ae931b73c59d7e0c089c0"
+
+// noPassword is the placeholder pwdump uses for empty LM and NT hashes
+const noPassword = "NO PASSWORD"
+
+// ErrMalformedDumpLine is returned if a line of a hash dump can not be parsed
+var ErrMalformedDumpLine = errors.New("malformed hash dump line")
+
+// DumpFormat represents the format of an NTLM hash dump
+type DumpFormat int
+
+const (
+	// DumpFormatAuto detects the format of each line of the hash dump. Lines with 4 or more fields
+	// are parsed as secretsdump/pwdump lines, lines with 2 fields as hashcat lines with user name and
+	// lines with a single field as plain NT hashes
+	DumpFormatAuto DumpFormat = iota
+	// DumpFormatSecretsdump represents the format of impacket's secretsdump and its .ntds output
+	// files: "[domain\]user:rid:lmhash:nthash:::"
+	DumpFormatSecretsdump
+	// DumpFormatPwdump represents the pwdump format: "user:rid:lmhash:nthash:comment:homedir:"
+	DumpFormatPwdump
+	// DumpFormatHashcat represents the hashcat input format for NTLM hashes (mode 1000), either as
+	// plain NT hash or with the user name when used with the --username flag: "[user:]nthash"
+	DumpFormatHashcat
+)
+
+// DumpLineError is returned if a line of a hash dump can not be parsed
+type DumpLineError struct {
+	// Line is the line number of the malformed line, starting at 1
+	Line int
+
+	// Text is the content of the malformed line
+	Text string
+}
+
+// Error satisfies the error interface for the DumpLineError type
+func (e *DumpLineError) Error() string {
+	return fmt.Sprintf("%s %d: %q", ErrMalformedDumpLine, e.Line, e.Text)
+}
+
+// Unwrap returns ErrMalformedDumpLine, so that errors.Is can be used on a DumpLineError
+func (e *DumpLineError) Unwrap() error {
+	return ErrMalformedDumpLine
+}
+
+// Account represents an account entry of an NTLM hash dump
+type Account struct {
+	// Domain is the domain of the account, if the hash dump provides it
+	Domain string
+
+	// User is the name of the account
+	User string
+
+	// RID is the relative identifier of the account, if the hash dump provides it
+	RID string
+
+	// LMHash is the lower case LM hash of the account, if the hash dump provides it
+	LMHash string
+
+	// NTHash is the lower case NT hash of the account
+	NTHash string
+
+	// Line is the line number of the account in the hash dump, starting at 1
+	Line int
+}
+
+// Name returns the name of the account, prefixed with the domain if it is set
+func (a Account) Name() string {
+	if a.Domain == "" {
+		return a.User
+	}
+	return a.Domain + `\` + a.User
+}
+
+// IsMachine indicates whether the account is a machine account
+func (a Account) IsMachine() bool {
+	return strings.HasSuffix(a.User, "$")
+}
+
+// DumpScanner reads the accounts of an NTLM hash dump line by line. It is used similar to a
+// bufio.Scanner:
+//
+//	ds := audit.NewDumpScanner(r, audit.DumpFormatAuto)
+//	for ds.Scan() {
+//		fmt.Println(ds.Account().Name())
+//	}
+//	if err := ds.Err(); err != nil {
+//		return err
+//	}
+//
+// By default, malformed lines are skipped and counted. With the WithStrictDumpParsing option, the
+// DumpScanner stops at the first malformed line and returns a DumpLineError.
+type DumpScanner struct {
+	so          *bufio.Scanner
+	format      DumpFormat
+	strict      bool
+	skipMachine bool
+	skipHistory bool
+	account     Account
+	err         error
+	line        int
+	malformed   int
+}
+
+// DumpScannerOption is a function that sets options on a DumpScanner
+type DumpScannerOption func(*DumpScanner)
+
+// WithStrictDumpParsing lets the DumpScanner fail on the first malformed line instead of
+// skipping it
+func WithStrictDumpParsing() DumpScannerOption {
+	return func(ds *DumpScanner) {
+		ds.strict = true
+	}
+}
+
+// WithoutMachineAccounts lets the DumpScanner skip machine accounts, which end with a "$"
+func WithoutMachineAccounts() DumpScannerOption {
+	return func(ds *DumpScanner) {
+		ds.skipMachine = true
+	}
+}
+
+// WithoutHistory lets the DumpScanner skip the password history entries that secretsdump
+// writes with the -history flag, i. e. "user_history0:rid:lmhash:nthash:::". Only user names that
+// end in "_history" and a number are skipped
+func WithoutHistory() DumpScannerOption {
+	return func(ds *DumpScanner) {
+		ds.skipHistory = true
+	}
+}
+
+// NewDumpScanner returns a new DumpScanner that reads the accounts of an NTLM hash dump in the
+// given DumpFormat from the given io.Reader
+func NewDumpScanner(r io.Reader, format DumpFormat, options ...DumpScannerOption) *DumpScanner {
+	ds := &DumpScanner{
+		so:     bufio.NewScanner(r),
+		format: format,
+	}
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+		option(ds)
+	}
+	return ds
+}
+
+// Scan advances the DumpScanner to the next account, which will then be available through the
+// Account method. It returns false when the scan stops, either by reaching the end of the input
+// or an error
+func (ds *DumpScanner) Scan() bool {
+	if ds.err != nil {
+		return false
+	}
+	for ds.so.Scan() {
+		ds.line++
+		text := strings.TrimSpace(ds.so.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		account, ok := ds.parseLine(text)
+		if !ok {
+			ds.malformed++
+			if ds.strict {
+				ds.err = &DumpLineError{Line: ds.line, Text: text}
+				return false
+			}
+			continue
+		}
+		if ds.skipMachine && account.IsMachine() {
+			continue
+		}
+		if ds.skipHistory && isHistoryEntry(account.User) {
+			continue
+		}
+		ds.account = account
+		return true
+	}
+	ds.err = ds.so.Err()
+	return false
+}
+
+// isHistoryEntry checks if the given user name is a password history entry of secretsdump, i. e. a
+// user name followed by "_history" and the number of the entry
+func isHistoryEntry(user string) bool {
+	i := strings.LastIndex(user, "_history")
+	if i <= 0 {
+		return false
+	}
+	num := user[i+len("_history"):]
+	if num == "" {
+		return false
+	}
+	for _, r := range num {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Account returns the most recent account read by a call to Scan
+func (ds *DumpScanner) Account() Account {
+	return ds.account
+}
+
+// Err returns the first error that was encountered by the DumpScanner
+func (ds *DumpScanner) Err() error {
+	return ds.err
+}
+
+// Malformed returns the number of malformed lines the DumpScanner has encountered so far
+func (ds *DumpScanner) Malformed() int {
+	return ds.malformed
+}
+
+// parseLine parses a single line of the hash dump according to the DumpFormat of the DumpScanner
+func (ds *DumpScanner) parseLine(text string) (Account, bool) {
+	fields := strings.Split(text, ":")
+	switch ds.format {
+	case DumpFormatSecretsdump, DumpFormatPwdump:
+		return ds.parseSAMLine(fields)
+	case DumpFormatHashcat:
+		return ds.parseHashcatLine(fields)
+	case DumpFormatAuto:
+		if len(fields) >= 4 {
+			return ds.parseSAMLine(fields)
+		}
+		return ds.parseHashcatLine(fields)
+	default:
+		return Account{}, false
+	}
+}
+
+// parseSAMLine parses the fields of a secretsdump or pwdump line: "user:rid:lmhash:nthash:...". The
+// "NO PASSWORD*****" placeholder of pwdump is replaced by the empty hash
+func (ds *DumpScanner) parseSAMLine(fields []string) (Account, bool) {
+	if len(fields) >= 4 && strings.HasPrefix(fields[3], noPassword) {
+		fields[3] = EmptyNTHash
+	}
+	if len(fields) < 4 || fields[0] == "" || !isNTHash(fields[3]) {
+		return Account{}, false
+	}
+	account := Account{
+		RID:    fields[1],
+		NTHash: strings.ToLower(fields[3]),
+		Line:   ds.line,
+	}
+	account.Domain, account.User = splitDomain(fields[0])
+	if isNTHash(fields[2]) {
+		account.LMHash = strings.ToLower(fields[2])
+	}
+	return account, true
+}
+
+// parseHashcatLine parses the fields of a hashcat line: "[user:]nthash"
+func (ds *DumpScanner) parseHashcatLine(fields []string) (Account, bool) {
+	var account Account
+	switch len(fields) {
+	case 1:
+		account.NTHash = fields[0]
+	case 2:
+		if fields[0] == "" {
+			return Account{}, false
+		}
+		account.Domain, account.User = splitDomain(fields[0])
+		account.NTHash = fields[1]
+	default:
+		return Account{}, false
+	}
+	if !isNTHash(account.NTHash) {
+		return Account{}, false
+	}
+	account.NTHash = strings.ToLower(account.NTHash)
+	account.Line = ds.line
+	return account, true
+}
+
+// splitDomain splits a "domain\user" name into its domain and user parts
+func splitDomain(name string) (string, string) {
+	if i := strings.LastIndex(name, `\`); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// isNTHash checks that the given string is a hex encoded NT hash
+func isNTHash(h string) bool {
+	if len(h) != 32 {
+		return false
+	}
+	for i := 0; i < len(h); i++ {
+		switch c := h[i]; {
+		case '0' <= c && c <= '9', 'a' <= c && c <= 'f', 'A' <= c && c <= 'F':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package audit
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const (
+	// testNTHashPassword is the NT hash of the password "password"
+	testNTHashPassword = "8846f7eaee8fb117ad06bdd830b7586c"
+
+	// testNTHashTest is the NT hash of the password "test"
+	testNTHashTest = "0cb6948805f797bf2a82807973b89537"
+
+	// testNTHashUnknown is an NT hash that is not part of the test sources
+	testNTHashUnknown = "c5a237b7e9d8e708d8436b6148a25fa1"
+
+	// testSecretsdump is a hash dump in secretsdump format
+	testSecretsdump = "CORP\\alice:1104:aad3b435b51404eeaad3b435b51404ee:8846f7eaee8fb117ad06bdd830b7586c:::\n" +
+		"CORP\\bob:1105:aad3b435b51404eeaad3b435b51404ee:8846F7EAEE8FB117AD06BDD830B7586C:::\n" +
+		"carol:1106:aad3b435b51404eeaad3b435b51404ee:0cb6948805f797bf2a82807973b89537:::\n" +
+		"\n" +
+		"WS01$:1107:aad3b435b51404eeaad3b435b51404ee:c5a237b7e9d8e708d8436b6148a25fa1:::\n" +
+		"alice_history0:1104:aad3b435b51404eeaad3b435b51404ee:0cb6948805f797bf2a82807973b89537:::\n" +
+		"this is not a valid line\n" +
+		"dave:1108:aad3b435b51404eeaad3b435b51404ee:not-a-hash:::\n"
+)
+
+func TestDumpScanner(t *testing.T) {
+	t.Run("secretsdump lines are parsed", func(t *testing.T) {
+		ds := NewDumpScanner(strings.NewReader(testSecretsdump), DumpFormatSecretsdump)
+		var accounts []Account
+		for ds.Scan() {
+			accounts = append(accounts, ds.Account())
+		}
+		if err := ds.Err(); err != nil {
+			t.Fatalf("dump scanner failed: %s", err)
+		}
+		if len(accounts) != 5 {
+			t.Fatalf("expected %d accounts, got %d", 5, len(accounts))
+		}
+		expected := Account{
+			Domain: "CORP", User: "bob", RID: "1105", LMHash: "aad3b435b51404eeaad3b435b51404ee",
+			NTHash: testNTHashPassword, Line: 2,
+		}
+		if accounts[1] != expected {
+			t.Errorf("expected account to be %+v, got %+v", expected, accounts[1])
+		}
+		if accounts[1].Name() != `CORP\bob` {
+			t.Errorf("expected account name to be %q, got %q", `CORP\bob`, accounts[1].Name())
+		}
+		if accounts[2].Name() != "carol" {
+			t.Errorf("expected account name to be %q, got %q", "carol", accounts[2].Name())
+		}
+		if !accounts[3].IsMachine() {
+			t.Errorf("expected %s to be a machine account", accounts[3].Name())
+		}
+		if ds.Malformed() != 2 {
+			t.Errorf("expected %d malformed lines, got %d", 2, ds.Malformed())
+		}
+	})
+	t.Run("machine accounts and history entries can be skipped", func(t *testing.T) {
+		ds := NewDumpScanner(strings.NewReader(testSecretsdump), DumpFormatAuto, WithoutMachineAccounts(),
+			WithoutHistory(), nil)
+		count := 0
+		for ds.Scan() {
+			if ds.Account().IsMachine() || isHistoryEntry(ds.Account().User) {
+				t.Errorf("expected account %s to be skipped", ds.Account().Name())
+			}
+			count++
+		}
+		if count != 3 {
+			t.Errorf("expected %d accounts, got %d", 3, count)
+		}
+	})
+	t.Run("only numbered history entries are skipped", func(t *testing.T) {
+		dump := "svc_history_reader:1109:aad3b435b51404eeaad3b435b51404ee:8846f7eaee8fb117ad06bdd830b7586c:::\n" +
+			"report_history:1110:aad3b435b51404eeaad3b435b51404ee:8846f7eaee8fb117ad06bdd830b7586c:::\n" +
+			"svc_history_reader_history12:1109:aad3b435b51404eeaad3b435b51404ee:0cb6948805f797bf2a82807973b89537:::\n"
+		ds := NewDumpScanner(strings.NewReader(dump), DumpFormatSecretsdump, WithoutHistory())
+		var users []string
+		for ds.Scan() {
+			users = append(users, ds.Account().User)
+		}
+		if len(users) != 2 || users[0] != "svc_history_reader" || users[1] != "report_history" {
+			t.Errorf("expected only the history entry to be skipped, got %v", users)
+		}
+	})
+	t.Run("strict dump scanner fails on the first malformed line", func(t *testing.T) {
+		ds := NewDumpScanner(strings.NewReader(testSecretsdump), DumpFormatSecretsdump, WithStrictDumpParsing())
+		for ds.Scan() {
+		}
+		err := ds.Err()
+		if !errors.Is(err, ErrMalformedDumpLine) {
+			t.Fatalf("expected error to be %s, got %v", ErrMalformedDumpLine, err)
+		}
+		var lineErr *DumpLineError
+		if !errors.As(err, &lineErr) {
+			t.Fatalf("expected error to be a DumpLineError, got %T", err)
+		}
+		if lineErr.Line != 7 {
+			t.Errorf("expected malformed line to be %d, got %d", 7, lineErr.Line)
+		}
+	})
+	t.Run("pwdump lines with empty passwords are parsed", func(t *testing.T) {
+		data := "Guest:501:NO PASSWORD*********************:NO PASSWORD*********************:::\n" +
+			"alice:1000:aad3b435b51404eeaad3b435b51404ee:8846f7eaee8fb117ad06bdd830b7586c:Alice:C:\\Users\\alice:\n"
+		ds := NewDumpScanner(strings.NewReader(data), DumpFormatPwdump, WithStrictDumpParsing())
+		var accounts []Account
+		for ds.Scan() {
+			accounts = append(accounts, ds.Account())
+		}
+		if err := ds.Err(); err != nil {
+			t.Fatalf("dump scanner failed: %s", err)
+		}
+		if len(accounts) != 2 {
+			t.Fatalf("expected %d accounts, got %d", 2, len(accounts))
+		}
+		if accounts[0].NTHash != EmptyNTHash || accounts[0].LMHash != "" {
+			t.Errorf("expected account with empty password, got %+v", accounts[0])
+		}
+		if accounts[1].NTHash != testNTHashPassword {
+			t.Errorf("expected NT hash to be %s, got %s", testNTHashPassword, accounts[1].NTHash)
+		}
+	})
+	t.Run("hashcat lines with and without user names are parsed", func(t *testing.T) {
+		data := "CORP\\alice:8846F7EAEE8FB117AD06BDD830B7586C\n0cb6948805f797bf2a82807973b89537\n"
+		for _, format := range []DumpFormat{DumpFormatHashcat, DumpFormatAuto} {
+			ds := NewDumpScanner(strings.NewReader(data), format, WithStrictDumpParsing())
+			var accounts []Account
+			for ds.Scan() {
+				accounts = append(accounts, ds.Account())
+			}
+			if err := ds.Err(); err != nil {
+				t.Fatalf("dump scanner failed: %s", err)
+			}
+			if len(accounts) != 2 {
+				t.Fatalf("expected %d accounts, got %d", 2, len(accounts))
+			}
+			if accounts[0].Name() != `CORP\alice` || accounts[0].NTHash != testNTHashPassword {
+				t.Errorf("unexpected account: %+v", accounts[0])
+			}
+			if accounts[1].User != "" || accounts[1].NTHash != testNTHashTest {
+				t.Errorf("unexpected account: %+v", accounts[1])
+			}
+		}
+	})
+	t.Run("secretsdump lines are malformed in hashcat format", func(t *testing.T) {
+		ds := NewDumpScanner(strings.NewReader(testSecretsdump), DumpFormatHashcat)
+		if ds.Scan() {
+			t.Errorf("expected no accounts, got %+v", ds.Account())
+		}
+	})
+	t.Run("unsupported dump format fails", func(t *testing.T) {
+		ds := NewDumpScanner(strings.NewReader(testSecretsdump), DumpFormat(99), WithStrictDumpParsing())
+		if ds.Scan() {
+			t.Errorf("expected no accounts, got %+v", ds.Account())
+		}
+		if !errors.Is(ds.Err(), ErrMalformedDumpLine) {
+			t.Errorf("expected error to be %s, got %v", ErrMalformedDumpLine, ds.Err())
+		}
+	})
+}
@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package audit
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// ErrNoSource is returned if an audit is started without a Source
+var ErrNoSource = errors.New("no hash source given")
+
+// NTLMResult is the audit result of a single account of an NTLM hash dump
+type NTLMResult struct {
+	Account
+
+	// Count is the prevalence count of the NT hash in the Pwned Passwords database. It is 0 if the
+	// hash was not found
+	Count int64
+
+	// Shared is the number of other accounts of the hash dump that use the same NT hash
+	Shared int
+}
+
+// IsBreached indicates whether the NT hash of the account was found in the Pwned Passwords database
+func (r NTLMResult) IsBreached() bool {
+	return r.Count > 0
+}
+
+// IsEmpty indicates whether the account uses an empty password
+func (r NTLMResult) IsEmpty() bool {
+	return r.NTHash == EmptyNTHash
+}
+
+// SharedHash is an NT hash that is used by more than one account of an NTLM hash dump
+type SharedHash struct {
+	// NTHash is the shared NT hash
+	NTHash string
+
+	// Count is the prevalence count of the NT hash in the Pwned Passwords database
+	Count int64
+
+	// Accounts holds the names of the accounts that use the NT hash, in the order of the hash dump
+	Accounts []string
+}
+
+// NTLMReport is the result of an audit of an NTLM hash dump
+type NTLMReport struct {
+	// Results holds the audit results of all accounts, in the order of the hash dump
+	Results []NTLMResult
+
+	// Shared holds the NT hashes that are used by more than one account, sorted by the number of
+	// accounts in descending order
+	Shared []SharedHash
+
+	// Malformed is the number of malformed lines of the hash dump that were skipped
+	Malformed int
+}
+
+// AuditNTLM reads the NTLM hash dump in the given DumpFormat from the given io.Reader, looks up
+// all unique NT hashes in the given Source and returns the NTLMReport for the hash dump
+func AuditNTLM(r io.Reader, format DumpFormat, src Source, options ...DumpScannerOption) (*NTLMReport, error) {
+	if src == nil {
+		return nil, ErrNoSource
+	}
+	report := &NTLMReport{}
+	users := make(map[string][]string)
+	var hashes []string
+	ds := NewDumpScanner(r, format, options...)
+	for ds.Scan() {
+		account := ds.Account()
+		if _, ok := users[account.NTHash]; !ok {
+			hashes = append(hashes, account.NTHash)
+		}
+		users[account.NTHash] = append(users[account.NTHash], account.Name())
+		report.Results = append(report.Results, NTLMResult{Account: account})
+	}
+	report.Malformed = ds.Malformed()
+	if err := ds.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read hash dump: %w", err)
+	}
+
+	counts, err := src.Lookup(hashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up NT hashes: %w", err)
+	}
+	for i := range report.Results {
+		result := &report.Results[i]
+		result.Count = counts[result.NTHash]
+		result.Shared = len(users[result.NTHash]) - 1
+	}
+	for _, h := range hashes {
+		if len(users[h]) < 2 {
+			continue
+		}
+		report.Shared = append(report.Shared, SharedHash{NTHash: h, Count: counts[h], Accounts: users[h]})
+	}
+	sort.SliceStable(report.Shared, func(i, j int) bool {
+		return len(report.Shared[i].Accounts) > len(report.Shared[j].Accounts)
+	})
+	return report, nil
+}
+
+// Breached returns the audit results of all accounts whose NT hash was found in the Pwned
+// Passwords database, sorted by the prevalence count in descending order
+func (r *NTLMReport) Breached() []NTLMResult {
+	var breached []NTLMResult
+	for _, result := range r.Results {
+		if result.IsBreached() {
+			breached = append(breached, result)
+		}
+	}
+	sort.SliceStable(breached, func(i, j int) bool {
+		return breached[i].Count > breached[j].Count
+	})
+	return breached
+}
+
+// WriteCSV writes the audit results of all accounts as CSV with a header line to the given
+// io.Writer. The NT hashes are not included in the output
+func (r *NTLMReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"account", "rid", "breached", "count", "shared", "empty"}); err != nil {
+		return err
+	}
+	for _, result := range r.Results {
+		record := []string{
+			result.Name(),
+			result.RID,
+			strconv.FormatBool(result.IsBreached()),
+			strconv.FormatInt(result.Count, 10),
+			strconv.Itoa(result.Shared),
+			strconv.FormatBool(result.IsEmpty()),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testErrSource is a Source that fails every lookup
+type testErrSource struct{}
+
+// Lookup satisfies the Source interface for the testErrSource type
+func (testErrSource) Lookup([]string) (map[string]int64, error) {
+	return nil, errors.New("lookup failed")
+}
+
+func TestAuditNTLM(t *testing.T) {
+	src := testMapSource{testNTHashPassword: 3000, testNTHashTest: 1200}
+	t.Run("audit reports breached and shared hashes", func(t *testing.T) {
+		report, err := AuditNTLM(strings.NewReader(testSecretsdump), DumpFormatSecretsdump, src)
+		if err != nil {
+			t.Fatalf("audit failed: %s", err)
+		}
+		if len(report.Results) != 5 {
+			t.Fatalf("expected %d results, got %d", 5, len(report.Results))
+		}
+		if report.Malformed != 2 {
+			t.Errorf("expected %d malformed lines, got %d", 2, report.Malformed)
+		}
+		breached := report.Breached()
+		if len(breached) != 4 {
+			t.Fatalf("expected %d breached accounts, got %d", 4, len(breached))
+		}
+		if breached[0].Name() != `CORP\alice` || breached[0].Count != 3000 || breached[0].Shared != 1 {
+			t.Errorf("unexpected first breached account: %+v", breached[0])
+		}
+		if breached[3].Count != 1200 {
+			t.Errorf("expected breached accounts to be sorted by count, got %+v", breached)
+		}
+		if report.Results[3].IsBreached() {
+			t.Errorf("expected %s not to be breached", report.Results[3].Name())
+		}
+		if len(report.Shared) != 2 {
+			t.Fatalf("expected %d shared hashes, got %d", 2, len(report.Shared))
+		}
+		shared := report.Shared[0]
+		if shared.NTHash != testNTHashPassword || shared.Count != 3000 {
+			t.Errorf("unexpected shared hash: %+v", shared)
+		}
+		if strings.Join(shared.Accounts, ",") != `CORP\alice,CORP\bob` {
+			t.Errorf("expected shared hash accounts to be %q, got %q", `CORP\alice,CORP\bob`, shared.Accounts)
+		}
+	})
+	t.Run("audit report is written as CSV", func(t *testing.T) {
+		data := "Guest:501:NO PASSWORD*********************:NO PASSWORD*********************:::\n" +
+			"carol:1106:aad3b435b51404eeaad3b435b51404ee:0cb6948805f797bf2a82807973b89537:::\n"
+		report, err := AuditNTLM(strings.NewReader(data), DumpFormatAuto, src)
+		if err != nil {
+			t.Fatalf("audit failed: %s", err)
+		}
+		buf := bytes.NewBuffer(nil)
+		if err = report.WriteCSV(buf); err != nil {
+			t.Fatalf("failed to write CSV: %s", err)
+		}
+		expected := "account,rid,breached,count,shared,empty\n" +
+			"Guest,501,false,0,0,true\n" +
+			"carol,1106,true,1200,0,false\n"
+		if buf.String() != expected {
+			t.Errorf("expected CSV to be %q, got %q", expected, buf.String())
+		}
+		if strings.Contains(buf.String(), testNTHashTest) {
+			t.Error("expected CSV not to contain NT hashes")
+		}
+	})
+	t.Run("audit fails without source", func(t *testing.T) {
+		_, err := AuditNTLM(strings.NewReader(testSecretsdump), DumpFormatAuto, nil)
+		if !errors.Is(err, ErrNoSource) {
+			t.Errorf("expected error to be %s, got %v", ErrNoSource, err)
+		}
+	})
+	t.Run("audit fails on malformed lines in strict mode", func(t *testing.T) {
+		_, err := AuditNTLM(strings.NewReader(testSecretsdump), DumpFormatAuto, src, WithStrictDumpParsing())
+		if !errors.Is(err, ErrMalformedDumpLine) {
+			t.Errorf("expected error to be %s, got %v", ErrMalformedDumpLine, err)
+		}
+	})
+	t.Run("audit fails on lookup errors", func(t *testing.T) {
+		_, err := AuditNTLM(strings.NewReader(testSecretsdump), DumpFormatAuto, testErrSource{})
+		if err == nil {
+			t.Error("expected audit to fail")
+		}
+	})
+}
@@ -0,0 +1,248 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wneessen/go-hibp"
+)
+
+// ErrNoClient is returned if an APISource is used without a HIBP client
+var ErrNoClient = errors.New("no HIBP client given")
+
+// Source looks up the prevalence counts of password hashes in the Pwned Passwords database
+type Source interface {
+	// Lookup returns the prevalence counts of the given lower case hashes. Hashes that are not
+	// found are omitted from the returned map
+	Lookup(hashes []string) (map[string]int64, error)
+}
+
+// APISource is a Source that looks up the hashes with the range API of the Pwned Passwords
+// database. Each hash prefix is only requested once per lookup.
+//
+// SHA-1 and NTLM hashes are told apart by their length. The hash mode is set per request, the
+// hash mode of the HIBP client is left untouched, so the client can be shared with other callers
+// and concurrent audits.
+type APISource struct {
+	client *hibp.Client
+}
+
+// NewAPISource returns a new APISource for the given HIBP client
+func NewAPISource(client *hibp.Client) *APISource {
+	return &APISource{client: client}
+}
+
+// Lookup satisfies the Source interface for the APISource type
+func (s *APISource) Lookup(hashes []string) (map[string]int64, error) {
+	if s.client == nil {
+		return nil, ErrNoClient
+	}
+	var sha1Hashes, ntlmHashes []string
+	for _, h := range hashes {
+		switch len(h) {
+		case 40:
+			sha1Hashes = append(sha1Hashes, h)
+		case 32:
+			ntlmHashes = append(ntlmHashes, h)
+		}
+	}
+	found := make(map[string]int64)
+	if err := s.lookup(hibp.HashModeSHA1, sha1Hashes, found); err != nil {
+		return found, err
+	}
+	if err := s.lookup(hibp.HashModeNTLM, ntlmHashes, found); err != nil {
+		return found, err
+	}
+	return found, nil
+}
+
+// lookup requests the prefixes of the given hashes in the given hash mode and stores the counts
+// of the found hashes in the given map
+func (s *APISource) lookup(mode hibp.HashMode, hashes []string, found map[string]int64) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	wanted := make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		wanted[h] = true
+	}
+	for _, prefix := range hashPrefixes(hashes) {
+		_, err := s.client.PwnedPassAPI.StreamHashesPrefixMode(mode, prefix, func(m hibp.Match) error {
+			if !m.IsPadding() && wanted[m.Hash] {
+				found[m.Hash] = m.Count
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to look up hash prefix %s: %w", prefix, err)
+		}
+	}
+	return nil
+}
+
+// CorpusSource is a Source that looks up the hashes in a local copy of the Pwned Passwords
+// database, as created by the official PwnedPasswordsDownloader. The corpus file consists of
+// "HASH:COUNT" lines and must be sorted by hash, like the output of the downloader. Each hash is
+// looked up with a binary search in the corpus file, so a lookup only reads a few small blocks of
+// the file per hash instead of the whole corpus.
+type CorpusSource struct {
+	path string
+}
+
+// NewCorpusSource returns a new CorpusSource for the corpus file at the given path
+func NewCorpusSource(path string) *CorpusSource {
+	return &CorpusSource{path: path}
+}
+
+// Lookup satisfies the Source interface for the CorpusSource type
+func (s *CorpusSource) Lookup(hashes []string) (map[string]int64, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open corpus file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open corpus file: %w", err)
+	}
+
+	found := make(map[string]int64)
+	corpus := &sortedCorpus{r: file, size: info.Size()}
+	for _, h := range hashes {
+		count, ok, err := corpus.search(strings.ToLower(h))
+		if err != nil {
+			return found, fmt.Errorf("failed to read corpus file: %w", err)
+		}
+		if ok {
+			found[strings.ToLower(h)] = count
+		}
+	}
+	return found, nil
+}
+
+// corpusBlockSize is the size of the blocks read from a corpus file. It holds the longest line of
+// a corpus file, a 40 character SHA-1 hash with its count
+const corpusBlockSize = 128
+
+// sortedCorpus is a corpus file that is sorted by hash
+type sortedCorpus struct {
+	r    io.ReaderAt
+	size int64
+	buf  [corpusBlockSize]byte
+}
+
+// search looks up the given lower case hash with a binary search and returns its count
+func (c *sortedCorpus) search(hash string) (int64, bool, error) {
+	// lo is always the start of a line and all lines before lo hold smaller hashes. The first line
+	// starting at or after hi holds a hash not smaller than the given hash, or hi is the end of file
+	lo, hi := int64(0), c.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, err := c.lineStart(mid)
+		if err != nil {
+			return 0, false, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+		lineHash, _, next, err := c.line(start)
+		if err != nil {
+			return 0, false, err
+		}
+		if lineHash < hash {
+			lo = next
+			continue
+		}
+		hi = mid
+	}
+	if lo >= c.size {
+		return 0, false, nil
+	}
+	lineHash, count, _, err := c.line(lo)
+	if err != nil || lineHash != hash {
+		return 0, false, err
+	}
+	n, err := strconv.ParseInt(count, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid count of hash %s: %w", hash, err)
+	}
+	return n, true, nil
+}
+
+// lineStart returns the start of the first line that starts at or after the given offset
+func (c *sortedCorpus) lineStart(off int64) (int64, error) {
+	if off == 0 {
+		return 0, nil
+	}
+	block, err := c.block(off - 1)
+	if err != nil {
+		return 0, err
+	}
+	i := bytes.IndexByte(block, '\n')
+	if i < 0 {
+		if off-1+int64(len(block)) >= c.size {
+			return c.size, nil
+		}
+		return 0, errors.New("line too long")
+	}
+	return off + int64(i), nil
+}
+
+// line returns the lower case hash and the count of the line at the given start and the start of
+// the next line
+func (c *sortedCorpus) line(start int64) (string, string, int64, error) {
+	block, err := c.block(start)
+	if err != nil {
+		return "", "", 0, err
+	}
+	next := start + int64(len(block))
+	if i := bytes.IndexByte(block, '\n'); i >= 0 {
+		block, next = block[:i], start+int64(i)+1
+	} else if next < c.size {
+		return "", "", 0, errors.New("line too long")
+	}
+	hash, count, _ := strings.Cut(strings.TrimSuffix(string(block), "\r"), ":")
+	return strings.ToLower(hash), count, next, nil
+}
+
+// block reads the block of the corpus file at the given offset. The block is shorter than
+// corpusBlockSize at the end of the file
+func (c *sortedCorpus) block(off int64) ([]byte, error) {
+	n, err := c.r.ReadAt(c.buf[:], off)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return c.buf[:n], nil
+}
+
+// hashPrefixes returns the sorted and de-duplicated 5 character prefixes of the given hashes
+func hashPrefixes(hashes []string) []string {
+	seen := make(map[string]bool)
+	var prefixes []string
+	for _, h := range hashes {
+		if len(h) < 5 {
+			continue
+		}
+		prefix := strings.ToLower(h[:5])
+		if seen[prefix] {
+			continue
+		}
+		seen[prefix] = true
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wneessen/go-hibp"
+)
+
+// testMapSource is a Source that looks up the hashes in a map
+type testMapSource map[string]int64
+
+// Lookup satisfies the Source interface for the testMapSource type
+func (s testMapSource) Lookup(hashes []string) (map[string]int64, error) {
+	found := make(map[string]int64)
+	for _, h := range hashes {
+		if count, ok := s[h]; ok {
+			found[h] = count
+		}
+	}
+	return found, nil
+}
+
+// testRangeClient is a hibp.HTTPClient that serves range responses from a map of hash prefixes and
+// records the requested URLs
+type testRangeClient struct {
+	mu       sync.Mutex
+	ranges   map[string]string
+	requests []string
+}
+
+// Do satisfies the hibp.HTTPClient interface for the testRangeClient type
+func (c *testRangeClient) Do(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req.URL.String())
+	prefix := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+	body, ok := c.ranges[prefix]
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+	return &http.Response{
+		Status:     http.StatusText(status),
+		StatusCode: status,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func TestAPISource_Lookup(t *testing.T) {
+	t.Run("hashes are looked up with one request per prefix and hash mode", func(t *testing.T) {
+		client := &testRangeClient{ranges: map[string]string{
+			"8846f": "7EAEE8FB117AD06BDD830B7586C:3000\r\n7EAEE8FB117AD06BDD830B7586D:0\r\n",
+			"0cb69": "48805F797BF2A82807973B89537:1200\r\n",
+			"a94a8": "FE5CCB19BA61C4C0873D391E987982FBBD3:86495\r\n",
+		}}
+		hc := hibp.New(hibp.WithHTTPClient(client))
+		src := NewAPISource(&hc)
+		found, err := src.Lookup([]string{
+			testNTHashPassword, "8846f7eaee8fb117ad06bdd830b7586d", testNTHashTest,
+			"a94a8fe5ccb19ba61c4c0873d391e987982fbbd3",
+		})
+		if err != nil {
+			t.Fatalf("lookup failed: %s", err)
+		}
+		expected := map[string]int64{
+			testNTHashPassword: 3000, testNTHashTest: 1200, "a94a8fe5ccb19ba61c4c0873d391e987982fbbd3": 86495,
+		}
+		if len(found) != len(expected) {
+			t.Errorf("expected %d hashes to be found, got %d", len(expected), len(found))
+		}
+		for h, count := range expected {
+			if found[h] != count {
+				t.Errorf("expected count of %s to be %d, got %d", h, count, found[h])
+			}
+		}
+		if len(client.requests) != 3 {
+			t.Errorf("expected %d requests, got %d: %v", 3, len(client.requests), client.requests)
+		}
+		for _, u := range client.requests {
+			isNTLM := strings.Contains(u, "mode=ntlm")
+			if strings.Contains(u, "a94a8") == isNTLM {
+				t.Errorf("request used the wrong hash mode: %s", u)
+			}
+		}
+		if hc.PwnedPassAPIOpts.HashMode != hibp.HashModeSHA1 {
+			t.Errorf("expected hash mode of the client to be unchanged, got %d", hc.PwnedPassAPIOpts.HashMode)
+		}
+	})
+	t.Run("lookup fails on HTTP errors", func(t *testing.T) {
+		hc := hibp.New(hibp.WithHTTPClient(&testRangeClient{}))
+		_, err := NewAPISource(&hc).Lookup([]string{testNTHashPassword})
+		if !errors.Is(err, hibp.ErrNonPositiveResponse) {
+			t.Errorf("expected error to be %s, got %v", hibp.ErrNonPositiveResponse, err)
+		}
+	})
+	t.Run("lookup fails without client", func(t *testing.T) {
+		_, err := NewAPISource(nil).Lookup([]string{testNTHashPassword})
+		if !errors.Is(err, ErrNoClient) {
+			t.Errorf("expected error to be %s, got %v", ErrNoClient, err)
+		}
+	})
+}
+
+func TestCorpusSource_Lookup(t *testing.T) {
+	t.Run("hashes are looked up in the corpus file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "pwnedpasswords_ntlm.txt")
+		corpus := "0CB6948805F797BF2A82807973B89537:1200\r\n8846F7EAEE8FB117AD06BDD830B7586C:3000\r\n"
+		if err := os.WriteFile(path, []byte(corpus), 0o600); err != nil {
+			t.Fatalf("failed to write corpus file: %s", err)
+		}
+		found, err := NewCorpusSource(path).Lookup([]string{testNTHashPassword, testNTHashUnknown})
+		if err != nil {
+			t.Fatalf("lookup failed: %s", err)
+		}
+		if len(found) != 1 || found[testNTHashPassword] != 3000 {
+			t.Errorf("expected only %s to be found with count %d, got %v", testNTHashPassword, 3000, found)
+		}
+	})
+	t.Run("hashes are searched in a large corpus file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "pwnedpasswords_ntlm.txt")
+		var corpus strings.Builder
+		hashes := make([]string, 0, 5000)
+		for i := 0; i < 5000; i++ {
+			hash := fmt.Sprintf("%032X", i*2)
+			hashes = append(hashes, strings.ToLower(hash))
+			corpus.WriteString(fmt.Sprintf("%s:%d", hash, i+1))
+			if i < 4999 {
+				corpus.WriteString("\r\n")
+			}
+		}
+		if err := os.WriteFile(path, []byte(corpus.String()), 0o600); err != nil {
+			t.Fatalf("failed to write corpus file: %s", err)
+		}
+		missing := []string{fmt.Sprintf("%032x", 1), fmt.Sprintf("%032x", 4567), fmt.Sprintf("%032x", 20000)}
+		found, err := NewCorpusSource(path).Lookup(append(append([]string{}, hashes...), missing...))
+		if err != nil {
+			t.Fatalf("lookup failed: %s", err)
+		}
+		if len(found) != len(hashes) {
+			t.Errorf("expected %d hashes to be found, got %d", len(hashes), len(found))
+		}
+		for i, hash := range hashes {
+			if found[hash] != int64(i+1) {
+				t.Errorf("expected %s to be found with count %d, got %d", hash, i+1, found[hash])
+			}
+		}
+	})
+	t.Run("lookup fails on missing corpus file", func(t *testing.T) {
+		_, err := NewCorpusSource(filepath.Join(t.TempDir(), "missing.txt")).Lookup([]string{testNTHashTest})
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected error to be %s, got %v", os.ErrNotExist, err)
+		}
+	})
+}
@@ -5,9 +5,9 @@
 package hibp
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/wneessen/niljson"
@@ -17,11 +17,15 @@ import (
 type BreachAPI struct {
 	hibp *Client // References back to the parent HIBP client
 
-	domain       string // Filter for a specific breach domain
-	disableTrunc bool   // Controls the truncateResponse parameter for the breaches API (defaults to false)
-	noUnverified bool   // Controls the includeUnverified parameter for the breaches API (defaults to false)
+	catalogueMu   sync.Mutex        // Mutex to protect the breach catalogue cache
+	catalogue     map[string]Breach // Cached catalogue of all breaches, keyed by the breach name
+	catalogueTime time.Time         // Time the breach catalogue was last fetched from the API
 }
 
+// BreachCatalogueTTL is the duration the cached breach catalogue is considered fresh before it is
+// fetched again from the breaches API
+const BreachCatalogueTTL = time.Hour
+
 // Breach represents a JSON response structure of the breaches API
 type Breach struct {
 	// Name is a pascal-cased name representing the breach which is unique across all other breaches.
@@ -95,6 +99,10 @@ type Breach struct {
 	// present is an internal indicator. It is set to true if the Breach was returned by the HIBP API.
 	// It can be used to make sure if a returned Breach was empty or not.
 	present bool
+
+	// truncated is an internal indicator. It is set to true if the Breach was returned by the HIBP API
+	// with a truncated response, so that only the Name attribute holds a value.
+	truncated bool
 }
 
 type SubscribedDomains struct {
@@ -122,23 +130,32 @@ type SubscribedDomains struct {
 	NextSubscriptionRenewal APIDate `json:"NextSubscriptionRenewal"`
 }
 
-// BreachOption is an additional option the can be set for the BreachApiClient
-type BreachOption func(*BreachAPI)
+// breachOpts holds the options for a single request to the breaches API. It is created for each
+// request, so that options of one request can not leak into another one
+type breachOpts struct {
+	domain       string // Filter for a specific breach domain
+	disableTrunc bool   // Controls the truncateResponse parameter for the breaches API (defaults to false)
+	noUnverified bool   // Controls the includeUnverified parameter for the breaches API (defaults to false)
+	hydrate      bool   // Controls if truncated breaches are hydrated from the breach catalogue (defaults to false)
+}
+
+// BreachOption is an additional option the can be set for a request to the breaches API
+type BreachOption func(*breachOpts)
 
 // Breaches returns a list of all breaches in the HIBP system
 //
 // Reference: https://haveibeenpwned.com/API/v3#AllBreaches
 func (b *BreachAPI) Breaches(options ...BreachOption) ([]Breach, *http.Response, error) {
-	qp := b.setBreachOpts(options...)
-	au := fmt.Sprintf("%s/breaches", BaseURL)
-
-	hb, hr, err := b.hibp.HTTPResBody(http.MethodGet, au, qp)
-	if err != nil {
-		return nil, hr, err
-	}
+	qp, _ := setBreachOpts(options...)
+	return b.fetchBreaches(qp)
+}
 
+// fetchBreaches performs the API call to the breaches endpoint with the given query parameters
+func (b *BreachAPI) fetchBreaches(qp map[string]string) ([]Breach, *http.Response, error) {
 	var bl []Breach
-	if err = json.Unmarshal(hb, &bl); err != nil {
+	au := fmt.Sprintf("%s/breaches", BaseURL)
+	hr, err := b.hibp.getJSON(apiRequest{url: au, query: qp}, &bl)
+	if err != nil {
 		return nil, hr, err
 	}
 	for i := range bl {
@@ -148,11 +165,12 @@ func (b *BreachAPI) Breaches(options ...BreachOption) ([]Breach, *http.Response,
 	return bl, hr, nil
 }
 
-// BreachByName returns a single breached site based on its name
+// BreachByName returns a single breached site based on its name. If there is no breach with the
+// given name, the returned error matches ErrNotFound
 //
 // Reference: https://haveibeenpwned.com/API/v3#SingleBreach
 func (b *BreachAPI) BreachByName(n string, options ...BreachOption) (Breach, *http.Response, error) {
-	qp := b.setBreachOpts(options...)
+	qp, _ := setBreachOpts(options...)
 	var bd Breach
 
 	if n == "" {
@@ -160,14 +178,10 @@ func (b *BreachAPI) BreachByName(n string, options ...BreachOption) (Breach, *ht
 	}
 
 	au := fmt.Sprintf("%s/breach/%s", BaseURL, n)
-	hb, hr, err := b.hibp.HTTPResBody(http.MethodGet, au, qp)
+	hr, err := b.hibp.getJSON(apiRequest{url: au, query: qp}, &bd)
 	if err != nil {
 		return bd, hr, err
 	}
-
-	if err = json.Unmarshal(hb, &bd); err != nil {
-		return bd, hr, err
-	}
 	bd.present = true
 
 	return bd, hr, nil
@@ -179,14 +193,10 @@ func (b *BreachAPI) BreachByName(n string, options ...BreachOption) (Breach, *ht
 func (b *BreachAPI) LatestBreach() (Breach, *http.Response, error) {
 	var bd Breach
 	au := fmt.Sprintf("%s/latestbreach", BaseURL)
-	hb, hr, err := b.hibp.HTTPResBody(http.MethodGet, au, nil)
+	hr, err := b.hibp.getJSON(apiRequest{url: au}, &bd)
 	if err != nil {
 		return bd, hr, err
 	}
-
-	if err = json.Unmarshal(hb, &bd); err != nil {
-		return bd, hr, err
-	}
 	bd.present = true
 
 	return bd, hr, nil
@@ -197,98 +207,107 @@ func (b *BreachAPI) LatestBreach() (Breach, *http.Response, error) {
 //
 // Reference: https://haveibeenpwned.com/API/v3#AllDataClasses
 func (b *BreachAPI) DataClasses() ([]string, *http.Response, error) {
+	var dc []string
 	au := fmt.Sprintf("%s/dataclasses", BaseURL)
-	hb, hr, err := b.hibp.HTTPResBody(http.MethodGet, au, nil)
+	hr, err := b.hibp.getJSON(apiRequest{url: au}, &dc)
 	if err != nil {
 		return nil, hr, err
 	}
 
-	var dc []string
-	if err = json.Unmarshal(hb, &dc); err != nil {
-		return nil, hr, err
-	}
-
 	return dc, hr, nil
 }
 
 // BreachedAccount returns all breaches for an account
-// This API is authenticated and requires a valid API key
+// This API is authenticated and requires a valid API key. If the account has not been found in any
+// breach, an empty list is returned without error. The account is normalized with NormalizeAccount
+// and path escaped for the request.
+//
+// By default, the API truncates the response, so that each returned Breach only has the Name
+// attribute set. Use the WithoutTruncate option to retrieve the full breach details from the API
+// or the WithHydration option to fill in the details from the cached breach catalogue instead.
 //
 // Reference: https://haveibeenpwned.com/API/v3#BreachesForAccount
 func (b *BreachAPI) BreachedAccount(a string, options ...BreachOption) ([]Breach, *http.Response, error) {
-	var bd []Breach
 	if err := requiresAPIKey(b.hibp); err != nil {
-		return bd, nil, err
-	}
-	qp := b.setBreachOpts(options...)
-
-	if a == "" {
-		return nil, nil, ErrNoAccountID
+		return nil, nil, err
 	}
+	qp, opts := setBreachOpts(options...)
 
-	au := fmt.Sprintf("%s/breachedaccount/%s", BaseURL, a)
-	hb, hr, err := b.hibp.HTTPResBody(http.MethodGet, au, qp)
+	a, err := NormalizeAccount(a)
 	if err != nil {
-		if hr != nil && hr.StatusCode == http.StatusNotFound {
-			return bd, nil, nil
-		}
-		return nil, hr, err
+		return nil, nil, err
 	}
 
-	if err = json.Unmarshal(hb, &bd); err != nil {
+	var bd []Breach
+	au := fmt.Sprintf("%s/breachedaccount/%s", BaseURL, escapeAccount(a))
+	hr, err := b.hibp.getJSON(apiRequest{url: au, query: qp, auth: true, notFound: true}, &bd)
+	if err != nil {
 		return nil, hr, err
 	}
 	for i := range bd {
 		bd[i].present = true
+		bd[i].truncated = qp["truncateResponse"] == "true"
+	}
+
+	if opts.hydrate && qp["truncateResponse"] == "true" {
+		if err = b.hydrateBreaches(bd); err != nil {
+			return bd, hr, err
+		}
 	}
 
 	return bd, hr, nil
 }
 
+// BreachedPhoneNumber returns all breaches for a phone number in international E.164 format. The
+// phone number is normalized with NormalizePhoneNumber and looked up with BreachedAccount.
+// This API is authenticated and requires a valid API key
+func (b *BreachAPI) BreachedPhoneNumber(number string, options ...BreachOption) ([]Breach, *http.Response, error) {
+	account, err := NormalizePhoneNumber(number)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b.BreachedAccount(account, options...)
+}
+
+// BreachedUsername returns all breaches for a username. The username is validated with
+// NormalizeUsername and looked up with BreachedAccount.
+// This API is authenticated and requires a valid API key
+func (b *BreachAPI) BreachedUsername(username string, options ...BreachOption) ([]Breach, *http.Response, error) {
+	account, err := NormalizeUsername(username)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b.BreachedAccount(account, options...)
+}
+
 // SubscribedDomains returns domains that have been successfully added to the domain
 // search dashboard after verifying control are returned via this API.
 // This API is authenticated and requires a valid API key.
 //
 // Reference: https://haveibeenpwned.com/API/v3#SubscribedDomains
 func (b *BreachAPI) SubscribedDomains() ([]SubscribedDomains, *http.Response, error) {
-	if err := requiresAPIKey(b.hibp); err != nil {
-		return nil, nil, err
-	}
+	var bd []SubscribedDomains
 	au := fmt.Sprintf("%s/subscribeddomains", BaseURL)
-	hb, hr, err := b.hibp.HTTPResBody(http.MethodGet, au, nil)
+	hr, err := b.hibp.getJSON(apiRequest{url: au, auth: true}, &bd)
 	if err != nil {
 		return nil, hr, err
 	}
 
-	var bd []SubscribedDomains
-	if err = json.Unmarshal(hb, &bd); err != nil {
-		return nil, hr, err
-	}
-
 	return bd, hr, nil
 }
 
 // BreachedDomain returns all email addresses on a given domain and the breaches they've appeared
 // in can be returned via the domain search API. Only domains that have been successfully added
 // to the domain search dashboard after verifying control can be searched.
-// This API is authenticated and requires a valid API key.
+// This API is authenticated and requires a valid API key. If no email address of the domain has
+// been found in any breach, an empty map is returned without error.
 //
 // https://haveibeenpwned.com/API/v3#BreachesForDomain
 func (b *BreachAPI) BreachedDomain(domain string) (map[string][]string, *http.Response, error) {
-	if err := requiresAPIKey(b.hibp); err != nil {
-		return nil, nil, err
-	}
 	var bd map[string][]string
 	au := fmt.Sprintf("%s/breacheddomain/%s", BaseURL, domain)
-	hb, hr, err := b.hibp.HTTPResBody(http.MethodGet, au, nil)
+	hr, err := b.hibp.getJSON(apiRequest{url: au, auth: true, notFound: true}, &bd)
 	if err != nil {
-		if hr != nil && hr.StatusCode == http.StatusNotFound {
-			return bd, nil, nil
-		}
-		return nil, hr, err
-	}
-
-	if err = json.Unmarshal(hb, &bd); err != nil {
 		return nil, hr, err
 	}
 
@@ -297,56 +316,128 @@ func (b *BreachAPI) BreachedDomain(domain string) (map[string][]string, *http.Re
 
 // WithDomain sets the domain filter for the breaches API
 func WithDomain(d string) BreachOption {
-	return func(b *BreachAPI) {
-		b.domain = d
+	return func(o *breachOpts) {
+		o.domain = d
 	}
 }
 
 // WithoutTruncate disables the truncateResponse parameter in the breaches API
 // This option only influences the BreachedAccount method
 func WithoutTruncate() BreachOption {
-	return func(b *BreachAPI) {
-		b.disableTrunc = true
+	return func(o *breachOpts) {
+		o.disableTrunc = true
 	}
 }
 
 // WithoutUnverified suppress unverified breaches from the query
 func WithoutUnverified() BreachOption {
-	return func(b *BreachAPI) {
-		b.noUnverified = true
+	return func(o *breachOpts) {
+		o.noUnverified = true
+	}
+}
+
+// WithHydration hydrates truncated breaches returned by the BreachedAccount method with the full
+// breach details from the cached breach catalogue. The catalogue is fetched once from the breaches
+// API and kept for the duration of BreachCatalogueTTL, instead of performing a BreachByName call
+// for each of the returned breaches. Breaches that can not be found in the catalogue remain truncated.
+// This option only influences the BreachedAccount method
+func WithHydration() BreachOption {
+	return func(o *breachOpts) {
+		o.hydrate = true
+	}
+}
+
+// ResetCatalogue clears the cached breach catalogue, so that it is fetched again from the breaches
+// API on the next hydration
+func (b *BreachAPI) ResetCatalogue() {
+	b.catalogueMu.Lock()
+	defer b.catalogueMu.Unlock()
+	b.catalogue = nil
+	b.catalogueTime = time.Time{}
+}
+
+// hydrateBreaches replaces the truncated breaches in the given slice with the full breach details
+// from the breach catalogue
+func (b *BreachAPI) hydrateBreaches(bl []Breach) error {
+	cat, err := b.breachCatalogue()
+	if err != nil {
+		return err
+	}
+	for i := range bl {
+		if !bl[i].truncated {
+			continue
+		}
+		if full, ok := cat[bl[i].Name]; ok {
+			bl[i] = full
+		}
+	}
+	return nil
+}
+
+// breachCatalogue returns the cached breach catalogue. If the catalogue has not been fetched yet
+// or is older than BreachCatalogueTTL, it is fetched from the breaches API first
+func (b *BreachAPI) breachCatalogue() (map[string]Breach, error) {
+	b.catalogueMu.Lock()
+	defer b.catalogueMu.Unlock()
+
+	if b.catalogue != nil && time.Since(b.catalogueTime) < BreachCatalogueTTL {
+		b.hibp.cacheHit(CacheBreachCatalogue)
+		return b.catalogue, nil
+	}
+
+	bl, _, err := b.fetchBreaches(map[string]string{"includeUnverified": "true"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch breach catalogue: %w", err)
+	}
+	cat := make(map[string]Breach, len(bl))
+	for _, bd := range bl {
+		cat[bd.Name] = bd
 	}
+	b.catalogue = cat
+	b.catalogueTime = time.Now()
+
+	return cat, nil
 }
 
 // setBreachOpts returns a map of default settings and overridden values from different BreachOption
-func (b *BreachAPI) setBreachOpts(options ...BreachOption) map[string]string {
+// as well as the request options they were derived from
+func setBreachOpts(options ...BreachOption) (map[string]string, breachOpts) {
 	qp := map[string]string{
 		"truncateResponse":  "true",
 		"includeUnverified": "true",
 	}
 
+	var opts breachOpts
 	for _, opt := range options {
 		if opt == nil {
 			continue
 		}
-		opt(b)
+		opt(&opts)
 	}
 
-	if b.domain != "" {
-		qp["domain"] = b.domain
+	if opts.domain != "" {
+		qp["domain"] = opts.domain
 	}
 
-	if b.disableTrunc {
+	if opts.disableTrunc {
 		qp["truncateResponse"] = "false"
 	}
 
-	if b.noUnverified {
+	if opts.noUnverified {
 		qp["includeUnverified"] = "false"
 	}
 
-	return qp
+	return qp, opts
 }
 
 // Present indicates whether the Breach object has been returned by the HIBP API.
 func (b Breach) Present() bool {
 	return b.present
 }
+
+// IsTruncated indicates whether the Breach object was returned by the HIBP API as part of a
+// truncated response. A truncated Breach only holds the Name attribute, all other attributes
+// should not be trusted.
+func (b Breach) IsTruncated() bool {
+	return b.truncated
+}
@@ -9,8 +9,10 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -88,6 +90,9 @@ const (
 	// ServerResponseBreachedDomainBroken represents the file path for a test dataset with a broken breached
 	// domain response.
 	ServerResponseBreachedDomainBroken = "testdata/breacheddomain-broken.txt"
+
+	// TestAPIKey is a placeholder API key for tests that run against the mocked test server.
+	TestAPIKey = "00000000000000000000000000000000"
 )
 
 func TestBreachAPI_Breaches(t *testing.T) {
@@ -291,6 +296,93 @@ func TestBreachAPI_Breaches_using_WithDomain(t *testing.T) {
 	}
 }
 
+func TestBreachAPI_options_do_not_leak(t *testing.T) {
+	t.Run("options of one request do not affect the next request", func(t *testing.T) {
+		handler := newTestQueryHandler(t)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)))
+		if _, _, err := hc.BreachAPI.Breaches(WithDomain("adobe.com"), WithoutTruncate(),
+			WithoutUnverified()); err != nil {
+			t.Fatalf("failed to get breaches: %s", err)
+		}
+		query := handler.LastQuery()
+		if query.Get("domain") != "adobe.com" {
+			t.Errorf("expected domain filter to be %q, got %q", "adobe.com", query.Get("domain"))
+		}
+		if query.Get("truncateResponse") != "false" {
+			t.Errorf("expected truncateResponse to be %q, got %q", "false", query.Get("truncateResponse"))
+		}
+		if query.Get("includeUnverified") != "false" {
+			t.Errorf("expected includeUnverified to be %q, got %q", "false", query.Get("includeUnverified"))
+		}
+
+		if _, _, err := hc.BreachAPI.Breaches(); err != nil {
+			t.Fatalf("failed to get breaches: %s", err)
+		}
+		query = handler.LastQuery()
+		if query.Has("domain") {
+			t.Errorf("expected no domain filter, got %q", query.Get("domain"))
+		}
+		if query.Get("truncateResponse") != "true" {
+			t.Errorf("expected truncateResponse to be %q, got %q", "true", query.Get("truncateResponse"))
+		}
+		if query.Get("includeUnverified") != "true" {
+			t.Errorf("expected includeUnverified to be %q, got %q", "true", query.Get("includeUnverified"))
+		}
+	})
+	t.Run("options of one method do not affect another method", func(t *testing.T) {
+		handler := newTestQueryHandler(t)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)))
+		if _, _, err := hc.BreachAPI.Breaches(WithDomain("adobe.com")); err != nil {
+			t.Fatalf("failed to get breaches: %s", err)
+		}
+		if _, _, err := hc.BreachAPI.BreachByName("Adobe"); err == nil {
+			t.Fatal("expected BreachByName to fail on array response")
+		}
+		if query := handler.LastQuery(); query.Has("domain") {
+			t.Errorf("expected no domain filter, got %q", query.Get("domain"))
+		}
+	})
+	t.Run("options do not leak between concurrent requests", func(t *testing.T) {
+		handler := newTestQueryHandler(t)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			domain := ""
+			if i%2 == 0 {
+				domain = fmt.Sprintf("domain%d.tld", i)
+			}
+			wg.Add(1)
+			go func(domain string) {
+				defer wg.Done()
+				var options []BreachOption
+				if domain != "" {
+					options = append(options, WithDomain(domain))
+				}
+				breaches, _, err := hc.BreachAPI.Breaches(options...)
+				if err != nil {
+					t.Errorf("failed to get breaches: %s", err)
+					return
+				}
+				if len(breaches) != 1 {
+					t.Errorf("expected %d breach, got %d", 1, len(breaches))
+					return
+				}
+				if breaches[0].Domain != domain {
+					t.Errorf("expected request to be filtered by domain %q, got %q", domain, breaches[0].Domain)
+				}
+			}(domain)
+		}
+		wg.Wait()
+	})
+}
+
 func TestBreachAPI_BreachByName(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -514,6 +606,116 @@ func TestBreachAPI_BreachedAccount(t *testing.T) {
 	})
 }
 
+func TestBreachAPI_BreachedAccount_withHydration(t *testing.T) {
+	email := "toni.tester@domain.tld"
+	routes := map[string]string{
+		"/api/v3/breaches":                 ServerResponseBreachesAllNonTruncatedUnverified,
+		"/api/v3/breachedaccount/" + email: fmt.Sprintf(ServerResponseBreachAccount, email),
+	}
+	t.Run("truncated breaches are marked as truncated", func(t *testing.T) {
+		handler := newTestRouteHandler(t, routes)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+		breaches, _, err := hc.BreachAPI.BreachedAccount(email)
+		if err != nil {
+			t.Fatalf("failed to get breached account: %s", err)
+		}
+		if len(breaches) != 5 {
+			t.Fatalf("expected %d breaches, got %d", 5, len(breaches))
+		}
+		for _, breach := range breaches {
+			if !breach.IsTruncated() {
+				t.Errorf("expected breach %q to be truncated", breach.Name)
+			}
+		}
+		if hits := handler.Hits("/api/v3/breaches"); hits != 0 {
+			t.Errorf("expected breach catalogue not to be fetched, got %d requests", hits)
+		}
+	})
+	t.Run("truncated breaches are hydrated from the catalogue", func(t *testing.T) {
+		handler := newTestRouteHandler(t, routes)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+		breaches, _, err := hc.BreachAPI.BreachedAccount(email, WithHydration())
+		if err != nil {
+			t.Fatalf("failed to get breached account: %s", err)
+		}
+		if len(breaches) != 5 {
+			t.Fatalf("expected %d breaches, got %d", 5, len(breaches))
+		}
+		for _, breach := range breaches {
+			if breach.IsTruncated() {
+				t.Errorf("expected breach %q to be hydrated", breach.Name)
+			}
+			if !breach.Present() {
+				t.Errorf("expected breach %q to be present", breach.Name)
+			}
+			if breach.Title == "" || breach.AddedDate.IsZero() {
+				t.Errorf("expected breach %q to hold the full breach details", breach.Name)
+			}
+		}
+		if breaches[0].Domain != "dropbox.com" {
+			t.Errorf("expected breach domain to be %q, got %q", "dropbox.com", breaches[0].Domain)
+		}
+	})
+	t.Run("breach catalogue is only fetched once", func(t *testing.T) {
+		handler := newTestRouteHandler(t, routes)
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+		for i := 0; i < 3; i++ {
+			if _, _, err := hc.BreachAPI.BreachedAccount(email, WithHydration()); err != nil {
+				t.Fatalf("failed to get breached account: %s", err)
+			}
+		}
+		if hits := handler.Hits("/api/v3/breaches"); hits != 1 {
+			t.Errorf("expected breach catalogue to be fetched %d time, got %d", 1, hits)
+		}
+		hc.BreachAPI.ResetCatalogue()
+		if _, _, err := hc.BreachAPI.BreachedAccount(email, WithHydration()); err != nil {
+			t.Fatalf("failed to get breached account: %s", err)
+		}
+		if hits := handler.Hits("/api/v3/breaches"); hits != 2 {
+			t.Errorf("expected breach catalogue to be fetched %d times after reset, got %d", 2, hits)
+		}
+	})
+	t.Run("non-truncated breaches skip the hydration", func(t *testing.T) {
+		handler := newTestRouteHandler(t, map[string]string{
+			"/api/v3/breachedaccount/" + email: ServerResponseBreachesAllNonTruncatedUnverified,
+		})
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+		breaches, _, err := hc.BreachAPI.BreachedAccount(email, WithHydration(), WithoutTruncate())
+		if err != nil {
+			t.Fatalf("failed to get breached account: %s", err)
+		}
+		if len(breaches) == 0 || breaches[0].IsTruncated() {
+			t.Error("expected non-truncated breaches")
+		}
+		if hits := handler.Hits("/api/v3/breaches"); hits != 0 {
+			t.Errorf("expected breach catalogue not to be fetched, got %d requests", hits)
+		}
+	})
+	t.Run("hydration fails if the catalogue can not be fetched", func(t *testing.T) {
+		handler := newTestRouteHandler(t, map[string]string{
+			"/api/v3/breachedaccount/" + email: fmt.Sprintf(ServerResponseBreachAccount, email),
+		})
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+		breaches, _, err := hc.BreachAPI.BreachedAccount(email, WithHydration())
+		if err == nil {
+			t.Error("expected hydration to fail")
+		}
+		if len(breaches) != 5 {
+			t.Errorf("expected the truncated breaches to be returned, got %d", len(breaches))
+		}
+	})
+}
+
 func TestBreachAPI_SubscribedDomains(t *testing.T) {
 	apiKey := os.Getenv("HIBP_API_KEY")
 	if apiKey == "" {
@@ -704,3 +906,36 @@ func ExampleBreachAPI_BreachedAccount() {
 		fmt.Printf("Your account was part of the %q breach\n", b.Name)
 	}
 }
+
+// testQueryHandler is an HTTP handler that records the query of the last request. It responds
+// with a single breach, holding the domain filter of the request as domain.
+type testQueryHandler struct {
+	t     *testing.T
+	mu    sync.Mutex
+	query url.Values
+}
+
+// newTestQueryHandler creates a new testQueryHandler.
+func newTestQueryHandler(t *testing.T) *testQueryHandler {
+	t.Helper()
+	return &testQueryHandler{t: t}
+}
+
+// ServeHTTP satisfies the http.Handler interface for the testQueryHandler type.
+func (h *testQueryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.query = r.URL.Query()
+	h.mu.Unlock()
+
+	_, err := fmt.Fprintf(w, `[{"Name":"Test","Domain":%q}]`, r.URL.Query().Get("domain"))
+	if err != nil {
+		h.t.Errorf("query handler failed to write response: %s", err)
+	}
+}
+
+// LastQuery returns the query of the last request served by the testQueryHandler.
+func (h *testQueryHandler) LastQuery() url.Values {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.query
+}
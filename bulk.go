@@ -0,0 +1,524 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBulkRetries is the default number of retries of a bulk account lookup that was rate
+	// limited by the API
+	DefaultBulkRetries = 3
+
+	// DefaultBulkConcurrency is the default number of concurrent requests of a bulk account lookup
+	DefaultBulkConcurrency = 4
+)
+
+var (
+	// ErrBulkRateLimit is returned if the rate limit of a bulk account lookup or domain search can not
+	// be determined
+	ErrBulkRateLimit = errors.New("subscription does not provide a rate limit")
+
+	// ErrBulkStopped is the error of the accounts that were not looked up, because the bulk account
+	// lookup was stopped
+	ErrBulkStopped = errors.New("bulk account lookup stopped")
+)
+
+// AccountIterator iterates over the accounts of a bulk account lookup
+type AccountIterator interface {
+	// Next advances the AccountIterator to the next account. It returns false when there are no
+	// more accounts or an error occurred
+	Next() bool
+
+	// Account returns the current account
+	Account() string
+
+	// Err returns the first error that occurred during the iteration
+	Err() error
+}
+
+// accountSlice is an AccountIterator over a slice of accounts
+type accountSlice struct {
+	accounts []string
+	pos      int
+}
+
+// NewAccountSlice returns an AccountIterator over the given accounts
+func NewAccountSlice(accounts []string) AccountIterator {
+	return &accountSlice{accounts: accounts}
+}
+
+// Next satisfies the AccountIterator interface for the accountSlice type
+func (a *accountSlice) Next() bool {
+	if a.pos >= len(a.accounts) {
+		return false
+	}
+	a.pos++
+	return true
+}
+
+// Account satisfies the AccountIterator interface for the accountSlice type
+func (a *accountSlice) Account() string {
+	return a.accounts[a.pos-1]
+}
+
+// Err satisfies the AccountIterator interface for the accountSlice type
+func (a *accountSlice) Err() error {
+	return nil
+}
+
+// accountScanner is an AccountIterator over the lines of an io.Reader
+type accountScanner struct {
+	so      *bufio.Scanner
+	account string
+}
+
+// NewAccountScanner returns an AccountIterator over the lines of the given io.Reader. Each line
+// holds one account. Spaces around the accounts are trimmed, empty lines and lines starting
+// with "#" are skipped
+func NewAccountScanner(r io.Reader) AccountIterator {
+	return &accountScanner{so: bufio.NewScanner(r)}
+}
+
+// Next satisfies the AccountIterator interface for the accountScanner type
+func (a *accountScanner) Next() bool {
+	for a.so.Scan() {
+		line := strings.TrimSpace(a.so.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		a.account = line
+		return true
+	}
+	return false
+}
+
+// Account satisfies the AccountIterator interface for the accountScanner type
+func (a *accountScanner) Account() string {
+	return a.account
+}
+
+// Err satisfies the AccountIterator interface for the accountScanner type
+func (a *accountScanner) Err() error {
+	return a.so.Err()
+}
+
+// BulkResult is the result of the lookup of a single account of a bulk account lookup
+type BulkResult struct {
+	// Index is the position of the account in the AccountIterator, starting at 0
+	Index int
+
+	// Account is the account that was looked up
+	Account string
+
+	// Breaches holds the breaches of the account. It is empty if the account has not been found in
+	// any breach or the lookup failed
+	Breaches []Breach
+
+	// Attempts is the number of requests that were performed for the account, including the retries
+	// of rate limited requests
+	Attempts int
+
+	// Err is the error of the lookup of the account, if it failed
+	Err error
+}
+
+// MarshalJSON satisfies the json.Marshaler interface for the BulkResult type. Only the names of the
+// breaches are included
+func (r BulkResult) MarshalJSON() ([]byte, error) {
+	record := struct {
+		Index    int      `json:"index"`
+		Account  string   `json:"account"`
+		Breaches []string `json:"breaches"`
+		Attempts int      `json:"attempts"`
+		Error    string   `json:"error,omitempty"`
+	}{Index: r.Index, Account: r.Account, Breaches: make([]string, 0, len(r.Breaches)), Attempts: r.Attempts}
+	for _, b := range r.Breaches {
+		record.Breaches = append(record.Breaches, b.Name)
+	}
+	if r.Err != nil {
+		record.Error = r.Err.Error()
+	}
+	return json.Marshal(record)
+}
+
+// NewBulkNDJSONWriter returns a function for BulkBreachedAccounts that writes each BulkResult as
+// line of newline delimited JSON to the given io.Writer
+func NewBulkNDJSONWriter(w io.Writer) func(BulkResult) error {
+	enc := json.NewEncoder(w)
+	return func(r BulkResult) error {
+		return enc.Encode(r)
+	}
+}
+
+// BulkCheckpoint persists the progress of a bulk account lookup, so that an interrupted lookup can
+// be resumed
+type BulkCheckpoint interface {
+	// Load returns the number of accounts that were already processed
+	Load() (int, error)
+
+	// Save stores the number of accounts that were processed
+	Save(processed int) error
+}
+
+// FileCheckpoint is a BulkCheckpoint that stores the progress of a bulk account lookup in a file
+type FileCheckpoint struct {
+	path string
+}
+
+// fileCheckpointData is the JSON content of the file of a FileCheckpoint
+type fileCheckpointData struct {
+	Processed int       `json:"processed"`
+	Updated   time.Time `json:"updated"`
+}
+
+// NewFileCheckpoint returns a new FileCheckpoint that stores the progress in the file with the
+// given path. A missing file means that no accounts were processed yet
+func NewFileCheckpoint(path string) *FileCheckpoint {
+	return &FileCheckpoint{path: path}
+}
+
+// Load satisfies the BulkCheckpoint interface for the FileCheckpoint type
+func (f *FileCheckpoint) Load() (int, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var cp fileCheckpointData
+	if err = json.Unmarshal(data, &cp); err != nil {
+		return 0, fmt.Errorf("failed to parse checkpoint file: %w", err)
+	}
+	return cp.Processed, nil
+}
+
+// Save satisfies the BulkCheckpoint interface for the FileCheckpoint type. The checkpoint is written
+// to a temporary file first, so that a crash never leaves a partially written checkpoint
+func (f *FileCheckpoint) Save(processed int) error {
+	data, err := json.Marshal(fileCheckpointData{Processed: processed, Updated: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(f.path, data)
+}
+
+// writeFileAtomic writes the given data to a temporary file in the directory of the given path and
+// renames it to the path, so that a crash never leaves a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// bulkOpts holds the options for a bulk account lookup
+type bulkOpts struct {
+	rpm           int            // Requests per minute, taken from the subscription if not set
+	retries       int            // Retries of rate limited requests
+	concurrency   int            // Concurrent requests
+	checkpoint    BulkCheckpoint // Checkpoint to resume the lookup from
+	breachOptions []BreachOption // Options for the BreachedAccount requests
+}
+
+// BulkOption is a function that sets options for a bulk account lookup
+type BulkOption func(*bulkOpts)
+
+// WithBulkRPM sets the number of requests per minute of a bulk account lookup. By default, the Rpm of
+// the SubscriptionStatus is used. Values below 1 are ignored
+func WithBulkRPM(rpm int) BulkOption {
+	return func(o *bulkOpts) {
+		if rpm > 0 {
+			o.rpm = rpm
+		}
+	}
+}
+
+// WithBulkRetries sets the number of retries of a rate limited request of a bulk account lookup. The
+// default is DefaultBulkRetries. Negative values are ignored
+func WithBulkRetries(retries int) BulkOption {
+	return func(o *bulkOpts) {
+		if retries >= 0 {
+			o.retries = retries
+		}
+	}
+}
+
+// WithBulkConcurrency sets the number of concurrent requests of a bulk account lookup. The requests
+// are still started within the rate limit, concurrency only helps to keep up with the rate limit
+// when requests are slow. The default is DefaultBulkConcurrency. Values below 1 are ignored
+func WithBulkConcurrency(concurrency int) BulkOption {
+	return func(o *bulkOpts) {
+		if concurrency > 0 {
+			o.concurrency = concurrency
+		}
+	}
+}
+
+// WithBulkCheckpoint sets the BulkCheckpoint of a bulk account lookup. The lookup skips the accounts
+// that were already processed according to the BulkCheckpoint and saves the progress after each
+// account
+func WithBulkCheckpoint(checkpoint BulkCheckpoint) BulkOption {
+	return func(o *bulkOpts) {
+		o.checkpoint = checkpoint
+	}
+}
+
+// WithBulkBreachOptions sets the BreachOption for the BreachedAccount requests of a bulk account
+// lookup
+func WithBulkBreachOptions(options ...BreachOption) BulkOption {
+	return func(o *bulkOpts) {
+		o.breachOptions = options
+	}
+}
+
+// bulkJob is a single account of a bulk account lookup
+type bulkJob struct {
+	index   int
+	account string
+}
+
+// BulkBreachedAccounts looks up the breaches of all accounts of the given AccountIterator with the
+// BreachedAccount method. The requests are scheduled within the rate limit of the subscription,
+// which is fetched with the SubscriptionAPI unless WithBulkRPM is set. Rate limited requests are
+// retried after the delay of the Retry-After header.
+// This API is authenticated and requires a valid API key.
+//
+// The given function is called with the BulkResult of each account, in the order of the
+// AccountIterator. Failed lookups are reported with the Err of the BulkResult and do not stop the
+// bulk lookup. The bulk lookup stops if the function returns an error, the API rejects the API key
+// or the BulkCheckpoint can not be saved. Each account is reported at least once: after a crash, the
+// lookup resumes from the BulkCheckpoint, which is saved after the function returned.
+func (b *BreachAPI) BulkBreachedAccounts(accounts AccountIterator, fn func(BulkResult) error,
+	options ...BulkOption,
+) error {
+	if err := requiresAPIKey(b.hibp); err != nil {
+		return err
+	}
+	opts := bulkOpts{retries: DefaultBulkRetries, concurrency: DefaultBulkConcurrency}
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+		option(&opts)
+	}
+	if opts.rpm < 1 {
+		rpm, err := b.subscriptionRPM()
+		if err != nil {
+			return err
+		}
+		opts.rpm = rpm
+	}
+	start := 0
+	if opts.checkpoint != nil {
+		var err error
+		if start, err = opts.checkpoint.Load(); err != nil {
+			return fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+	}
+
+	done := make(chan struct{})
+	pacer := &requestPacer{interval: time.Minute / time.Duration(opts.rpm), done: done}
+	jobs := make(chan bulkJob)
+	results := make(chan BulkResult, opts.concurrency)
+
+	var iterErr error
+	go func() {
+		defer close(jobs)
+		for index := 0; accounts.Next(); index++ {
+			if index < start {
+				continue
+			}
+			select {
+			case jobs <- bulkJob{index: index, account: accounts.Account()}:
+			case <-done:
+				return
+			}
+		}
+		iterErr = accounts.Err()
+	}()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < opts.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				select {
+				case results <- b.bulkLookup(job, pacer, opts):
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var err error
+	stop := func(e error) {
+		err = e
+		close(done)
+	}
+	pending := make(map[int]BulkResult)
+	next := start
+	for result := range results {
+		if err != nil {
+			continue
+		}
+		pending[result.Index] = result
+		for err == nil {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			if fatalAPIError(result.Err) {
+				stop(fmt.Errorf("failed to look up account %q: %w", result.Account, result.Err))
+				break
+			}
+			if e := fn(result); e != nil {
+				stop(e)
+				break
+			}
+			next++
+			if opts.checkpoint == nil {
+				continue
+			}
+			if e := opts.checkpoint.Save(next); e != nil {
+				stop(fmt.Errorf("failed to save checkpoint: %w", e))
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if iterErr != nil {
+		return fmt.Errorf("failed to iterate accounts: %w", iterErr)
+	}
+	return nil
+}
+
+// bulkLookup looks up the breaches of a single account of a bulk account lookup and retries rate
+// limited requests
+func (b *BreachAPI) bulkLookup(job bulkJob, pacer *requestPacer, opts bulkOpts) BulkResult {
+	result := BulkResult{Index: job.index, Account: job.account}
+	for {
+		if !pacer.wait() {
+			result.Err = ErrBulkStopped
+			return result
+		}
+		result.Attempts++
+		breaches, hr, err := b.BreachedAccount(job.account, opts.breachOptions...)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests &&
+			result.Attempts <= opts.retries {
+			pacer.backoff(retryAfter(hr, result.Attempts))
+			continue
+		}
+		result.Breaches, result.Err = breaches, err
+		return result
+	}
+}
+
+// subscriptionRPM returns the number of requests per minute of the subscription of the API key
+func (b *BreachAPI) subscriptionRPM() (int, error) {
+	status, _, err := b.hibp.SubscriptionAPI.Status()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get subscription rate limit: %w", err)
+	}
+	if status.Rpm < 1 {
+		return 0, ErrBulkRateLimit
+	}
+	return status.Rpm, nil
+}
+
+// fatalAPIError checks if the given error of a single request makes all other requests fail, so that
+// a bulk account lookup or domain search has to stop
+func fatalAPIError(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden
+	}
+	return errors.Is(err, ErrMethodRequiresAPIKey)
+}
+
+// retryAfter returns the delay of the Retry-After header of the given HTTP response. Without a valid
+// header, the delay grows exponentially with the number of attempts
+func retryAfter(hr *http.Response, attempts int) time.Duration {
+	if hr != nil {
+		if seconds, err := strconv.Atoi(hr.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return time.Second << attempts
+}
+
+// requestPacer schedules requests at a fixed interval, shared by all concurrent requests
+type requestPacer struct {
+	mu       sync.Mutex
+	interval time.Duration // Interval between two requests
+	next     time.Time     // Time of the next free request slot
+	done     <-chan struct{}
+}
+
+// wait blocks until the next request slot. It returns false if the requests were stopped
+func (p *requestPacer) wait() bool {
+	p.mu.Lock()
+	now := time.Now()
+	slot := p.next
+	if slot.Before(now) {
+		slot = now
+	}
+	p.next = slot.Add(p.interval)
+	p.mu.Unlock()
+
+	timer := time.NewTimer(slot.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// backoff delays all further request slots by the given duration
+func (p *requestPacer) backoff(delay time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if resume := time.Now().Add(delay); resume.After(p.next) {
+		p.next = resume
+	}
+}
@@ -0,0 +1,303 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testBulkHandler is an HTTP handler for the breached account endpoint that responds based on the
+// local part of the account: "breached" accounts have breaches, "failing" accounts fail with HTTP 500,
+// "limited" accounts are rate limited once and all other accounts are not found
+type testBulkHandler struct {
+	mu       sync.Mutex
+	requests map[string]int
+	status   int // If set, all requests fail with this status code
+}
+
+// newTestBulkHandler returns a new testBulkHandler
+func newTestBulkHandler() *testBulkHandler {
+	return &testBulkHandler{requests: make(map[string]int)}
+}
+
+// ServeHTTP satisfies the http.Handler interface for the testBulkHandler type
+func (h *testBulkHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api/v3/subscription/status" {
+		http.ServeFile(w, r, ServerResponseSubscriptionStatus)
+		return
+	}
+	account := strings.TrimPrefix(r.URL.Path, "/api/v3/breachedaccount/")
+	h.mu.Lock()
+	h.requests[account]++
+	requests := h.requests[account]
+	h.mu.Unlock()
+	if h.status != 0 {
+		w.WriteHeader(h.status)
+		return
+	}
+	switch {
+	case strings.HasPrefix(account, "breached"):
+		_, _ = w.Write([]byte(`[{"Name":"Adobe"},{"Name":"Dropbox"}]`))
+	case strings.HasPrefix(account, "failing"):
+		w.WriteHeader(http.StatusInternalServerError)
+	case strings.HasPrefix(account, "limited") && requests == 1:
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	case strings.HasPrefix(account, "limited"):
+		_, _ = w.Write([]byte(`[{"Name":"Adobe"}]`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// Requests returns the number of requests for the given account
+func (h *testBulkHandler) Requests(account string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.requests[account]
+}
+
+func TestBreachAPI_BulkBreachedAccounts(t *testing.T) {
+	accounts := []string{
+		"breached1@example.com", "clean@example.com", "failing@example.com", "limited@example.com",
+		"breached2@example.com",
+	}
+	t.Run("results are reported in order", func(t *testing.T) {
+		handler := newTestBulkHandler()
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+		var results []BulkResult
+		err := hc.BreachAPI.BulkBreachedAccounts(NewAccountSlice(accounts), func(r BulkResult) error {
+			results = append(results, r)
+			return nil
+		}, WithBulkRPM(60000), WithBulkConcurrency(3))
+		if err != nil {
+			t.Fatalf("bulk lookup failed: %s", err)
+		}
+		if len(results) != len(accounts) {
+			t.Fatalf("expected %d results, got %d", len(accounts), len(results))
+		}
+		wantBreaches := []int{2, 0, 0, 1, 2}
+		for i, r := range results {
+			if r.Index != i || r.Account != accounts[i] {
+				t.Errorf("expected result %d to be for %s, got %d: %s", i, accounts[i], r.Index, r.Account)
+			}
+			if len(r.Breaches) != wantBreaches[i] {
+				t.Errorf("expected %d breaches for %s, got %d", wantBreaches[i], r.Account, len(r.Breaches))
+			}
+		}
+		if !errors.Is(results[2].Err, ErrNonPositiveResponse) {
+			t.Errorf("expected failing account to report an error, got %v", results[2].Err)
+		}
+		for _, i := range []int{0, 1, 3, 4} {
+			if results[i].Err != nil {
+				t.Errorf("expected lookup of %s to succeed, got %s", results[i].Account, results[i].Err)
+			}
+		}
+		if results[3].Attempts != 2 || handler.Requests("limited@example.com") != 2 {
+			t.Errorf("expected rate limited account to be retried once, got %d attempts", results[3].Attempts)
+		}
+	})
+	t.Run("rate limited requests fail after the retries", func(t *testing.T) {
+		server := httptest.NewServer(newTestBulkHandler())
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+		var result BulkResult
+		err := hc.BreachAPI.BulkBreachedAccounts(NewAccountSlice([]string{"limited@example.com"}),
+			func(r BulkResult) error {
+				result = r
+				return nil
+			}, WithBulkRPM(60000), WithBulkRetries(0))
+		if err != nil {
+			t.Fatalf("bulk lookup failed: %s", err)
+		}
+		var apiErr *APIError
+		if !errors.As(result.Err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("expected rate limit error, got %v", result.Err)
+		}
+	})
+	t.Run("requests are scheduled within the rate limit", func(t *testing.T) {
+		server := httptest.NewServer(newTestBulkHandler())
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+		start := time.Now()
+		err := hc.BreachAPI.BulkBreachedAccounts(NewAccountSlice(accounts[:3]), func(BulkResult) error {
+			return nil
+		}, WithBulkRPM(600), WithBulkConcurrency(3))
+		if err != nil {
+			t.Fatalf("bulk lookup failed: %s", err)
+		}
+		if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+			t.Errorf("expected 3 requests at 600 rpm to take at least 200ms, took %s", elapsed)
+		}
+	})
+	t.Run("rate limit is taken from the subscription", func(t *testing.T) {
+		handler := newTestBulkHandler()
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+		results := 0
+		err := hc.BreachAPI.BulkBreachedAccounts(NewAccountSlice(accounts[:1]), func(BulkResult) error {
+			results++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("bulk lookup failed: %s", err)
+		}
+		if results != 1 {
+			t.Errorf("expected %d result, got %d", 1, results)
+		}
+	})
+	t.Run("rejected API key stops the lookup", func(t *testing.T) {
+		handler := newTestBulkHandler()
+		handler.status = http.StatusUnauthorized
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+		results := 0
+		err := hc.BreachAPI.BulkBreachedAccounts(NewAccountSlice(accounts), func(BulkResult) error {
+			results++
+			return nil
+		}, WithBulkRPM(60000), WithBulkConcurrency(1))
+		if !errors.Is(err, ErrNonPositiveResponse) {
+			t.Errorf("expected lookup to fail with %s, got %v", ErrNonPositiveResponse, err)
+		}
+		if results != 0 {
+			t.Errorf("expected no results, got %d", results)
+		}
+	})
+	t.Run("lookup without API key fails", func(t *testing.T) {
+		hc := New()
+		err := hc.BreachAPI.BulkBreachedAccounts(NewAccountSlice(accounts), func(BulkResult) error {
+			return nil
+		})
+		if !errors.Is(err, ErrMethodRequiresAPIKey) {
+			t.Errorf("expected error to be %s, got %v", ErrMethodRequiresAPIKey, err)
+		}
+	})
+}
+
+func TestBreachAPI_BulkBreachedAccounts_checkpoint(t *testing.T) {
+	accounts := []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"}
+	handler := newTestBulkHandler()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+	checkpoint := NewFileCheckpoint(filepath.Join(t.TempDir(), "checkpoint.json"))
+	errCrash := errors.New("crash")
+
+	var seen []string
+	err := hc.BreachAPI.BulkBreachedAccounts(NewAccountSlice(accounts), func(r BulkResult) error {
+		if r.Index == 2 {
+			return errCrash
+		}
+		seen = append(seen, r.Account)
+		return nil
+	}, WithBulkRPM(60000), WithBulkCheckpoint(checkpoint))
+	if !errors.Is(err, errCrash) {
+		t.Fatalf("expected lookup to stop with callback error, got %v", err)
+	}
+	processed, err := checkpoint.Load()
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %s", err)
+	}
+	if processed != 2 {
+		t.Errorf("expected checkpoint to be at %d, got %d", 2, processed)
+	}
+
+	err = hc.BreachAPI.BulkBreachedAccounts(NewAccountSlice(accounts), func(r BulkResult) error {
+		seen = append(seen, r.Account)
+		return nil
+	}, WithBulkRPM(60000), WithBulkCheckpoint(checkpoint))
+	if err != nil {
+		t.Fatalf("resumed bulk lookup failed: %s", err)
+	}
+	if strings.Join(seen, ",") != strings.Join(accounts, ",") {
+		t.Errorf("expected all accounts to be reported once in order, got %v", seen)
+	}
+	if processed, _ = checkpoint.Load(); processed != len(accounts) {
+		t.Errorf("expected checkpoint to be at %d, got %d", len(accounts), processed)
+	}
+	if handler.Requests("a@example.com") != 1 {
+		t.Errorf("expected processed accounts not to be looked up again, got %d requests",
+			handler.Requests("a@example.com"))
+	}
+}
+
+func TestFileCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	checkpoint := NewFileCheckpoint(path)
+	if processed, err := checkpoint.Load(); err != nil || processed != 0 {
+		t.Errorf("expected missing checkpoint to be 0, got %d, err: %v", processed, err)
+	}
+	if err := checkpoint.Save(42); err != nil {
+		t.Fatalf("failed to save checkpoint: %s", err)
+	}
+	if processed, err := checkpoint.Load(); err != nil || processed != 42 {
+		t.Errorf("expected checkpoint to be 42, got %d, err: %v", processed, err)
+	}
+	if err := os.WriteFile(path, []byte("broken"), 0o600); err != nil {
+		t.Fatalf("failed to write checkpoint file: %s", err)
+	}
+	if _, err := checkpoint.Load(); err == nil {
+		t.Error("expected broken checkpoint to fail")
+	}
+}
+
+func TestNewBulkNDJSONWriter(t *testing.T) {
+	buf := bytes.Buffer{}
+	write := NewBulkNDJSONWriter(&buf)
+	results := []BulkResult{
+		{Index: 0, Account: "a@example.com", Breaches: []Breach{{Name: "Adobe"}}, Attempts: 1},
+		{Index: 1, Account: "b@example.com", Attempts: 1},
+		{Index: 2, Account: "c@example.com", Attempts: 2, Err: errors.New("failed")},
+	}
+	for _, r := range results {
+		if err := write(r); err != nil {
+			t.Fatalf("failed to write result: %s", err)
+		}
+	}
+	want := []string{
+		`{"index":0,"account":"a@example.com","breaches":["Adobe"],"attempts":1}`,
+		`{"index":1,"account":"b@example.com","breaches":[],"attempts":1}`,
+		`{"index":2,"account":"c@example.com","breaches":[],"attempts":2,"error":"failed"}`,
+	}
+	so := bufio.NewScanner(&buf)
+	for i := 0; so.Scan(); i++ {
+		if !json.Valid(so.Bytes()) {
+			t.Errorf("expected line %d to be valid JSON", i)
+		}
+		if so.Text() != want[i] {
+			t.Errorf("expected line %d to be %s, got %s", i, want[i], so.Text())
+		}
+	}
+}
+
+func TestNewAccountScanner(t *testing.T) {
+	input := "# accounts\na@example.com\n\n  b@example.com  \r\n#c@example.com\nd@example.com"
+	it := NewAccountScanner(strings.NewReader(input))
+	var got []string
+	for it.Next() {
+		got = append(got, it.Account())
+	}
+	if it.Err() != nil {
+		t.Errorf("account scanner failed: %s", it.Err())
+	}
+	if strings.Join(got, ",") != "a@example.com,b@example.com,d@example.com" {
+		t.Errorf("unexpected accounts: %v", got)
+	}
+}
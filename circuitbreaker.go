@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCircuitBreakerThreshold is the default number of consecutive failures after which the
+	// circuit breaker opens
+	DefaultCircuitBreakerThreshold = 5
+
+	// DefaultCircuitBreakerCooldown is the default duration the circuit breaker stays open before it
+	// allows a probe request
+	DefaultCircuitBreakerCooldown = time.Second * 30
+)
+
+// ErrCircuitOpen is returned if a request was not performed, because the circuit breaker of the
+// Client is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState represents the state of the circuit breaker of the Client
+type CircuitState int
+
+const (
+	// CircuitClosed is the normal state of the circuit breaker, in which all requests are performed
+	CircuitClosed CircuitState = iota
+	// CircuitOpen is the state after too many consecutive failures, in which all requests fail fast
+	// with ErrCircuitOpen
+	CircuitOpen
+	// CircuitHalfOpen is the state after the cooldown of an open circuit breaker, in which a single
+	// probe request is performed to decide whether the circuit breaker closes or opens again
+	CircuitHalfOpen
+)
+
+// String satisfies the fmt.Stringer interface for the CircuitState type
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// circuitBreaker keeps track of consecutive failed requests and fails fast, once the API is
+// considered unavailable
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int           // Number of consecutive failures that open the circuit breaker
+	cooldown  time.Duration // Duration the circuit breaker stays open before allowing a probe
+	failures  int           // Number of consecutive failures
+	open      bool          // Indicates whether the circuit breaker is open
+	openedAt  time.Time     // Time the circuit breaker was opened
+	probing   bool          // Indicates whether a probe request is in flight
+	gen       uint64        // Generation of the circuit breaker, increased on each state change
+}
+
+// WithCircuitBreaker enables the circuit breaker for the Client. The circuit breaker opens after the
+// given number of consecutive failed requests, so that all further requests fail fast with
+// ErrCircuitOpen instead of waiting for the HTTP timeout. After the given cooldown, a single probe
+// request is allowed. If it succeeds, the circuit breaker closes again, otherwise it stays open for
+// another cooldown. Transport errors, timeouts and HTTP 5xx responses are considered failures.
+//
+// If the threshold or the cooldown are not positive, DefaultCircuitBreakerThreshold and
+// DefaultCircuitBreakerCooldown are used instead.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	if threshold <= 0 {
+		threshold = DefaultCircuitBreakerThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultCircuitBreakerCooldown
+	}
+	return func(c *Client) {
+		c.breaker = &circuitBreaker{threshold: threshold, cooldown: cooldown}
+	}
+}
+
+// CircuitState returns the current state of the circuit breaker of the Client. If the circuit
+// breaker is not enabled, CircuitClosed is returned.
+func (c *Client) CircuitState() CircuitState {
+	if c.breaker == nil {
+		return CircuitClosed
+	}
+	return c.breaker.state()
+}
+
+// state returns the current state of the circuit breaker
+func (cb *circuitBreaker) state() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch {
+	case !cb.open:
+		return CircuitClosed
+	case cb.probing || time.Since(cb.openedAt) >= cb.cooldown:
+		return CircuitHalfOpen
+	default:
+		return CircuitOpen
+	}
+}
+
+// allow returns ErrCircuitOpen if the circuit breaker does not allow a request. If the cooldown of an
+// open circuit breaker has passed, a single probe request is allowed. The returned generation has to
+// be passed to record with the result of the request
+func (cb *circuitBreaker) allow() (uint64, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if !cb.open {
+		return cb.gen, nil
+	}
+	if cb.probing || time.Since(cb.openedAt) < cb.cooldown {
+		return 0, ErrCircuitOpen
+	}
+	cb.probing = true
+	cb.gen++
+	return cb.gen, nil
+}
+
+// record updates the circuit breaker with the result of a request that was allowed in the given
+// generation. Results of requests that were allowed before the last state change are ignored, so
+// that a late response of a request from before the circuit breaker opened neither closes it nor
+// ends the probe
+func (cb *circuitBreaker) record(gen uint64, hr *http.Response, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if gen != cb.gen {
+		return
+	}
+	if err == nil && hr != nil && hr.StatusCode < http.StatusInternalServerError {
+		cb.failures = 0
+		if cb.open {
+			cb.open, cb.probing = false, false
+			cb.gen++
+		}
+		return
+	}
+	cb.failures++
+	if cb.open || cb.failures >= cb.threshold {
+		cb.open, cb.probing = true, false
+		cb.openedAt = time.Now()
+		cb.gen++
+	}
+}
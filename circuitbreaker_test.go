@@ -0,0 +1,203 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitState_String(t *testing.T) {
+	tests := []struct {
+		state CircuitState
+		want  string
+	}{
+		{CircuitClosed, "closed"},
+		{CircuitOpen, "open"},
+		{CircuitHalfOpen, "half-open"},
+		{CircuitState(99), "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if tt.state.String() != tt.want {
+				t.Errorf("expected circuit state string to be %q, got %q", tt.want, tt.state.String())
+			}
+		})
+	}
+}
+
+func TestClient_WithCircuitBreaker(t *testing.T) {
+	t.Run("circuit breaker is disabled by default", func(t *testing.T) {
+		hc := New()
+		if hc.breaker != nil {
+			t.Error("expected circuit breaker to be disabled")
+		}
+		if hc.CircuitState() != CircuitClosed {
+			t.Errorf("expected circuit state to be %s, got %s", CircuitClosed, hc.CircuitState())
+		}
+	})
+	t.Run("circuit breaker uses defaults for invalid values", func(t *testing.T) {
+		hc := New(WithCircuitBreaker(0, -1))
+		if hc.breaker.threshold != DefaultCircuitBreakerThreshold {
+			t.Errorf("expected threshold to be %d, got %d", DefaultCircuitBreakerThreshold, hc.breaker.threshold)
+		}
+		if hc.breaker.cooldown != DefaultCircuitBreakerCooldown {
+			t.Errorf("expected cooldown to be %s, got %s", DefaultCircuitBreakerCooldown, hc.breaker.cooldown)
+		}
+	})
+	t.Run("circuit breaker opens after consecutive failures", func(t *testing.T) {
+		var hits int32
+		server := httptest.NewServer(newTestCountingHandler(&hits, http.StatusInternalServerError))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)), WithCircuitBreaker(3, time.Minute))
+		for i := 0; i < 3; i++ {
+			if hc.CircuitState() != CircuitClosed {
+				t.Errorf("expected circuit state to be %s, got %s", CircuitClosed, hc.CircuitState())
+			}
+			_, _, err := hc.BreachAPI.Breaches()
+			if err == nil || errors.Is(err, ErrCircuitOpen) {
+				t.Errorf("expected request to fail with HTTP error, got %v", err)
+			}
+		}
+		if hc.CircuitState() != CircuitOpen {
+			t.Errorf("expected circuit state to be %s, got %s", CircuitOpen, hc.CircuitState())
+		}
+		_, _, err := hc.BreachAPI.Breaches()
+		if !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("expected error to be %s, got %v", ErrCircuitOpen, err)
+		}
+		_, _, err = hc.PwnedPassAPI.CheckPassword(PwStringInsecure)
+		if !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("expected error to be %s, got %v", ErrCircuitOpen, err)
+		}
+		if atomic.LoadInt32(&hits) != 3 {
+			t.Errorf("expected %d requests to reach the server, got %d", 3, atomic.LoadInt32(&hits))
+		}
+	})
+	t.Run("successful requests reset the failure count", func(t *testing.T) {
+		var status int32 = http.StatusInternalServerError
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(int(atomic.LoadInt32(&status)))
+		}))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)), WithCircuitBreaker(2, time.Minute))
+		_, _, _ = hc.BreachAPI.Breaches()
+		atomic.StoreInt32(&status, http.StatusNotFound)
+		_, _, _ = hc.BreachAPI.Breaches()
+		atomic.StoreInt32(&status, http.StatusInternalServerError)
+		_, _, _ = hc.BreachAPI.Breaches()
+		if hc.CircuitState() != CircuitClosed {
+			t.Errorf("expected circuit state to be %s, got %s", CircuitClosed, hc.CircuitState())
+		}
+	})
+	t.Run("circuit breaker closes after a successful probe", func(t *testing.T) {
+		var status int32 = http.StatusInternalServerError
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(int(atomic.LoadInt32(&status)))
+			_, _ = w.Write([]byte(`[]`))
+		}))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)), WithCircuitBreaker(1, time.Millisecond*50))
+		_, _, _ = hc.BreachAPI.Breaches()
+		if hc.CircuitState() != CircuitOpen {
+			t.Fatalf("expected circuit state to be %s, got %s", CircuitOpen, hc.CircuitState())
+		}
+		time.Sleep(time.Millisecond * 60)
+		if hc.CircuitState() != CircuitHalfOpen {
+			t.Fatalf("expected circuit state to be %s, got %s", CircuitHalfOpen, hc.CircuitState())
+		}
+		atomic.StoreInt32(&status, http.StatusOK)
+		if _, _, err := hc.BreachAPI.Breaches(); err != nil {
+			t.Fatalf("expected probe request to succeed, got %s", err)
+		}
+		if hc.CircuitState() != CircuitClosed {
+			t.Errorf("expected circuit state to be %s, got %s", CircuitClosed, hc.CircuitState())
+		}
+	})
+	t.Run("circuit breaker opens again after a failed probe", func(t *testing.T) {
+		var hits int32
+		server := httptest.NewServer(newTestCountingHandler(&hits, http.StatusBadGateway))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)), WithCircuitBreaker(2, time.Millisecond*50))
+		_, _, _ = hc.BreachAPI.Breaches()
+		_, _, _ = hc.BreachAPI.Breaches()
+		time.Sleep(time.Millisecond * 60)
+		if _, _, err := hc.BreachAPI.Breaches(); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("expected probe request to fail with HTTP error, got %v", err)
+		}
+		if hc.CircuitState() != CircuitOpen {
+			t.Errorf("expected circuit state to be %s, got %s", CircuitOpen, hc.CircuitState())
+		}
+		if _, _, err := hc.BreachAPI.Breaches(); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("expected error to be %s, got %v", ErrCircuitOpen, err)
+		}
+		if atomic.LoadInt32(&hits) != 3 {
+			t.Errorf("expected %d requests to reach the server, got %d", 3, atomic.LoadInt32(&hits))
+		}
+	})
+	t.Run("only a single probe is allowed while half-open", func(t *testing.T) {
+		breaker := &circuitBreaker{threshold: 1, cooldown: time.Millisecond}
+		breaker.record(0, nil, errors.New("timeout"))
+		time.Sleep(time.Millisecond * 5)
+		if _, err := breaker.allow(); err != nil {
+			t.Fatalf("expected probe request to be allowed, got %s", err)
+		}
+		if _, err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("expected second request to fail with %s, got %v", ErrCircuitOpen, err)
+		}
+		if breaker.state() != CircuitHalfOpen {
+			t.Errorf("expected circuit state to be %s, got %s", CircuitHalfOpen, breaker.state())
+		}
+	})
+	t.Run("results of requests from before the circuit breaker opened are ignored", func(t *testing.T) {
+		ok := &http.Response{StatusCode: http.StatusOK}
+		breaker := &circuitBreaker{threshold: 1, cooldown: time.Millisecond}
+		early, _ := breaker.allow()
+		late, _ := breaker.allow()
+		breaker.record(early, nil, errors.New("timeout"))
+		breaker.record(late, ok, nil)
+		if breaker.state() == CircuitClosed {
+			t.Fatal("expected late response to leave the circuit breaker open")
+		}
+		time.Sleep(time.Millisecond * 5)
+		probe, err := breaker.allow()
+		if err != nil {
+			t.Fatalf("expected probe request to be allowed, got %s", err)
+		}
+		breaker.record(late, nil, errors.New("timeout"))
+		if _, err = breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("expected late response not to end the probe, got %v", err)
+		}
+		breaker.record(probe, ok, nil)
+		if breaker.state() != CircuitClosed {
+			t.Errorf("expected circuit state to be %s, got %s", CircuitClosed, breaker.state())
+		}
+	})
+	t.Run("transport errors open the circuit breaker", func(t *testing.T) {
+		fault := func(HTTPClient) HTTPClient {
+			return HTTPClientFunc(func(*http.Request) (*http.Response, error) {
+				return nil, errors.New("timeout")
+			})
+		}
+		hc := New(WithMiddleware(fault), WithCircuitBreaker(1, time.Minute))
+		_, _, _ = hc.BreachAPI.Breaches()
+		if hc.CircuitState() != CircuitOpen {
+			t.Errorf("expected circuit state to be %s, got %s", CircuitOpen, hc.CircuitState())
+		}
+	})
+}
+
+// newTestCountingHandler returns an HTTP handler that counts the requests and responds with the
+// given status code.
+func newTestCountingHandler(hits *int32, code int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		w.WriteHeader(code)
+	})
+}
@@ -0,0 +1,271 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wneessen/go-hibp"
+	"github.com/wneessen/go-hibp/contrib/hibpprom"
+)
+
+// exposure holds the data of the HIBP API the metrics are built from
+type exposure struct {
+	breaches     int                            // Number of breaches in the catalogue
+	latestAdded  time.Time                      // Time the latest breach was added
+	domains      map[string]map[string][]string // Breach names by alias by subscribed domain
+	dataClasses  map[string]map[string]int      // Number of aliases by data class by subscribed domain
+	health       *hibp.SubscriptionHealth       // Health of the subscription
+	failedSearch int                            // Number of domains whose last search failed
+}
+
+// DefaultRetryInterval is the default delay of the first retry of a failed refresh
+const DefaultRetryInterval = time.Minute
+
+// collector is a prometheus.Collector that exports the exposure of the subscribed domains. The data
+// is fetched from the HIBP API in the background by run and refreshed after the configured TTL, so
+// that scrapes only serve the cached data and never hit the API
+type collector struct {
+	mu sync.Mutex
+
+	client        *hibp.Client
+	authenticated bool // Whether the client has an API key for the subscription and domain metrics
+	ttl           time.Duration
+	retryInterval time.Duration // Delay of the first retry of a failed refresh, doubled for each failure
+	searchOptions []hibp.DomainSearchOption
+	healthOptions []hibp.HealthOption
+	logger        *slog.Logger
+	now           func() time.Time
+
+	cached        *exposure
+	fetched       time.Time // Time the cached exposure was fetched
+	refreshed     bool      // Whether a refresh was attempted
+	refreshFailed bool      // Whether the last refresh failed
+	refreshErrors int       // Number of failed refreshes
+
+	healthMetrics      *hibpprom.HealthMetrics
+	breachedAliases    *prometheus.Desc
+	dataClassAliases   *prometheus.Desc
+	catalogueBreaches  *prometheus.Desc
+	latestBreachAge    *prometheus.Desc
+	domainSearchErrors *prometheus.Desc
+	lastRefresh        *prometheus.Desc
+	refreshSuccess     *prometheus.Desc
+	refreshErrorsTotal *prometheus.Desc
+}
+
+// newCollector returns a new collector that fetches the data with the given hibp.Client and caches
+// it for the given TTL
+func newCollector(client *hibp.Client, authenticated bool, ttl time.Duration, namespace string,
+	logger *slog.Logger,
+) *collector {
+	name := func(n string) string {
+		return prometheus.BuildFQName(namespace, "", n)
+	}
+	return &collector{
+		client:        client,
+		authenticated: authenticated,
+		ttl:           ttl,
+		retryInterval: DefaultRetryInterval,
+		logger:        logger,
+		now:           time.Now,
+		healthMetrics: hibpprom.NewHealthMetrics(hibpprom.WithNamespace(namespace)),
+		breachedAliases: prometheus.NewDesc(name("domain_breached_aliases"),
+			"Breached aliases of a subscribed domain.", []string{"domain"}, nil),
+		dataClassAliases: prometheus.NewDesc(name("domain_data_class_aliases"),
+			"Breached aliases of a subscribed domain that were exposed with a data class.",
+			[]string{"domain", "data_class"}, nil),
+		catalogueBreaches: prometheus.NewDesc(name("catalogue_breaches"),
+			"Breaches in the HIBP breach catalogue.", nil, nil),
+		latestBreachAge: prometheus.NewDesc(name("latest_breach_age_seconds"),
+			"Time since the latest breach was added to HIBP.", nil, nil),
+		domainSearchErrors: prometheus.NewDesc(name("domain_search_errors"),
+			"Subscribed domains whose last search failed and that are exported from an earlier search.",
+			nil, nil),
+		lastRefresh: prometheus.NewDesc(name("exporter_last_refresh_timestamp_seconds"),
+			"Time the exported data was last fetched from the HIBP API.", nil, nil),
+		refreshSuccess: prometheus.NewDesc(name("exporter_refresh_success"),
+			"Whether the last refresh of the exported data from the HIBP API succeeded.", nil, nil),
+		refreshErrorsTotal: prometheus.NewDesc(name("exporter_refresh_errors_total"),
+			"Total number of failed refreshes of the exported data from the HIBP API.", nil, nil),
+	}
+}
+
+// Describe satisfies the prometheus.Collector interface for the collector type
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	c.healthMetrics.Describe(ch)
+	ch <- c.breachedAliases
+	ch <- c.dataClassAliases
+	ch <- c.catalogueBreaches
+	ch <- c.latestBreachAge
+	ch <- c.domainSearchErrors
+	ch <- c.lastRefresh
+	ch <- c.refreshSuccess
+	ch <- c.refreshErrorsTotal
+}
+
+// run refreshes the cached data until the given context is canceled. After a successful refresh, the
+// next refresh starts after the TTL. Failed refreshes are retried after the retry interval, which is
+// doubled for each consecutive failure up to the TTL, so that an outage of the HIBP API does not
+// cause more requests than the regular refreshes
+func (c *collector) run(ctx context.Context) {
+	failures := 0
+	for {
+		delay := c.ttl
+		if c.update() {
+			failures = 0
+		} else {
+			failures++
+			delay = c.retryDelay(failures)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay returns the delay of the retry after the given number of consecutive failed refreshes
+func (c *collector) retryDelay(failures int) time.Duration {
+	delay := c.retryInterval
+	for i := 1; i < failures && delay < c.ttl; i++ {
+		delay *= 2
+	}
+	if delay > c.ttl {
+		delay = c.ttl
+	}
+	return delay
+}
+
+// update refreshes the cached data and reports whether the refresh succeeded. If the refresh fails,
+// the previously cached data is kept
+func (c *collector) update() bool {
+	data, err := c.refresh()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshed, c.refreshFailed = true, err != nil
+	if err != nil {
+		c.refreshErrors++
+		c.logger.Error("failed to refresh HIBP data", slog.Any("error", err))
+		return false
+	}
+	c.cached, c.fetched = data, c.now()
+	return true
+}
+
+// Collect satisfies the prometheus.Collector interface for the collector type. Only the cached data
+// is exported, the HIBP API is never requested during a scrape
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.refreshed {
+		return
+	}
+	now := c.now()
+	success := 1.0
+	if c.refreshFailed {
+		success = 0
+	}
+	ch <- prometheus.MustNewConstMetric(c.refreshSuccess, prometheus.GaugeValue, success)
+	ch <- prometheus.MustNewConstMetric(c.refreshErrorsTotal, prometheus.CounterValue, float64(c.refreshErrors))
+	if c.cached == nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.lastRefresh, prometheus.GaugeValue, float64(c.fetched.Unix()))
+
+	data := c.cached
+	ch <- prometheus.MustNewConstMetric(c.catalogueBreaches, prometheus.GaugeValue, float64(data.breaches))
+	if !data.latestAdded.IsZero() {
+		ch <- prometheus.MustNewConstMetric(c.latestBreachAge, prometheus.GaugeValue,
+			now.Sub(data.latestAdded).Seconds())
+	}
+	if !c.authenticated {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.domainSearchErrors, prometheus.GaugeValue, float64(data.failedSearch))
+	for domain, aliases := range data.domains {
+		ch <- prometheus.MustNewConstMetric(c.breachedAliases, prometheus.GaugeValue, float64(len(aliases)), domain)
+	}
+	for domain, classes := range data.dataClasses {
+		for class, count := range classes {
+			ch <- prometheus.MustNewConstMetric(c.dataClassAliases, prometheus.GaugeValue, float64(count),
+				domain, class)
+		}
+	}
+
+	// The remaining time of the subscription is exported relative to the scrape, not to the refresh
+	health := *data.health
+	health.Checked = now
+	c.healthMetrics.Update(&health)
+	c.healthMetrics.Collect(ch)
+}
+
+// refresh fetches the data of the metrics from the HIBP API. Domains whose search fails are taken
+// over from the cached data. It is only called by update, which is the only writer of the cached
+// data, so the cached data is read without lock
+func (c *collector) refresh() (*exposure, error) {
+	breaches, _, err := c.client.BreachAPI.Breaches()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get breaches: %w", err)
+	}
+	data := &exposure{breaches: len(breaches)}
+	catalogue := make(map[string][]string, len(breaches))
+	for _, b := range breaches {
+		catalogue[b.Name] = b.DataClasses
+		if b.AddedDate.After(data.latestAdded) {
+			data.latestAdded = b.AddedDate
+		}
+	}
+	if !c.authenticated {
+		return data, nil
+	}
+
+	if data.health, err = c.client.SubscriptionAPI.Health(c.healthOptions...); err != nil {
+		return nil, err
+	}
+	result, err := c.client.BreachAPI.SearchAllDomains(c.searchOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search domains: %w", err)
+	}
+	data.domains = result.Domains
+	for domain, err := range result.Errors {
+		c.logger.Warn("failed to search domain", slog.String("domain", domain), slog.Any("error", err))
+		data.failedSearch++
+		if c.cached != nil {
+			if aliases, ok := c.cached.domains[domain]; ok {
+				data.domains[domain] = aliases
+			}
+		}
+	}
+
+	data.dataClasses = make(map[string]map[string]int, len(data.domains))
+	for domain, aliases := range data.domains {
+		classes := make(map[string]int)
+		for _, names := range aliases {
+			exposed := make(map[string]struct{})
+			for _, name := range names {
+				for _, class := range catalogue[name] {
+					exposed[class] = struct{}{}
+				}
+			}
+			for class := range exposed {
+				classes[class]++
+			}
+		}
+		data.dataClasses[domain] = classes
+	}
+	return data, nil
+}
@@ -0,0 +1,241 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/wneessen/go-hibp"
+)
+
+// testRouteClient is a hibp.HTTPClient that sends all requests to the test server
+type testRouteClient struct {
+	target *url.URL
+}
+
+// Do satisfies the hibp.HTTPClient interface for the testRouteClient type
+func (c *testRouteClient) Do(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme, req.URL.Host, req.Host = c.target.Scheme, c.target.Host, c.target.Host
+	return http.DefaultClient.Do(req)
+}
+
+// testServer is a fake HIBP API that counts the requests per path
+type testServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	hits   map[string]int
+	failed bool
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	s := &testServer{hits: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.hits[r.URL.Path]++
+		failed := s.failed
+		s.mu.Unlock()
+		if failed {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v3/breaches":
+			_, _ = fmt.Fprint(w, `[
+{"Name":"Adobe","AddedDate":"2013-12-04T00:00:00Z","DataClasses":["Email addresses","Passwords"]},
+{"Name":"Foodora","AddedDate":"2020-06-01T00:00:00Z","DataClasses":["Email addresses","Names"]}]`)
+		case "/api/v3/subscription/status":
+			_, _ = fmt.Fprint(w, `{"SubscriptionName":"Pwned 1","SubscribedUntil":"2030-01-01T00:00:00",
+"DomainSearchMaxBreachedAccounts":25,"Rpm":60000}`)
+		case "/api/v3/subscribeddomains":
+			_, _ = fmt.Fprint(w, `[{"DomainName":"domain.tld","PwnCount":2,"PwnCountExcludingSpamLists":2,
+"PwnCountExcludingSpamListsAtLastSubscriptionRenewal":1},{"DomainName":"new.tld","PwnCount":null}]`)
+		case "/api/v3/breacheddomain/domain.tld":
+			_, _ = fmt.Fprint(w, `{"toni.tester":["Adobe","Foodora"],"tina.tester":["Foodora"]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testServer) requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
+
+func (s *testServer) fail(failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed = failed
+}
+
+func newTestCollector(t *testing.T, server *testServer, apiKey string) *collector {
+	t.Helper()
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse test server URL: %s", err)
+	}
+	client := hibp.New(hibp.WithHTTPClient(&testRouteClient{target}), hibp.WithAPIKey(apiKey))
+	return newCollector(&client, apiKey != "", time.Hour, "hibp", slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestCollector(t *testing.T) {
+	t.Run("metrics are exported", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestCollector(t, server, "00000000000000000000000000000000")
+		now := time.Date(2020, 6, 2, 0, 0, 0, 0, time.UTC)
+		c.now = func() time.Time { return now }
+		if !c.update() {
+			t.Fatal("failed to refresh data")
+		}
+
+		expected := `
+# HELP hibp_catalogue_breaches Breaches in the HIBP breach catalogue.
+# TYPE hibp_catalogue_breaches gauge
+hibp_catalogue_breaches 2
+# HELP hibp_domain_breached_aliases Breached aliases of a subscribed domain.
+# TYPE hibp_domain_breached_aliases gauge
+hibp_domain_breached_aliases{domain="domain.tld"} 2
+# HELP hibp_domain_data_class_aliases Breached aliases of a subscribed domain that were exposed with a data class.
+# TYPE hibp_domain_data_class_aliases gauge
+hibp_domain_data_class_aliases{data_class="Email addresses",domain="domain.tld"} 2
+hibp_domain_data_class_aliases{data_class="Names",domain="domain.tld"} 2
+hibp_domain_data_class_aliases{data_class="Passwords",domain="domain.tld"} 1
+# HELP hibp_latest_breach_age_seconds Time since the latest breach was added to HIBP.
+# TYPE hibp_latest_breach_age_seconds gauge
+hibp_latest_breach_age_seconds 86400
+# HELP hibp_health_warning Set to 1 for each warning of the last HIBP subscription health check.
+# TYPE hibp_health_warning gauge
+hibp_health_warning{domain="new.tld",kind="domain_never_searched"} 1
+# HELP hibp_exporter_refresh_success Whether the last refresh of the exported data from the HIBP API succeeded.
+# TYPE hibp_exporter_refresh_success gauge
+hibp_exporter_refresh_success 1
+`
+		if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "hibp_catalogue_breaches",
+			"hibp_domain_breached_aliases", "hibp_domain_data_class_aliases", "hibp_latest_breach_age_seconds",
+			"hibp_health_warning", "hibp_exporter_refresh_success"); err != nil {
+			t.Errorf("unexpected metrics: %s", err)
+		}
+		registry := prometheus.NewRegistry()
+		if err := registry.Register(c); err != nil {
+			t.Fatalf("failed to register collector: %s", err)
+		}
+		if count := testutil.CollectAndCount(registry, "hibp_subscription_days_remaining"); count != 1 {
+			t.Errorf("expected subscription days remaining to be exported, got %d", count)
+		}
+	})
+	t.Run("scrapes are served from the cache", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestCollector(t, server, "00000000000000000000000000000000")
+		if count := testutil.CollectAndCount(c); count != 0 {
+			t.Errorf("expected no metrics before the first refresh, got %d", count)
+		}
+		if !c.update() {
+			t.Fatal("failed to refresh data")
+		}
+		testutil.CollectAndCount(c)
+		testutil.CollectAndCount(c)
+		if hits := server.requests("/api/v3/breacheddomain/domain.tld"); hits != 1 {
+			t.Errorf("expected scrapes not to request the API, got %d domain searches", hits)
+		}
+	})
+	t.Run("cached data is exported if the refresh fails", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestCollector(t, server, "00000000000000000000000000000000")
+		if !c.update() {
+			t.Fatal("failed to refresh data")
+		}
+		server.fail(true)
+		if c.update() {
+			t.Fatal("expected refresh to fail")
+		}
+		expected := `
+# HELP hibp_catalogue_breaches Breaches in the HIBP breach catalogue.
+# TYPE hibp_catalogue_breaches gauge
+hibp_catalogue_breaches 2
+# HELP hibp_exporter_refresh_success Whether the last refresh of the exported data from the HIBP API succeeded.
+# TYPE hibp_exporter_refresh_success gauge
+hibp_exporter_refresh_success 0
+# HELP hibp_exporter_refresh_errors_total Total number of failed refreshes of the exported data from the HIBP API.
+# TYPE hibp_exporter_refresh_errors_total counter
+hibp_exporter_refresh_errors_total 1
+`
+		if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "hibp_catalogue_breaches",
+			"hibp_exporter_refresh_success", "hibp_exporter_refresh_errors_total"); err != nil {
+			t.Errorf("unexpected metrics: %s", err)
+		}
+	})
+	t.Run("only catalogue metrics are exported without API key", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestCollector(t, server, "")
+		if !c.update() {
+			t.Fatal("failed to refresh data")
+		}
+		if count := testutil.CollectAndCount(c, "hibp_domain_breached_aliases"); count != 0 {
+			t.Errorf("expected no domain metrics without API key, got %d", count)
+		}
+		if count := testutil.CollectAndCount(c, "hibp_catalogue_breaches"); count != 1 {
+			t.Errorf("expected catalogue metrics without API key, got %d", count)
+		}
+		if hits := server.requests("/api/v3/subscribeddomains"); hits != 0 {
+			t.Errorf("expected no authenticated requests without API key, got %d", hits)
+		}
+	})
+	t.Run("data is refreshed in the background", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestCollector(t, server, "")
+		c.ttl = 10 * time.Millisecond
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			c.run(ctx)
+			close(done)
+		}()
+		deadline := time.Now().Add(5 * time.Second)
+		for server.requests("/api/v3/breaches") < 3 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		cancel()
+		<-done
+		if hits := server.requests("/api/v3/breaches"); hits < 3 {
+			t.Errorf("expected data to be refreshed after the TTL, got %d refreshes", hits)
+		}
+	})
+}
+
+func TestCollector_retryDelay(t *testing.T) {
+	c := &collector{ttl: time.Hour, retryInterval: time.Minute}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{6, 32 * time.Minute},
+		{7, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		if delay := c.retryDelay(tt.failures); delay != tt.want {
+			t.Errorf("expected retry delay after %d failures to be %s, got %s", tt.failures, tt.want, delay)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+module github.com/wneessen/go-hibp/cmd/hibp-exporter
+
+go 1.21
+
+require (
+	github.com/prometheus/client_golang v1.19.0
+	github.com/wneessen/go-hibp v1.2.0
+	github.com/wneessen/go-hibp/contrib/hibpprom v0.1.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/prometheus/client_model v0.5.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
+	github.com/wneessen/niljson v0.1.1 // indirect
+	golang.org/x/sys v0.16.0 // indirect
+	google.golang.org/protobuf v1.32.0 // indirect
+)
@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+// Command hibp-exporter is a Prometheus exporter for the exposure of the subscribed domains in the
+// "Have I Been Pwned" API. It exports the breached aliases and the exposed data classes per
+// subscribed domain, the size of the breach catalogue, the age of the latest breach and the health
+// of the subscription.
+//
+// The API key is read from the HIBP_API_KEY environment variable. Without an API key, only the
+// metrics of the public breach catalogue are exported. The data is fetched from the HIBP API in the
+// background and refreshed after -cache-ttl, scrapes are served from the cached data. Failed
+// refreshes are retried after -retry-interval, which is doubled for each consecutive failure up to
+// -cache-ttl.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/wneessen/go-hibp"
+	"github.com/wneessen/go-hibp/contrib/hibpprom"
+)
+
+// EnvAPIKey is the environment variable the API key is read from
+const EnvAPIKey = "HIBP_API_KEY"
+
+// config holds the command line configuration of the exporter
+type config struct {
+	listen        string
+	path          string
+	namespace     string
+	ttl           time.Duration
+	retryInterval time.Duration
+	timeout       time.Duration
+	concurrency   int
+	expiryDays    int
+	capacity      float64
+}
+
+func main() {
+	var cfg config
+	flag.StringVar(&cfg.listen, "listen", ":9775", "address to listen on for scrapes")
+	flag.StringVar(&cfg.path, "metrics-path", "/metrics", "path the metrics are exposed on")
+	flag.StringVar(&cfg.namespace, "namespace", hibpprom.DefaultNamespace, "namespace of the metrics")
+	flag.DurationVar(&cfg.ttl, "cache-ttl", time.Hour, "duration the data of the HIBP API is cached for")
+	flag.DurationVar(&cfg.retryInterval, "retry-interval", DefaultRetryInterval,
+		"delay of the first retry of a failed refresh")
+	flag.DurationVar(&cfg.timeout, "timeout", hibp.DefaultTimeout, "timeout of the requests to the HIBP API")
+	flag.IntVar(&cfg.concurrency, "domain-concurrency", hibp.DefaultDomainSearchConcurrency,
+		"number of concurrent domain searches")
+	flag.IntVar(&cfg.expiryDays, "expiry-days", hibp.DefaultHealthExpiryDays,
+		"days before the end of the subscription to warn about the expiry")
+	flag.Float64Var(&cfg.capacity, "capacity-threshold", hibp.DefaultHealthCapacityThreshold,
+		"ratio of the domain search capacity to warn about a domain")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, logger, cfg); err != nil {
+		logger.Error("exporter failed", slog.Any("error", err))
+		stop()
+		os.Exit(1)
+	}
+}
+
+// run refreshes the data in the background, starts the HTTP server of the exporter and blocks until
+// the given context is canceled or the HTTP server fails
+func run(ctx context.Context, logger *slog.Logger, cfg config) error {
+	if cfg.ttl <= 0 || cfg.retryInterval <= 0 {
+		return errors.New("cache TTL and retry interval must be positive")
+	}
+	apiKey := os.Getenv(EnvAPIKey)
+	if apiKey == "" {
+		logger.Warn("no API key set, only breach catalogue metrics are exported", slog.String("env", EnvAPIKey))
+	}
+
+	observer := hibpprom.NewObserver(hibpprom.WithNamespace(cfg.namespace))
+	client := hibp.New(hibp.WithAPIKey(apiKey), hibp.WithHTTPTimeout(cfg.timeout),
+		hibp.WithUserAgent("hibp-exporter"), hibp.WithObserver(observer))
+	c := newCollector(&client, apiKey != "", cfg.ttl, cfg.namespace, logger)
+	c.retryInterval = cfg.retryInterval
+	c.searchOptions = []hibp.DomainSearchOption{hibp.WithDomainSearchConcurrency(cfg.concurrency)}
+	c.healthOptions = []hibp.HealthOption{
+		hibp.WithHealthExpiryDays(cfg.expiryDays),
+		hibp.WithHealthCapacityThreshold(cfg.capacity),
+	}
+	go c.run(ctx)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(observer, c)
+	mux := http.NewServeMux()
+	mux.Handle(cfg.path, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{
+		Addr:              cfg.listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info("serving metrics", slog.String("address", cfg.listen), slog.String("path", cfg.path),
+		slog.Duration("cache_ttl", cfg.ttl))
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
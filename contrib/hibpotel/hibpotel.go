@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+// Package hibpotel provides a go-hibp Observer that creates OpenTelemetry spans for the requests
+// to the "Have I Been Pwned" API. It lives in its own module, so that the core go-hibp module does
+// not depend on the OpenTelemetry libraries.
+package hibpotel
+
+import (
+	"context"
+
+	"github.com/wneessen/go-hibp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name of the tracer used by the Observer
+const ScopeName = "github.com/wneessen/go-hibp/contrib/hibpotel"
+
+// Observer is a hibp.Observer that creates an OpenTelemetry span for each API request.
+type Observer struct {
+	tracer trace.Tracer
+}
+
+// Option is a function that is used for grouping of Observer options.
+type Option func(*options)
+
+// options holds the configuration of the Observer
+type options struct {
+	provider trace.TracerProvider
+}
+
+// WithTracerProvider overrides the global TracerProvider the Observer uses to create its tracer
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.provider = tp
+	}
+}
+
+// NewObserver returns a new Observer that creates OpenTelemetry spans
+func NewObserver(opts ...Option) *Observer {
+	o := &options{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(o)
+	}
+	if o.provider == nil {
+		o.provider = otel.GetTracerProvider()
+	}
+	return &Observer{tracer: o.provider.Tracer(ScopeName, trace.WithInstrumentationVersion(hibp.Version))}
+}
+
+// RequestStart satisfies the hibp.Observer interface for the Observer type. It starts a new client
+// span for the API request
+func (o *Observer) RequestStart(ctx context.Context, ev hibp.RequestEvent) context.Context {
+	ctx, _ = o.tracer.Start(ctx, "HIBP "+ev.Method+" "+ev.Endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", ev.Method),
+			attribute.String("url.template", ev.Endpoint),
+		),
+	)
+	return ctx
+}
+
+// Response satisfies the hibp.Observer interface for the Observer type. It ends the span of the
+// API request
+func (o *Observer) Response(ctx context.Context, ev hibp.ResponseEvent) {
+	span := trace.SpanFromContext(ctx)
+	if ev.StatusCode > 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", ev.StatusCode))
+	}
+	span.SetAttributes(attribute.Int("hibp.retries", ev.Retries))
+	if ev.Err != nil {
+		span.RecordError(ev.Err)
+		span.SetStatus(codes.Error, ev.Err.Error())
+	}
+	span.End()
+}
+
+// Retry satisfies the hibp.Observer interface for the Observer type. It adds a retry event to the
+// span of the API request
+func (o *Observer) Retry(ctx context.Context, ev hibp.RetryEvent) {
+	trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+		attribute.Int("hibp.retry.attempt", ev.Attempt),
+		attribute.Float64("hibp.retry.wait_seconds", ev.Wait.Seconds()),
+	))
+}
+
+// CacheHit satisfies the hibp.Observer interface for the Observer type. Cache hits are not part of
+// an API request and the hibp.Client passes no request context, so there is no span to record them
+// on and they are ignored
+func (o *Observer) CacheHit(context.Context, hibp.CacheEvent) {}
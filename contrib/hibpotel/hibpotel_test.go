@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibpotel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wneessen/go-hibp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestObserver(t *testing.T) {
+	request := hibp.RequestEvent{Method: "GET", Endpoint: "/api/v3/breachedaccount/{account}"}
+	t.Run("a span is created for each request", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		observer := NewObserver(WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))))
+		ctx := observer.RequestStart(context.Background(), request)
+		observer.Retry(ctx, hibp.RetryEvent{RequestEvent: request, Attempt: 1, Wait: time.Second})
+		observer.Response(ctx, hibp.ResponseEvent{RequestEvent: request, StatusCode: 200, Retries: 1})
+
+		spans := recorder.Ended()
+		if len(spans) != 1 {
+			t.Fatalf("expected %d span, got %d", 1, len(spans))
+		}
+		span := spans[0]
+		if span.Name() != "HIBP GET /api/v3/breachedaccount/{account}" {
+			t.Errorf("unexpected span name: %s", span.Name())
+		}
+		if !hasAttribute(span.Attributes(), attribute.Int("http.response.status_code", 200)) {
+			t.Error("expected span to hold the HTTP status code")
+		}
+		if !hasAttribute(span.Attributes(), attribute.Int("hibp.retries", 1)) {
+			t.Error("expected span to hold the retry count")
+		}
+		if len(span.Events()) != 1 || span.Events()[0].Name != "retry" {
+			t.Errorf("expected span to hold a retry event, got %v", span.Events())
+		}
+		if span.Status().Code == codes.Error {
+			t.Error("expected span not to have an error status")
+		}
+	})
+	t.Run("failed requests set the span status", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		observer := NewObserver(WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))),
+			nil)
+		ctx := observer.RequestStart(context.Background(), request)
+		observer.Response(ctx, hibp.ResponseEvent{RequestEvent: request, Err: errors.New("failed")})
+
+		spans := recorder.Ended()
+		if len(spans) != 1 {
+			t.Fatalf("expected %d span, got %d", 1, len(spans))
+		}
+		if spans[0].Status().Code != codes.Error {
+			t.Errorf("expected span to have an error status, got %s", spans[0].Status().Code)
+		}
+	})
+	t.Run("observer uses the global tracer provider by default", func(t *testing.T) {
+		observer := NewObserver()
+		ctx := observer.RequestStart(context.Background(), request)
+		observer.Response(ctx, hibp.ResponseEvent{RequestEvent: request, StatusCode: 200})
+	})
+}
+
+// hasAttribute returns true if the given attribute is part of the list of attributes.
+func hasAttribute(attrs []attribute.KeyValue, attr attribute.KeyValue) bool {
+	for _, a := range attrs {
+		if a == attr {
+			return true
+		}
+	}
+	return false
+}
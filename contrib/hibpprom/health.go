@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibpprom
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wneessen/go-hibp"
+)
+
+// HealthMetrics exports a hibp.SubscriptionHealth as Prometheus metrics. The metrics are set with
+// Update, i. e. after each scheduled health check. It satisfies the prometheus.Collector interface
+// and needs to be registered with a prometheus.Registerer.
+type HealthMetrics struct {
+	mu sync.Mutex
+
+	daysRemaining       prometheus.Gauge
+	maxBreachedAccounts prometheus.Gauge
+	breachedAccounts    *prometheus.GaugeVec
+	growth              *prometheus.GaugeVec
+	capacity            *prometheus.GaugeVec
+	warnings            *prometheus.GaugeVec
+}
+
+// NewHealthMetrics returns a new HealthMetrics. Only the WithNamespace option applies to the
+// HealthMetrics
+func NewHealthMetrics(opts ...Option) *HealthMetrics {
+	o := &options{namespace: DefaultNamespace}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(o)
+	}
+
+	return &HealthMetrics{
+		daysRemaining: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Name:      "subscription_days_remaining",
+			Help:      "Days until the HIBP subscription ends, negative if it has expired.",
+		}),
+		maxBreachedAccounts: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Name:      "subscription_domain_search_max_breached_accounts",
+			Help:      "Size of the largest domain the HIBP subscription can search, 0 if there is no limit.",
+		}),
+		breachedAccounts: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Name:      "domain_breached_accounts",
+			Help:      "Breached accounts of a subscribed domain, excluding those that appear solely in spam lists.",
+		}, []string{"domain"}),
+		growth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Name:      "domain_breached_accounts_growth",
+			Help:      "Breached accounts of a subscribed domain that were added since the last subscription renewal.",
+		}, []string{"domain"}),
+		capacity: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Name:      "domain_search_capacity_ratio",
+			Help:      "Ratio of the breached accounts of a subscribed domain to the search capacity at the next subscription renewal.",
+		}, []string{"domain"}),
+		warnings: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Name:      "health_warning",
+			Help:      "Set to 1 for each warning of the last HIBP subscription health check.",
+		}, []string{"kind", "domain"}),
+	}
+}
+
+// Update sets the metrics to the given hibp.SubscriptionHealth. The metrics of domains and warnings
+// that are no longer part of the hibp.SubscriptionHealth are removed
+func (h *HealthMetrics) Update(health *hibp.SubscriptionHealth) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.daysRemaining.Set(health.DaysRemaining())
+	h.maxBreachedAccounts.Set(float64(health.MaxBreachedAccounts))
+	h.breachedAccounts.Reset()
+	h.growth.Reset()
+	h.capacity.Reset()
+	h.warnings.Reset()
+	for _, domain := range health.Domains {
+		if domain.NeverSearched {
+			continue
+		}
+		h.breachedAccounts.WithLabelValues(domain.Domain).Set(float64(domain.BreachedAccounts))
+		h.growth.WithLabelValues(domain.Domain).Set(float64(domain.Growth()))
+		if health.MaxBreachedAccounts > 0 {
+			h.capacity.WithLabelValues(domain.Domain).Set(domain.Capacity)
+		}
+	}
+	for _, warning := range health.Warnings {
+		h.warnings.WithLabelValues(warning.Kind.String(), warning.Domain).Set(1)
+	}
+}
+
+// Describe satisfies the prometheus.Collector interface for the HealthMetrics type
+func (h *HealthMetrics) Describe(ch chan<- *prometheus.Desc) {
+	h.daysRemaining.Describe(ch)
+	h.maxBreachedAccounts.Describe(ch)
+	h.breachedAccounts.Describe(ch)
+	h.growth.Describe(ch)
+	h.capacity.Describe(ch)
+	h.warnings.Describe(ch)
+}
+
+// Collect satisfies the prometheus.Collector interface for the HealthMetrics type
+func (h *HealthMetrics) Collect(ch chan<- prometheus.Metric) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.daysRemaining.Collect(ch)
+	h.maxBreachedAccounts.Collect(ch)
+	h.breachedAccounts.Collect(ch)
+	h.growth.Collect(ch)
+	h.capacity.Collect(ch)
+	h.warnings.Collect(ch)
+}
@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibpprom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/wneessen/go-hibp"
+)
+
+func TestHealthMetrics(t *testing.T) {
+	now := time.Now()
+	health := &hibp.SubscriptionHealth{
+		Checked:             now,
+		SubscribedUntil:     now.Add(10 * 24 * time.Hour),
+		MaxBreachedAccounts: 25,
+		Domains: []hibp.DomainHealth{
+			{Domain: "domain.tld", BreachedAccounts: 21, BreachedAccountsAtRenewal: 15, Capacity: 0.84},
+			{Domain: "new.tld", NeverSearched: true},
+		},
+		Warnings: []hibp.HealthWarning{
+			{Kind: hibp.HealthSubscriptionExpiring},
+			{Kind: hibp.HealthDomainCapacity, Domain: "domain.tld"},
+			{Kind: hibp.HealthDomainNeverSearched, Domain: "new.tld"},
+		},
+	}
+	metrics := NewHealthMetrics(WithNamespace("custom"), nil)
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(metrics); err != nil {
+		t.Fatalf("failed to register health metrics: %s", err)
+	}
+	metrics.Update(health)
+
+	if days := testutil.ToFloat64(metrics.daysRemaining); days != 10 {
+		t.Errorf("expected %d days remaining, got %f", 10, days)
+	}
+	if count := testutil.ToFloat64(metrics.breachedAccounts.WithLabelValues("domain.tld")); count != 21 {
+		t.Errorf("expected %d breached accounts, got %f", 21, count)
+	}
+	if growth := testutil.ToFloat64(metrics.growth.WithLabelValues("domain.tld")); growth != 6 {
+		t.Errorf("expected growth of %d, got %f", 6, growth)
+	}
+	if count := testutil.CollectAndCount(metrics.breachedAccounts); count != 1 {
+		t.Errorf("expected never searched domain to be omitted, got %d domains", count)
+	}
+	if count := testutil.CollectAndCount(metrics.warnings); count != 3 {
+		t.Errorf("expected %d warnings, got %d", 3, count)
+	}
+	if warning := testutil.ToFloat64(metrics.warnings.WithLabelValues("domain_capacity", "domain.tld")); warning != 1 {
+		t.Errorf("expected capacity warning to be set, got %f", warning)
+	}
+
+	health.Warnings = nil
+	metrics.Update(health)
+	if count := testutil.CollectAndCount(metrics.warnings); count != 0 {
+		t.Errorf("expected warnings to be removed, got %d", count)
+	}
+	if count := testutil.CollectAndCount(registry, "custom_subscription_days_remaining"); count != 1 {
+		t.Errorf("expected metric with custom namespace, got %d", count)
+	}
+}
@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+// Package hibpprom provides a go-hibp Observer that records Prometheus metrics about the requests
+// to the "Have I Been Pwned" API and HealthMetrics that export the subscription health checks. It
+// lives in its own module, so that the core go-hibp module does not depend on the Prometheus client
+// library.
+package hibpprom
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wneessen/go-hibp"
+)
+
+// DefaultNamespace is the default namespace for the metrics of the Observer
+const DefaultNamespace = "hibp"
+
+// StatusError is the status label value for API requests that failed without a HTTP response
+const StatusError = "error"
+
+// Observer is a hibp.Observer that records Prometheus metrics. It satisfies the prometheus.Collector
+// interface and needs to be registered with a prometheus.Registerer.
+type Observer struct {
+	hibp.NopObserver
+
+	requests      *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	retries       *prometheus.CounterVec
+	rateLimitWait *prometheus.CounterVec
+	cacheHits     *prometheus.CounterVec
+}
+
+// Option is a function that is used for grouping of Observer options.
+type Option func(*options)
+
+// options holds the configuration of the Observer
+type options struct {
+	namespace string
+	buckets   []float64
+}
+
+// WithNamespace overrides the DefaultNamespace of the metrics
+func WithNamespace(ns string) Option {
+	return func(o *options) {
+		o.namespace = ns
+	}
+}
+
+// WithBuckets overrides the default buckets of the request duration histogram
+func WithBuckets(b []float64) Option {
+	return func(o *options) {
+		o.buckets = b
+	}
+}
+
+// NewObserver returns a new Observer that records Prometheus metrics
+func NewObserver(opts ...Option) *Observer {
+	o := &options{
+		namespace: DefaultNamespace,
+		buckets:   prometheus.DefBuckets,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(o)
+	}
+
+	return &Observer{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "requests_total",
+			Help:      "Total number of requests to the HIBP API.",
+		}, []string{"method", "endpoint", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Duration of requests to the HIBP API, including retries and rate limit waits.",
+			Buckets:   o.buckets,
+		}, []string{"method", "endpoint"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "request_retries_total",
+			Help:      "Total number of retried requests to the HIBP API.",
+		}, []string{"method", "endpoint"}),
+		rateLimitWait: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "rate_limit_wait_seconds_total",
+			Help:      "Total time spent waiting for the HIBP API rate limit.",
+		}, []string{"method", "endpoint"}),
+		cacheHits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "cache_hits_total",
+			Help:      "Total number of results served from a cache of the HIBP client.",
+		}, []string{"cache"}),
+	}
+}
+
+// Response satisfies the hibp.Observer interface for the Observer type
+func (o *Observer) Response(_ context.Context, ev hibp.ResponseEvent) {
+	status := StatusError
+	if ev.StatusCode > 0 {
+		status = strconv.Itoa(ev.StatusCode)
+	}
+	o.requests.WithLabelValues(ev.Method, ev.Endpoint, status).Inc()
+	o.duration.WithLabelValues(ev.Method, ev.Endpoint).Observe(ev.Duration.Seconds())
+}
+
+// Retry satisfies the hibp.Observer interface for the Observer type
+func (o *Observer) Retry(_ context.Context, ev hibp.RetryEvent) {
+	o.retries.WithLabelValues(ev.Method, ev.Endpoint).Inc()
+	o.rateLimitWait.WithLabelValues(ev.Method, ev.Endpoint).Add(ev.Wait.Seconds())
+}
+
+// CacheHit satisfies the hibp.Observer interface for the Observer type
+func (o *Observer) CacheHit(_ context.Context, ev hibp.CacheEvent) {
+	o.cacheHits.WithLabelValues(ev.Cache).Inc()
+}
+
+// Describe satisfies the prometheus.Collector interface for the Observer type
+func (o *Observer) Describe(ch chan<- *prometheus.Desc) {
+	o.requests.Describe(ch)
+	o.duration.Describe(ch)
+	o.retries.Describe(ch)
+	o.rateLimitWait.Describe(ch)
+	o.cacheHits.Describe(ch)
+}
+
+// Collect satisfies the prometheus.Collector interface for the Observer type
+func (o *Observer) Collect(ch chan<- prometheus.Metric) {
+	o.requests.Collect(ch)
+	o.duration.Collect(ch)
+	o.retries.Collect(ch)
+	o.rateLimitWait.Collect(ch)
+	o.cacheHits.Collect(ch)
+}
@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibpprom
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/wneessen/go-hibp"
+)
+
+func TestObserver(t *testing.T) {
+	request := hibp.RequestEvent{Method: "GET", Endpoint: "/api/v3/breaches"}
+	t.Run("responses are counted by status", func(t *testing.T) {
+		observer := NewObserver()
+		ctx := observer.RequestStart(context.Background(), request)
+		observer.Response(ctx, hibp.ResponseEvent{RequestEvent: request, StatusCode: 200, Duration: time.Second})
+		observer.Response(ctx, hibp.ResponseEvent{RequestEvent: request, StatusCode: 200, Duration: time.Second})
+		observer.Response(ctx, hibp.ResponseEvent{RequestEvent: request, Err: errors.New("failed")})
+
+		if count := testutil.ToFloat64(observer.requests.WithLabelValues("GET", "/api/v3/breaches", "200")); count != 2 {
+			t.Errorf("expected %d successful requests, got %f", 2, count)
+		}
+		if count := testutil.ToFloat64(observer.requests.WithLabelValues("GET", "/api/v3/breaches",
+			StatusError)); count != 1 {
+			t.Errorf("expected %d failed request, got %f", 1, count)
+		}
+		if count := testutil.CollectAndCount(observer.duration); count != 1 {
+			t.Errorf("expected %d duration histogram, got %d", 1, count)
+		}
+	})
+	t.Run("retries and cache hits are counted", func(t *testing.T) {
+		observer := NewObserver()
+		observer.Retry(context.Background(), hibp.RetryEvent{RequestEvent: request, Attempt: 1, Wait: 2 * time.Second})
+		observer.CacheHit(context.Background(), hibp.CacheEvent{Cache: hibp.CacheBreachCatalogue})
+
+		if count := testutil.ToFloat64(observer.retries.WithLabelValues("GET", "/api/v3/breaches")); count != 1 {
+			t.Errorf("expected %d retry, got %f", 1, count)
+		}
+		if wait := testutil.ToFloat64(observer.rateLimitWait.WithLabelValues("GET", "/api/v3/breaches")); wait != 2 {
+			t.Errorf("expected rate limit wait of %d seconds, got %f", 2, wait)
+		}
+		if count := testutil.ToFloat64(observer.cacheHits.WithLabelValues(hibp.CacheBreachCatalogue)); count != 1 {
+			t.Errorf("expected %d cache hit, got %f", 1, count)
+		}
+	})
+	t.Run("observer registers with custom namespace", func(t *testing.T) {
+		observer := NewObserver(WithNamespace("custom"), WithBuckets([]float64{0.1, 1}), nil)
+		registry := prometheus.NewRegistry()
+		if err := registry.Register(observer); err != nil {
+			t.Fatalf("failed to register observer: %s", err)
+		}
+		observer.Response(context.Background(), hibp.ResponseEvent{RequestEvent: request, StatusCode: 200})
+		expected := `
+# HELP custom_requests_total Total number of requests to the HIBP API.
+# TYPE custom_requests_total counter
+custom_requests_total{endpoint="/api/v3/breaches",method="GET",status="200"} 1
+`
+		if err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+			"custom_requests_total"); err != nil {
+			t.Errorf("unexpected metrics: %s", err)
+		}
+	})
+}
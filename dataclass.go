@@ -0,0 +1,399 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DataClassCategory represents the category of a data class, i. e. the kind of data that was
+// compromised in a breach
+type DataClassCategory int
+
+const (
+	// DataClassUncategorised is the category of data classes that are not part of the taxonomy of
+	// the DataClassCatalogue
+	DataClassUncategorised DataClassCategory = iota
+	// DataClassCredentials represents data that grants access to accounts, like passwords, security
+	// questions or auth tokens
+	DataClassCredentials
+	// DataClassFinancial represents financial data, like credit cards, bank accounts or transactions
+	DataClassFinancial
+	// DataClassIdentity represents data that identifies or describes a person, like names, dates of
+	// birth, government issued IDs or health data
+	DataClassIdentity
+	// DataClassContact represents data that allows to contact a person, like email addresses, phone
+	// numbers or physical addresses
+	DataClassContact
+	// DataClassBehavioural represents data about the behaviour and activity of a person, like
+	// browsing histories, private messages, habits or device data
+	DataClassBehavioural
+)
+
+// String satisfies the fmt.Stringer interface for the DataClassCategory type
+func (c DataClassCategory) String() string {
+	switch c {
+	case DataClassCredentials:
+		return "credentials"
+	case DataClassFinancial:
+		return "financial"
+	case DataClassIdentity:
+		return "identity"
+	case DataClassContact:
+		return "contact"
+	case DataClassBehavioural:
+		return "behavioural"
+	default:
+		return "uncategorised"
+	}
+}
+
+// Severity represents how severe the compromise of data is. Severities can be compared, a higher
+// value is more severe
+type Severity int
+
+const (
+	// SeverityUnknown is the severity of data that is not rated, i. e. of uncategorised data classes
+	SeverityUnknown Severity = iota
+	// SeverityLow represents data with little potential for harm on its own, like genders or time zones
+	SeverityLow
+	// SeverityMedium represents personal data that enables phishing or profiling, like email addresses
+	// or phone numbers
+	SeverityMedium
+	// SeverityHigh represents sensitive data that enables fraud or discrimination, like bank account
+	// numbers, private messages or health data
+	SeverityHigh
+	// SeverityCritical represents data that allows immediate account takeover, financial loss or
+	// identity theft, like passwords, credit cards or government issued IDs
+	SeverityCritical
+)
+
+// String satisfies the fmt.Stringer interface for the Severity type
+func (s Severity) String() string {
+	switch s {
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// DataClass is a data class of the HIBP API with its category and severity
+type DataClass struct {
+	// Name is the name of the data class as returned by the API, i. e. "Email addresses"
+	Name string
+
+	// Category is the category of the data class
+	Category DataClassCategory
+
+	// Severity is the severity of the compromise of the data class
+	Severity Severity
+}
+
+// IsCategorised indicates whether the DataClass is part of the taxonomy of the DataClassCatalogue
+func (d DataClass) IsCategorised() bool {
+	return d.Category != DataClassUncategorised
+}
+
+// DataClassCatalogue maps the data classes of the HIBP API to a category and a severity. Data
+// classes are looked up case-insensitively. The DataClassCatalogue is safe for concurrent use.
+type DataClassCatalogue struct {
+	mu      sync.RWMutex
+	classes map[string]DataClass // Data classes, keyed by the lower case name
+}
+
+// NewDataClassCatalogue returns a new DataClassCatalogue with the taxonomy of all data classes
+// known to this package
+func NewDataClassCatalogue() *DataClassCatalogue {
+	c := &DataClassCatalogue{classes: make(map[string]DataClass, len(defaultDataClasses))}
+	c.Add(defaultDataClasses...)
+	return c
+}
+
+// Add adds the given data classes to the DataClassCatalogue. Existing data classes with the same
+// name are replaced, so the taxonomy can be extended and overridden
+func (c *DataClassCatalogue) Add(classes ...DataClass) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, class := range classes {
+		c.classes[strings.ToLower(class.Name)] = class
+	}
+}
+
+// Lookup returns the DataClass for the given data class name. If the name is not in the
+// DataClassCatalogue, an uncategorised DataClass with the given name is returned and the bool
+// return value is false
+func (c *DataClassCatalogue) Lookup(name string) (DataClass, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	class, ok := c.classes[strings.ToLower(name)]
+	if !ok {
+		return DataClass{Name: name}, false
+	}
+	return class, true
+}
+
+// Classes returns all data classes of the DataClassCatalogue, sorted by name
+func (c *DataClassCatalogue) Classes() []DataClass {
+	c.mu.RLock()
+	classes := make([]DataClass, 0, len(c.classes))
+	for _, class := range c.classes {
+		classes = append(classes, class)
+	}
+	c.mu.RUnlock()
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i].Name < classes[j].Name
+	})
+	return classes
+}
+
+// Unknown returns the names of the given data classes that are not categorised by the
+// DataClassCatalogue, in the given order
+func (c *DataClassCatalogue) Unknown(names []string) []string {
+	var unknown []string
+	for _, name := range names {
+		if class, _ := c.Lookup(name); !class.IsCategorised() {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// Refresh fetches the list of all data classes from the API and adds the data classes that are not
+// in the DataClassCatalogue yet as uncategorised data classes. The names of the added data classes
+// are returned, so that they can be categorised with Add
+func (c *DataClassCatalogue) Refresh(api *BreachAPI) ([]string, error) {
+	names, _, err := api.DataClasses()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var added []string
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if _, ok := c.classes[key]; ok {
+			continue
+		}
+		c.classes[key] = DataClass{Name: name}
+		added = append(added, name)
+	}
+	return added, nil
+}
+
+// Categories returns the categories of the data classes of the given Breach, each with the
+// highest severity of its data classes. Uncategorised data classes are not included
+func (c *DataClassCatalogue) Categories(b Breach) map[DataClassCategory]Severity {
+	categories := make(map[DataClassCategory]Severity)
+	for _, name := range b.DataClasses {
+		class, _ := c.Lookup(name)
+		if !class.IsCategorised() {
+			continue
+		}
+		if class.Severity > categories[class.Category] {
+			categories[class.Category] = class.Severity
+		}
+	}
+	return categories
+}
+
+// Severity returns the highest severity of the data classes of the given Breach. It is
+// SeverityUnknown if the Breach has no categorised data classes
+func (c *DataClassCatalogue) Severity(b Breach) Severity {
+	severity := SeverityUnknown
+	for _, s := range c.Categories(b) {
+		if s > severity {
+			severity = s
+		}
+	}
+	return severity
+}
+
+// Filter returns the breaches that have at least one data class in one of the given categories
+// with at least the given severity, in the given order. Without categories, the data classes of all
+// categories are considered
+func (c *DataClassCatalogue) Filter(breaches []Breach, minSeverity Severity, categories ...DataClassCategory) []Breach {
+	var filtered []Breach
+	for _, b := range breaches {
+		for category, severity := range c.Categories(b) {
+			if severity < minSeverity || (len(categories) > 0 && !containsCategory(categories, category)) {
+				continue
+			}
+			filtered = append(filtered, b)
+			break
+		}
+	}
+	return filtered
+}
+
+// containsCategory checks if the given list of categories contains the given category
+func containsCategory(categories []DataClassCategory, category DataClassCategory) bool {
+	for _, c := range categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultDataClasses is the taxonomy of all data classes known to this package
+var defaultDataClasses = []DataClass{
+	{"Account balances", DataClassFinancial, SeverityMedium},
+	{"Address book contacts", DataClassContact, SeverityMedium},
+	{"Age groups", DataClassIdentity, SeverityLow},
+	{"Ages", DataClassIdentity, SeverityLow},
+	{"Appointments", DataClassBehavioural, SeverityLow},
+	{"Apps installed on devices", DataClassBehavioural, SeverityLow},
+	{"Astrological signs", DataClassIdentity, SeverityLow},
+	{"Audio recordings", DataClassIdentity, SeverityMedium},
+	{"Auth tokens", DataClassCredentials, SeverityCritical},
+	{"Avatars", DataClassIdentity, SeverityLow},
+	{"Bank account numbers", DataClassFinancial, SeverityHigh},
+	{"Beauty ratings", DataClassIdentity, SeverityLow},
+	{"Biometric data", DataClassIdentity, SeverityCritical},
+	{"Bios", DataClassIdentity, SeverityLow},
+	{"Browser user agent details", DataClassBehavioural, SeverityLow},
+	{"Browsing histories", DataClassBehavioural, SeverityMedium},
+	{"Buying preferences", DataClassBehavioural, SeverityLow},
+	{"Car ownership statuses", DataClassIdentity, SeverityLow},
+	{"Career levels", DataClassIdentity, SeverityLow},
+	{"Cellular network names", DataClassContact, SeverityLow},
+	{"Charitable donations", DataClassFinancial, SeverityLow},
+	{"Chat logs", DataClassBehavioural, SeverityHigh},
+	{"Citizenship statuses", DataClassIdentity, SeverityMedium},
+	{"Clothing sizes", DataClassIdentity, SeverityLow},
+	{"Comments", DataClassBehavioural, SeverityLow},
+	{"Company names", DataClassIdentity, SeverityLow},
+	{"Credit card CVV", DataClassFinancial, SeverityCritical},
+	{"Credit cards", DataClassFinancial, SeverityCritical},
+	{"Credit status information", DataClassFinancial, SeverityHigh},
+	{"Cryptocurrency wallet addresses", DataClassFinancial, SeverityMedium},
+	{"Customer feedback", DataClassBehavioural, SeverityLow},
+	{"Customer interactions", DataClassBehavioural, SeverityLow},
+	{"Dates of birth", DataClassIdentity, SeverityMedium},
+	{"Deceased date", DataClassIdentity, SeverityLow},
+	{"Deceased statuses", DataClassIdentity, SeverityLow},
+	{"Delivery instructions", DataClassContact, SeverityLow},
+	{"Device information", DataClassBehavioural, SeverityLow},
+	{"Device serial numbers", DataClassBehavioural, SeverityLow},
+	{"Device usage tracking data", DataClassBehavioural, SeverityMedium},
+	{"Drinking habits", DataClassBehavioural, SeverityMedium},
+	{"Driver's licenses", DataClassIdentity, SeverityHigh},
+	{"Drug habits", DataClassBehavioural, SeverityHigh},
+	{"Eating habits", DataClassBehavioural, SeverityLow},
+	{"Education levels", DataClassIdentity, SeverityLow},
+	{"Email addresses", DataClassContact, SeverityMedium},
+	{"Email messages", DataClassBehavioural, SeverityHigh},
+	{"Employers", DataClassIdentity, SeverityLow},
+	{"Employment statuses", DataClassIdentity, SeverityLow},
+	{"Encrypted keys", DataClassCredentials, SeverityHigh},
+	{"Ethnicities", DataClassIdentity, SeverityMedium},
+	{"Family members' names", DataClassIdentity, SeverityLow},
+	{"Family plans", DataClassBehavioural, SeverityLow},
+	{"Family structure", DataClassIdentity, SeverityLow},
+	{"Financial investments", DataClassFinancial, SeverityMedium},
+	{"Financial transactions", DataClassFinancial, SeverityHigh},
+	{"Fitness levels", DataClassIdentity, SeverityLow},
+	{"Flights taken", DataClassBehavioural, SeverityMedium},
+	{"Genders", DataClassIdentity, SeverityLow},
+	{"Geographic locations", DataClassContact, SeverityMedium},
+	{"Government issued IDs", DataClassIdentity, SeverityCritical},
+	{"Health insurance information", DataClassIdentity, SeverityHigh},
+	{"Historical passwords", DataClassCredentials, SeverityHigh},
+	{"HIV statuses", DataClassIdentity, SeverityCritical},
+	{"Home ownership statuses", DataClassIdentity, SeverityLow},
+	{"Homepage URLs", DataClassContact, SeverityLow},
+	{"IMEI numbers", DataClassBehavioural, SeverityMedium},
+	{"IMSI numbers", DataClassBehavioural, SeverityMedium},
+	{"Income levels", DataClassFinancial, SeverityMedium},
+	{"Instant messenger identities", DataClassContact, SeverityLow},
+	{"IP addresses", DataClassBehavioural, SeverityMedium},
+	{"Job applications", DataClassIdentity, SeverityMedium},
+	{"Job titles", DataClassIdentity, SeverityLow},
+	{"Licence plates", DataClassIdentity, SeverityMedium},
+	{"Living costs", DataClassFinancial, SeverityLow},
+	{"Loan information", DataClassFinancial, SeverityHigh},
+	{"Login histories", DataClassBehavioural, SeverityMedium},
+	{"Loyalty program details", DataClassFinancial, SeverityLow},
+	{"MAC addresses", DataClassBehavioural, SeverityLow},
+	{"Marital statuses", DataClassIdentity, SeverityLow},
+	{"Military service", DataClassIdentity, SeverityMedium},
+	{"Mnemonic phrases", DataClassCredentials, SeverityCritical},
+	{"Mothers maiden names", DataClassCredentials, SeverityHigh},
+	{"Names", DataClassIdentity, SeverityLow},
+	{"Nationalities", DataClassIdentity, SeverityLow},
+	{"Net worths", DataClassFinancial, SeverityMedium},
+	{"Nicknames", DataClassIdentity, SeverityLow},
+	{"Occupations", DataClassIdentity, SeverityLow},
+	{"Parenting plans", DataClassBehavioural, SeverityLow},
+	{"Partial credit card data", DataClassFinancial, SeverityMedium},
+	{"Partial dates of birth", DataClassIdentity, SeverityLow},
+	{"Partial phone numbers", DataClassContact, SeverityLow},
+	{"Passport numbers", DataClassIdentity, SeverityCritical},
+	{"Password hints", DataClassCredentials, SeverityHigh},
+	{"Password strengths", DataClassCredentials, SeverityLow},
+	{"Passwords", DataClassCredentials, SeverityCritical},
+	{"Payment histories", DataClassFinancial, SeverityMedium},
+	{"Payment methods", DataClassFinancial, SeverityMedium},
+	{"Personal descriptions", DataClassIdentity, SeverityLow},
+	{"Personal health data", DataClassIdentity, SeverityHigh},
+	{"Personal interests", DataClassBehavioural, SeverityLow},
+	{"Phone numbers", DataClassContact, SeverityMedium},
+	{"Photos", DataClassIdentity, SeverityMedium},
+	{"Physical addresses", DataClassContact, SeverityMedium},
+	{"Physical attributes", DataClassIdentity, SeverityLow},
+	{"PINs", DataClassCredentials, SeverityHigh},
+	{"Places of birth", DataClassIdentity, SeverityMedium},
+	{"Political donations", DataClassFinancial, SeverityMedium},
+	{"Political views", DataClassIdentity, SeverityMedium},
+	{"Private messages", DataClassBehavioural, SeverityHigh},
+	{"Professional skills", DataClassIdentity, SeverityLow},
+	{"Profile photos", DataClassIdentity, SeverityLow},
+	{"Purchases", DataClassFinancial, SeverityLow},
+	{"Purchasing habits", DataClassBehavioural, SeverityLow},
+	{"Races", DataClassIdentity, SeverityMedium},
+	{"Recovery email addresses", DataClassCredentials, SeverityMedium},
+	{"Relationship statuses", DataClassIdentity, SeverityLow},
+	{"Religions", DataClassIdentity, SeverityMedium},
+	{"Reward program balances", DataClassFinancial, SeverityLow},
+	{"Salutations", DataClassIdentity, SeverityLow},
+	{"School grades (class levels)", DataClassIdentity, SeverityLow},
+	{"Security questions and answers", DataClassCredentials, SeverityHigh},
+	{"Sexual fetishes", DataClassIdentity, SeverityHigh},
+	{"Sexual orientations", DataClassIdentity, SeverityHigh},
+	{"Smoking habits", DataClassBehavioural, SeverityLow},
+	{"SMS messages", DataClassBehavioural, SeverityHigh},
+	{"Social connections", DataClassBehavioural, SeverityLow},
+	{"Social media profiles", DataClassContact, SeverityLow},
+	{"Social security numbers", DataClassIdentity, SeverityCritical},
+	{"Spoken languages", DataClassIdentity, SeverityLow},
+	{"Spouses names", DataClassIdentity, SeverityLow},
+	{"Support tickets", DataClassBehavioural, SeverityLow},
+	{"Survey results", DataClassBehavioural, SeverityLow},
+	{"Tattoo status", DataClassIdentity, SeverityLow},
+	{"Taxation records", DataClassFinancial, SeverityHigh},
+	{"Telecommunications carrier", DataClassContact, SeverityLow},
+	{"Time zones", DataClassContact, SeverityLow},
+	{"Travel habits", DataClassBehavioural, SeverityLow},
+	{"Travel plans", DataClassBehavioural, SeverityMedium},
+	{"User statuses", DataClassBehavioural, SeverityLow},
+	{"User website URLs", DataClassContact, SeverityLow},
+	{"Usernames", DataClassCredentials, SeverityMedium},
+	{"Utility bills", DataClassFinancial, SeverityMedium},
+	{"Vehicle details", DataClassIdentity, SeverityLow},
+	{"Vehicle identification numbers (VINs)", DataClassIdentity, SeverityMedium},
+	{"Warranty claims", DataClassBehavioural, SeverityLow},
+	{"Website activity", DataClassBehavioural, SeverityLow},
+	{"Work habits", DataClassBehavioural, SeverityLow},
+	{"Years of professional experience", DataClassIdentity, SeverityLow},
+}
@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDataClassCatalogue_Lookup(t *testing.T) {
+	catalogue := NewDataClassCatalogue()
+	tests := []struct {
+		name     string
+		class    string
+		category DataClassCategory
+		severity Severity
+		known    bool
+	}{
+		{"passwords are critical credentials", "Passwords", DataClassCredentials, SeverityCritical, true},
+		{"lookup is case-insensitive", "email ADDRESSES", DataClassContact, SeverityMedium, true},
+		{"credit cards are critical financial data", "Credit cards", DataClassFinancial, SeverityCritical, true},
+		{"names are identity data", "Names", DataClassIdentity, SeverityLow, true},
+		{"browsing histories are behavioural data", "Browsing histories", DataClassBehavioural, SeverityMedium, true},
+		{"unknown data class", "Favourite colours", DataClassUncategorised, SeverityUnknown, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class, ok := catalogue.Lookup(tt.class)
+			if ok != tt.known {
+				t.Errorf("expected data class to be known: %t, got %t", tt.known, ok)
+			}
+			if class.Category != tt.category {
+				t.Errorf("expected category %s, got %s", tt.category, class.Category)
+			}
+			if class.Severity != tt.severity {
+				t.Errorf("expected severity %s, got %s", tt.severity, class.Severity)
+			}
+			if !ok && class.Name != tt.class {
+				t.Errorf("expected unknown data class name %q, got %q", tt.class, class.Name)
+			}
+		})
+	}
+}
+
+func TestDataClassCatalogue_complete(t *testing.T) {
+	server := httptest.NewServer(newTestFileHandler(t, ServerResponseDataClasses))
+	defer server.Close()
+	hc := New(WithHTTPClient(newTestClient(t, server.URL)))
+	names, _, err := hc.BreachAPI.DataClasses()
+	if err != nil {
+		t.Fatalf("failed to get data classes: %s", err)
+	}
+	catalogue := NewDataClassCatalogue()
+	if unknown := catalogue.Unknown(names); len(unknown) != 0 {
+		t.Errorf("expected all data classes of the API to be categorised, got unknown: %v", unknown)
+	}
+	for _, class := range catalogue.Classes() {
+		if class.Severity == SeverityUnknown {
+			t.Errorf("expected data class %q to have a severity", class.Name)
+		}
+	}
+}
+
+func TestDataClassCatalogue_Refresh(t *testing.T) {
+	t.Run("new data classes are added uncategorised", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = fmt.Fprint(w, `["Email addresses","Passwords","Favourite colours"]`)
+		}))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)))
+		catalogue := NewDataClassCatalogue()
+		total := len(catalogue.Classes())
+		added, err := catalogue.Refresh(hc.BreachAPI)
+		if err != nil {
+			t.Fatalf("failed to refresh data class catalogue: %s", err)
+		}
+		if len(added) != 1 || added[0] != "Favourite colours" {
+			t.Errorf("expected %q to be added, got %v", "Favourite colours", added)
+		}
+		if len(catalogue.Classes()) != total+1 {
+			t.Errorf("expected %d data classes, got %d", total+1, len(catalogue.Classes()))
+		}
+		if _, ok := catalogue.Lookup("Favourite colours"); !ok {
+			t.Error("expected refreshed data class to be known")
+		}
+		if unknown := catalogue.Unknown([]string{"Passwords", "Favourite colours"}); len(unknown) != 1 {
+			t.Errorf("expected refreshed data class to be uncategorised, got %v", unknown)
+		}
+		added, err = catalogue.Refresh(hc.BreachAPI)
+		if err != nil {
+			t.Fatalf("failed to refresh data class catalogue: %s", err)
+		}
+		if len(added) != 0 {
+			t.Errorf("expected no data classes to be added on second refresh, got %v", added)
+		}
+
+		catalogue.Add(DataClass{Name: "Favourite colours", Category: DataClassIdentity, Severity: SeverityLow})
+		if unknown := catalogue.Unknown([]string{"Favourite colours"}); len(unknown) != 0 {
+			t.Errorf("expected added data class to be categorised, got %v", unknown)
+		}
+	})
+	t.Run("refresh fails on HTTP error", func(t *testing.T) {
+		server := httptest.NewServer(newTestFailureHandler(t, http.StatusInternalServerError))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)))
+		if _, err := NewDataClassCatalogue().Refresh(hc.BreachAPI); err == nil {
+			t.Error("expected refresh to fail on HTTP error")
+		}
+	})
+}
+
+func TestDataClassCatalogue_Severity(t *testing.T) {
+	catalogue := NewDataClassCatalogue()
+	breaches := []Breach{
+		{Name: "Passwords", DataClasses: []string{"Email addresses", "Passwords"}},
+		{Name: "Cards", DataClasses: []string{"Names", "Credit cards"}},
+		{Name: "Contacts", DataClasses: []string{"Email addresses", "Names"}},
+		{Name: "Unknown", DataClasses: []string{"Favourite colours"}},
+	}
+
+	categories := catalogue.Categories(breaches[0])
+	if len(categories) != 2 || categories[DataClassContact] != SeverityMedium ||
+		categories[DataClassCredentials] != SeverityCritical {
+		t.Errorf("unexpected categories: %v", categories)
+	}
+	severities := []Severity{SeverityCritical, SeverityCritical, SeverityMedium, SeverityUnknown}
+	for i, b := range breaches {
+		if s := catalogue.Severity(b); s != severities[i] {
+			t.Errorf("expected severity of breach %s to be %s, got %s", b.Name, severities[i], s)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		minSeverity Severity
+		categories  []DataClassCategory
+		want        []string
+	}{
+		{"all categorised breaches", SeverityUnknown, nil, []string{"Passwords", "Cards", "Contacts"}},
+		{"critical breaches", SeverityCritical, nil, []string{"Passwords", "Cards"}},
+		{"financial breaches", SeverityLow, []DataClassCategory{DataClassFinancial}, []string{"Cards"}},
+		{"identity or credentials", SeverityLow, []DataClassCategory{DataClassIdentity, DataClassCredentials},
+			[]string{"Passwords", "Cards", "Contacts"}},
+		{"high severity identity data", SeverityHigh, []DataClassCategory{DataClassIdentity}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered := catalogue.Filter(breaches, tt.minSeverity, tt.categories...)
+			if len(filtered) != len(tt.want) {
+				t.Fatalf("expected %d breaches, got %d", len(tt.want), len(filtered))
+			}
+			for i, b := range filtered {
+				if b.Name != tt.want[i] {
+					t.Errorf("expected breach %d to be %s, got %s", i, tt.want[i], b.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestSeverity_String(t *testing.T) {
+	if SeverityCritical.String() != "critical" || Severity(99).String() != "unknown" {
+		t.Errorf("unexpected severity strings: %s, %s", SeverityCritical, Severity(99))
+	}
+	if DataClassBehavioural.String() != "behavioural" || DataClassUncategorised.String() != "uncategorised" {
+		t.Errorf("unexpected category strings: %s, %s", DataClassBehavioural, DataClassUncategorised)
+	}
+}
@@ -0,0 +1,331 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"encoding/xml"
+	"html"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// descriptionTags is the allow-list of HTML elements that are kept in a sanitised breach
+// description. All other elements are removed, but their text content is kept
+var descriptionTags = map[string]bool{
+	"a": true, "b": true, "br": true, "em": true, "i": true, "li": true, "ol": true, "p": true,
+	"strong": true, "ul": true,
+}
+
+// descriptionVoidTags is the list of allowed HTML elements that have no content and no end tag
+var descriptionVoidTags = map[string]bool{"br": true}
+
+// descriptionDropTags is the list of HTML elements that are removed from a breach description
+// together with their content
+var descriptionDropTags = map[string]bool{
+	"iframe": true, "noscript": true, "object": true, "script": true, "style": true, "template": true,
+}
+
+// descriptionSchemes is the allow-list of URL schemes for the links in a breach description
+var descriptionSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// descriptionNode is a node of the parsed and allow-listed breach description. A node either holds
+// text or is an allowed HTML element with child nodes
+type descriptionNode struct {
+	tag      string
+	href     string
+	text     string
+	children []*descriptionNode
+}
+
+// DescriptionHTML returns the Description of the Breach as sanitised HTML fragment. Only the
+// allow-listed elements a, b, br, em, i, li, ol, p, strong and ul are kept, all attributes except
+// for the href of links are removed and script-like elements are removed with their content.
+// Links are restricted to http, https and mailto URLs and are marked with
+// rel="noopener noreferrer nofollow". The returned fragment is always well-formed.
+func (b Breach) DescriptionHTML() string {
+	var sb strings.Builder
+	for _, n := range parseDescription(b.Description).children {
+		n.writeHTML(&sb)
+	}
+	return sb.String()
+}
+
+// DescriptionText returns the Description of the Breach as plain text. Links are rendered as
+// "text (URL)", list items are put on separate lines and prefixed with "- "
+func (b Breach) DescriptionText() string {
+	var sb strings.Builder
+	for _, n := range parseDescription(b.Description).children {
+		n.writeText(&sb, false)
+	}
+	return tidyText(sb.String())
+}
+
+// DescriptionMarkdown returns the Description of the Breach as Markdown. Links are rendered as
+// "[text](URL)", emphasis as "*text*" and "**text**" and list items are prefixed with "- ". Markdown
+// control characters in the text are escaped
+func (b Breach) DescriptionMarkdown() string {
+	var sb strings.Builder
+	for _, n := range parseDescription(b.Description).children {
+		n.writeText(&sb, true)
+	}
+	return tidyText(sb.String())
+}
+
+// parseDescription tokenizes the given HTML fragment with the lenient mode of the encoding/xml
+// decoder and returns the tree of the allow-listed elements and the text. Unbalanced elements are
+// closed automatically. Malformed tags are skipped. If the decoder fails on anything else, the
+// rest of the input is treated as text
+func parseDescription(s string) *descriptionNode {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	root := &descriptionNode{}
+	stack := []*descriptionNode{root}
+	drop := 0
+
+	dec := newDescriptionDecoder(s)
+	base := int64(0)
+	for {
+		offset := base + dec.InputOffset()
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Skip a malformed tag and continue after it. Everything else is kept as text
+			end := strings.IndexByte(s[offset:], '>')
+			if end < 0 || s[offset] != '<' {
+				if drop == 0 {
+					stack[len(stack)-1].appendText(html.UnescapeString(s[offset:]))
+				}
+				break
+			}
+			base = offset + int64(end) + 1
+			dec = newDescriptionDecoder(s[base:])
+			continue
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			tag := strings.ToLower(t.Name.Local)
+			switch {
+			case descriptionDropTags[tag]:
+				drop++
+			case drop > 0 || !descriptionTags[tag]:
+			default:
+				stack = closeImplicit(stack, tag)
+				n := &descriptionNode{tag: tag}
+				if tag == "a" {
+					n.href = descriptionHref(t.Attr)
+				}
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+				if !descriptionVoidTags[tag] {
+					stack = append(stack, n)
+				}
+			}
+		case xml.EndElement:
+			tag := strings.ToLower(t.Name.Local)
+			if descriptionDropTags[tag] {
+				if drop > 0 {
+					drop--
+				}
+				continue
+			}
+			if drop > 0 || !descriptionTags[tag] {
+				continue
+			}
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].tag == tag {
+					stack = stack[:i]
+					break
+				}
+			}
+		case xml.CharData:
+			if drop == 0 {
+				stack[len(stack)-1].appendText(string(t))
+			}
+		}
+	}
+	return root
+}
+
+// newDescriptionDecoder returns a lenient encoding/xml decoder for the given HTML fragment
+func newDescriptionDecoder(s string) *xml.Decoder {
+	dec := xml.NewDecoder(strings.NewReader(s))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+	return dec
+}
+
+// closeImplicit closes the open elements that are implicitly ended by the start of the given
+// element, i. e. a list item ends the previous list item of the same list and a paragraph ends the
+// previous paragraph
+func closeImplicit(stack []*descriptionNode, tag string) []*descriptionNode {
+	if tag != "li" && tag != "p" {
+		return stack
+	}
+	for i := len(stack) - 1; i > 0; i-- {
+		switch stack[i].tag {
+		case tag:
+			return stack[:i]
+		case "ul", "ol":
+			return stack
+		}
+	}
+	return stack
+}
+
+// appendText appends a text node to the descriptionNode
+func (n *descriptionNode) appendText(text string) {
+	if text == "" {
+		return
+	}
+	n.children = append(n.children, &descriptionNode{text: text})
+}
+
+// descriptionHref returns the href attribute of a link, if it is an absolute URL with an allowed
+// scheme. Otherwise, an empty string is returned
+func descriptionHref(attrs []xml.Attr) string {
+	for _, attr := range attrs {
+		if !strings.EqualFold(attr.Name.Local, "href") || attr.Name.Space != "" {
+			continue
+		}
+		u, err := url.Parse(strings.TrimSpace(attr.Value))
+		if err != nil || !descriptionSchemes[strings.ToLower(u.Scheme)] {
+			return ""
+		}
+		if u.Scheme != "mailto" && u.Host == "" {
+			return ""
+		}
+		return u.String()
+	}
+	return ""
+}
+
+// writeHTML writes the descriptionNode and its children as HTML to the given strings.Builder
+func (n *descriptionNode) writeHTML(sb *strings.Builder) {
+	if n.tag == "" {
+		sb.WriteString(html.EscapeString(n.text))
+		return
+	}
+	if n.tag == "a" && n.href == "" {
+		for _, c := range n.children {
+			c.writeHTML(sb)
+		}
+		return
+	}
+	sb.WriteString("<" + n.tag)
+	if n.tag == "a" {
+		sb.WriteString(` href="` + html.EscapeString(n.href) + `" rel="noopener noreferrer nofollow"`)
+	}
+	sb.WriteString(">")
+	if descriptionVoidTags[n.tag] {
+		return
+	}
+	for _, c := range n.children {
+		c.writeHTML(sb)
+	}
+	sb.WriteString("</" + n.tag + ">")
+}
+
+// writeText writes the descriptionNode and its children as plain text or as Markdown to the given
+// strings.Builder
+func (n *descriptionNode) writeText(sb *strings.Builder, markdown bool) {
+	var content strings.Builder
+	for _, c := range n.children {
+		c.writeText(&content, markdown)
+	}
+	inner := content.String()
+
+	switch n.tag {
+	case "":
+		text := collapseSpace(n.text)
+		if markdown {
+			text = markdownEscaper.Replace(text)
+		}
+		sb.WriteString(text)
+	case "a":
+		label := strings.TrimSpace(inner)
+		switch {
+		case n.href == "":
+			sb.WriteString(inner)
+		case markdown:
+			if label == "" {
+				label = markdownEscaper.Replace(n.href)
+			}
+			sb.WriteString("[" + label + "](" + markdownURLEscaper.Replace(n.href) + ")")
+		case label == "" || label == n.href:
+			sb.WriteString(n.href)
+		default:
+			sb.WriteString(inner + " (" + n.href + ")")
+		}
+	case "b", "strong":
+		if markdown && strings.TrimSpace(inner) != "" {
+			sb.WriteString("**" + strings.TrimSpace(inner) + "**")
+			return
+		}
+		sb.WriteString(inner)
+	case "em", "i":
+		if markdown && strings.TrimSpace(inner) != "" {
+			sb.WriteString("*" + strings.TrimSpace(inner) + "*")
+			return
+		}
+		sb.WriteString(inner)
+	case "br":
+		sb.WriteString("\n")
+	case "li":
+		sb.WriteString("\n- " + strings.TrimSpace(inner))
+	case "p", "ul", "ol":
+		sb.WriteString("\n\n" + inner + "\n\n")
+	default:
+		sb.WriteString(inner)
+	}
+}
+
+// markdownEscaper escapes the Markdown control characters in text
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `\<`, `>`, `\>`, `#`, `\#`,
+)
+
+// markdownURLEscaper escapes the characters of a URL that would end a Markdown link
+var markdownURLEscaper = strings.NewReplacer(`(`, `%28`, `)`, `%29`, ` `, `%20`)
+
+// collapseSpace replaces all runs of whitespace in the given text with a single space, like
+// browsers do when rendering HTML
+func collapseSpace(text string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range text {
+		switch r {
+		case ' ', '\t', '\n', '\r', '\f':
+			space = true
+			continue
+		}
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+		sb.WriteRune(r)
+	}
+	if space {
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
+
+// tidyText trims the spaces around the lines of the given text, collapses repeated blank lines into
+// a single one and trims the text
+func tidyText(text string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" && (len(out) == 0 || out[len(out)-1] == "") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
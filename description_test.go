@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBreach_DescriptionHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		desc string
+		want string
+	}{
+		{
+			"link attributes are replaced",
+			`A <a href="https://example.com/post" target="_blank" rel="noopener" onclick="x()">breach</a>.`,
+			`A <a href="https://example.com/post" rel="noopener noreferrer nofollow">breach</a>.`,
+		},
+		{
+			"javascript link is unwrapped",
+			`<a href="javascript:alert(1)">click</a>`,
+			`click`,
+		},
+		{
+			"relative link is unwrapped",
+			`<a href="/breach/Adobe">Adobe</a>`,
+			`Adobe`,
+		},
+		{
+			"mailto link is kept",
+			`<a href="mailto:cybercrime@politsei.ee">mail</a>`,
+			`<a href="mailto:cybercrime@politsei.ee" rel="noopener noreferrer nofollow">mail</a>`,
+		},
+		{
+			"script is removed with content",
+			`before<script>alert("x")</script>after`,
+			`beforeafter`,
+		},
+		{
+			"unknown elements are removed but text is kept",
+			`<div class="x"><span style="color:red">red</span> <img src="x" onerror="y()"/>text</div>`,
+			`red text`,
+		},
+		{
+			"attributes of allowed elements are removed",
+			`<b onmouseover="x()">bold</b> <em class="y">em</em>`,
+			`<b>bold</b> <em>em</em>`,
+		},
+		{
+			"entities are decoded and escaped",
+			`&quot;Tom&quot; &amp; Jerry &mdash; &lt;script&gt;`,
+			`&#34;Tom&#34; &amp; Jerry — &lt;script&gt;`,
+		},
+		{
+			"unbalanced elements are closed",
+			`<b>bold <i>both</b> none`,
+			`<b>bold <i>both</i></b> none`,
+		},
+		{
+			"void element",
+			`line<br>next<br/>last`,
+			`line<br>next<br>last`,
+		},
+		{
+			"lists",
+			`<ul><li>one<li>two</ul>`,
+			`<ul><li>one</li><li>two</li></ul>`,
+		},
+		{
+			"malformed tag is skipped",
+			`Text <a href="https://example.com target="_blank" rel="noopener">link</a> <b>end</b>`,
+			`Text link <b>end</b>`,
+		},
+		{
+			"paragraphs are closed implicitly",
+			`<p>one<p>two`,
+			`<p>one</p><p>two</p>`,
+		},
+		{"empty description", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Breach{Description: tt.desc}.DescriptionHTML()
+			if got != tt.want {
+				t.Errorf("expected HTML to be %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBreach_DescriptionText(t *testing.T) {
+	tests := []struct {
+		name string
+		desc string
+		want string
+	}{
+		{
+			"links are rendered with URL",
+			`The <a href="https://example.com/post" target="_blank">site was breached</a> in 2020.`,
+			`The site was breached (https://example.com/post) in 2020.`,
+		},
+		{
+			"link with URL as text",
+			`See <a href="https://example.com">https://example.com</a>.`,
+			`See https://example.com.`,
+		},
+		{
+			"javascript link is unwrapped",
+			`<a href="javascript:alert(1)">click</a> here`,
+			`click here`,
+		},
+		{
+			"emphasis and entities",
+			`It was a <em>very</em> bad year &mdash; &quot;really&quot;`,
+			`It was a very bad year — "really"`,
+		},
+		{
+			"script is removed with content",
+			`before <style>b { color: red }</style>after`,
+			`before after`,
+		},
+		{
+			"paragraphs and lists",
+			"<p>First  paragraph\n with  spaces.</p><p>Data:</p><ul><li>emails</li><li> passwords </li></ul>",
+			"First paragraph with spaces.\n\nData:\n\n- emails\n- passwords",
+		},
+		{
+			"line breaks",
+			`one<br>two`,
+			"one\ntwo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Breach{Description: tt.desc}.DescriptionText()
+			if got != tt.want {
+				t.Errorf("expected text to be %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBreach_DescriptionMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		desc string
+		want string
+	}{
+		{
+			"links",
+			`The <a href="https://example.com/post" target="_blank">site was breached</a>.`,
+			`The [site was breached](https://example.com/post).`,
+		},
+		{
+			"link URL is escaped",
+			`<a href="https://example.com/a_(b)">link</a>`,
+			`[link](https://example.com/a_%28b%29)`,
+		},
+		{
+			"emphasis",
+			`<b><i>important</i></b> and <em>very</em> <strong>bold</strong>`,
+			`***important*** and *very* **bold**`,
+		},
+		{
+			"control characters are escaped",
+			`*stars* and [brackets] and _under_score &lt;b&gt; #1`,
+			`\*stars\* and \[brackets\] and \_under\_score \<b\> \#1`,
+		},
+		{
+			"lists",
+			`<ol><li>one</li><li>two</li></ol>`,
+			"- one\n- two",
+		},
+		{
+			"javascript link is unwrapped",
+			`<a href="javascript:alert(1)">click</a>`,
+			`click`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Breach{Description: tt.desc}.DescriptionMarkdown()
+			if got != tt.want {
+				t.Errorf("expected Markdown to be %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBreach_Description_catalogue(t *testing.T) {
+	server := httptest.NewServer(newTestFileHandler(t, ServerResponseBreachesAllNonTruncatedUnverified))
+	defer server.Close()
+	hc := New(WithHTTPClient(newTestClient(t, server.URL)))
+	breaches, _, err := hc.BreachAPI.Breaches()
+	if err != nil {
+		t.Fatalf("failed to get breaches: %s", err)
+	}
+	for _, breach := range breaches {
+		descHTML := breach.DescriptionHTML()
+		if strings.Contains(descHTML, "target=") {
+			t.Errorf("expected target attribute to be removed from description of %s", breach.Name)
+		}
+		if strings.Contains(breach.Description, "<a ") && !strings.Contains(descHTML, `rel="noopener noreferrer nofollow"`) {
+			t.Errorf("expected links to be kept in description of %s", breach.Name)
+		}
+		if text := breach.DescriptionText(); strings.ContainsAny(text, "<>") {
+			t.Errorf("expected text description of %s not to contain markup, got %q", breach.Name, text)
+		}
+	}
+}
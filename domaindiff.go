@@ -0,0 +1,256 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// DomainSnapshot holds the breached aliases of the searched domains at a point in time
+type DomainSnapshot struct {
+	// Taken is the time the domains were searched
+	Taken time.Time `json:"taken"`
+
+	// Domains maps the searched domains to their breached aliases. Each alias is mapped to the names
+	// of the breaches it appeared in
+	Domains map[string]map[string][]string `json:"domains"`
+}
+
+// NewDomainSnapshot returns a new DomainSnapshot of the searched domains of the given
+// DomainSearchResult, taken at the current time
+func NewDomainSnapshot(result *DomainSearchResult) *DomainSnapshot {
+	snapshot := &DomainSnapshot{Taken: time.Now().UTC(), Domains: make(map[string]map[string][]string)}
+	if result == nil {
+		return snapshot
+	}
+	for domain, aliases := range result.Domains {
+		snapshot.Domains[domain] = aliases
+	}
+	return snapshot
+}
+
+// DomainStore persists the DomainSnapshot of the last domain search, so that the next domain search
+// can be compared with it
+type DomainStore interface {
+	// Load returns the last saved DomainSnapshot. It returns nil without error if no DomainSnapshot
+	// has been saved yet
+	Load() (*DomainSnapshot, error)
+
+	// Save stores the given DomainSnapshot and replaces the last saved one
+	Save(snapshot *DomainSnapshot) error
+}
+
+// FileDomainStore is a DomainStore that stores the DomainSnapshot as JSON in a file
+type FileDomainStore struct {
+	path string
+}
+
+// NewFileDomainStore returns a new FileDomainStore that stores the DomainSnapshot in the file with
+// the given path. A missing file means that no DomainSnapshot has been saved yet
+func NewFileDomainStore(path string) *FileDomainStore {
+	return &FileDomainStore{path: path}
+}
+
+// Load satisfies the DomainStore interface for the FileDomainStore type
+func (f *FileDomainStore) Load() (*DomainSnapshot, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshot DomainSnapshot
+	if err = json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse domain snapshot file: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// Save satisfies the DomainStore interface for the FileDomainStore type. The DomainSnapshot is
+// written to a temporary file first, so that a crash never leaves a partially written file
+func (f *FileDomainStore) Save(snapshot *DomainSnapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(f.path, data)
+}
+
+// AliasChange is the change of the breaches of a single alias between two DomainSnapshot
+type AliasChange struct {
+	// Domain is the domain of the alias
+	Domain string `json:"domain"`
+
+	// Alias is the alias, i. e. the local part of the email address
+	Alias string `json:"alias"`
+
+	// Added holds the names of the breaches the alias newly appeared in
+	Added []string `json:"added,omitempty"`
+
+	// Removed holds the names of the breaches the alias no longer appears in
+	Removed []string `json:"removed,omitempty"`
+}
+
+// DomainDiff holds the changes of the breached aliases between two DomainSnapshot. The changes are
+// sorted by domain and alias
+type DomainDiff struct {
+	// NewAliases holds the aliases that were not breached in the previous DomainSnapshot
+	NewAliases []AliasChange `json:"newAliases"`
+
+	// NewBreaches holds the aliases that were breached before and appeared in new breaches
+	NewBreaches []AliasChange `json:"newBreaches"`
+
+	// RemovedBreaches holds the aliases that no longer appear in some or all of their previous
+	// breaches, i. e. because a breach was removed or flagged as spam list
+	RemovedBreaches []AliasChange `json:"removedBreaches"`
+}
+
+// Empty checks if the DomainDiff does not hold any changes
+func (d *DomainDiff) Empty() bool {
+	return len(d.NewAliases) == 0 && len(d.NewBreaches) == 0 && len(d.RemovedBreaches) == 0
+}
+
+// DiffDomainSnapshots compares the given DomainSnapshot with the previous one. A nil previous
+// DomainSnapshot is treated as empty, so that all aliases are reported as new. Domains that are
+// missing in the current DomainSnapshot, i. e. because their search failed, are not compared
+func DiffDomainSnapshots(previous, current *DomainSnapshot) *DomainDiff {
+	diff := &DomainDiff{}
+	if current == nil {
+		return diff
+	}
+	var prevDomains map[string]map[string][]string
+	if previous != nil {
+		prevDomains = previous.Domains
+	}
+
+	for _, domain := range sortedKeys(current.Domains) {
+		prevAliases, curAliases := prevDomains[domain], current.Domains[domain]
+		for _, alias := range sortedKeys(curAliases) {
+			prevBreaches, ok := prevAliases[alias]
+			if !ok {
+				diff.NewAliases = append(diff.NewAliases, AliasChange{
+					Domain: domain, Alias: alias, Added: sortedCopy(curAliases[alias]),
+				})
+				continue
+			}
+			added, removed := diffBreachNames(prevBreaches, curAliases[alias])
+			if len(added) > 0 {
+				diff.NewBreaches = append(diff.NewBreaches, AliasChange{Domain: domain, Alias: alias, Added: added})
+			}
+			if len(removed) > 0 {
+				diff.RemovedBreaches = append(diff.RemovedBreaches,
+					AliasChange{Domain: domain, Alias: alias, Removed: removed})
+			}
+		}
+		for _, alias := range sortedKeys(prevAliases) {
+			if _, ok := curAliases[alias]; !ok {
+				diff.RemovedBreaches = append(diff.RemovedBreaches, AliasChange{
+					Domain: domain, Alias: alias, Removed: sortedCopy(prevAliases[alias]),
+				})
+			}
+		}
+	}
+	sortAliasChanges(diff.RemovedBreaches)
+	return diff
+}
+
+// DiffDomains searches all subscribed domains with SearchAllDomains, compares the result with the
+// DomainSnapshot of the given DomainStore and saves the new DomainSnapshot to the DomainStore. It is
+// meant to be run by a scheduled job, each run reports the changes since the previous run.
+// This API is authenticated and requires a valid API key.
+//
+// Domains whose search failed or that were skipped are taken over from the previous DomainSnapshot,
+// so that they are neither reported as changed nor dropped from the saved DomainSnapshot and are
+// compared again in the next run they are searched in. The DomainSearchResult is returned to report the failed and
+// skipped domains. If the domain search stops, the DomainStore is not updated.
+func (b *BreachAPI) DiffDomains(store DomainStore, options ...DomainSearchOption,
+) (*DomainDiff, *DomainSearchResult, error) {
+	previous, err := store.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load domain snapshot: %w", err)
+	}
+	result, err := b.SearchAllDomains(options...)
+	if err != nil {
+		return nil, result, err
+	}
+
+	current := NewDomainSnapshot(result)
+	if previous != nil {
+		carried := append([]string{}, result.Skipped...)
+		for domain := range result.Errors {
+			carried = append(carried, domain)
+		}
+		for _, domain := range carried {
+			if aliases, ok := previous.Domains[domain]; ok {
+				current.Domains[domain] = aliases
+			}
+		}
+	}
+	diff := DiffDomainSnapshots(previous, current)
+	if err = store.Save(current); err != nil {
+		return diff, result, fmt.Errorf("failed to save domain snapshot: %w", err)
+	}
+	return diff, result, nil
+}
+
+// diffBreachNames returns the sorted names of the breaches that were added to and removed from the
+// given previous breach names
+func diffBreachNames(previous, current []string) ([]string, []string) {
+	prev := make(map[string]struct{}, len(previous))
+	for _, name := range previous {
+		prev[name] = struct{}{}
+	}
+	cur := make(map[string]struct{}, len(current))
+	var added []string
+	for _, name := range current {
+		cur[name] = struct{}{}
+		if _, ok := prev[name]; !ok {
+			added = append(added, name)
+		}
+	}
+	var removed []string
+	for _, name := range previous {
+		if _, ok := cur[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// sortAliasChanges sorts the given AliasChange by domain and alias
+func sortAliasChanges(changes []AliasChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Domain != changes[j].Domain {
+			return changes[i].Domain < changes[j].Domain
+		}
+		return changes[i].Alias < changes[j].Alias
+	})
+}
+
+// sortedKeys returns the sorted keys of the given map
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedCopy returns a sorted copy of the given strings
+func sortedCopy(s []string) []string {
+	c := make([]string, len(s))
+	copy(c, s)
+	sort.Strings(c)
+	return c
+}
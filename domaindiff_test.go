@@ -0,0 +1,193 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDiffDomainSnapshots(t *testing.T) {
+	previous := &DomainSnapshot{Domains: map[string]map[string][]string{
+		"domain.tld": {
+			"toni.tester": {"Foodora"},
+			"tina.tester": {"Adobe", "Lastfm"},
+			"paul":        {"PDL"},
+		},
+		"failed.tld": {"info": {"PDL"}},
+	}}
+	current := &DomainSnapshot{Domains: map[string]map[string][]string{
+		"domain.tld": {
+			"toni.tester": {"Twitter200M", "Foodora"},
+			"tina.tester": {"Adobe"},
+			"sales":       {"PDL", "B2BUSABusinesses"},
+		},
+		"new.tld": {"info": {"LeadHunter"}},
+	}}
+
+	diff := DiffDomainSnapshots(previous, current)
+	wantNew := []AliasChange{
+		{Domain: "domain.tld", Alias: "sales", Added: []string{"B2BUSABusinesses", "PDL"}},
+		{Domain: "new.tld", Alias: "info", Added: []string{"LeadHunter"}},
+	}
+	if !reflect.DeepEqual(diff.NewAliases, wantNew) {
+		t.Errorf("expected new aliases %v, got %v", wantNew, diff.NewAliases)
+	}
+	wantBreaches := []AliasChange{{Domain: "domain.tld", Alias: "toni.tester", Added: []string{"Twitter200M"}}}
+	if !reflect.DeepEqual(diff.NewBreaches, wantBreaches) {
+		t.Errorf("expected new breaches %v, got %v", wantBreaches, diff.NewBreaches)
+	}
+	wantRemoved := []AliasChange{
+		{Domain: "domain.tld", Alias: "paul", Removed: []string{"PDL"}},
+		{Domain: "domain.tld", Alias: "tina.tester", Removed: []string{"Lastfm"}},
+	}
+	if !reflect.DeepEqual(diff.RemovedBreaches, wantRemoved) {
+		t.Errorf("expected removed breaches %v, got %v", wantRemoved, diff.RemovedBreaches)
+	}
+
+	if diff = DiffDomainSnapshots(current, current); !diff.Empty() {
+		t.Errorf("expected diff of equal snapshots to be empty, got %v", diff)
+	}
+	if diff = DiffDomainSnapshots(nil, current); len(diff.NewAliases) != 4 {
+		t.Errorf("expected all aliases to be new without previous snapshot, got %v", diff.NewAliases)
+	}
+}
+
+func TestFileDomainStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "domains.json")
+	store := NewFileDomainStore(path)
+	snapshot, err := store.Load()
+	if err != nil || snapshot != nil {
+		t.Fatalf("expected no snapshot for missing file, got %v, %v", snapshot, err)
+	}
+	saved := NewDomainSnapshot(&DomainSearchResult{Domains: map[string]map[string][]string{
+		"domain.tld": {"toni.tester": {"Foodora"}},
+	}})
+	if err = store.Save(saved); err != nil {
+		t.Fatalf("failed to save snapshot: %s", err)
+	}
+	snapshot, err = store.Load()
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %s", err)
+	}
+	if !snapshot.Taken.Equal(saved.Taken) || !reflect.DeepEqual(snapshot.Domains, saved.Domains) {
+		t.Errorf("expected loaded snapshot %v, got %v", saved, snapshot)
+	}
+
+	if err = os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatalf("failed to write snapshot file: %s", err)
+	}
+	if _, err = store.Load(); err == nil {
+		t.Error("expected loading of invalid snapshot file to fail")
+	}
+}
+
+func TestBreachAPI_DiffDomains(t *testing.T) {
+	var run atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/subscribeddomains":
+			_, _ = fmt.Fprint(w, `[{"DomainName":"domain.tld","PwnCount":2},{"DomainName":"broken.tld","PwnCount":1}]`)
+		case "/api/v3/breacheddomain/domain.tld":
+			if run.Load() == 1 {
+				_, _ = fmt.Fprint(w, `{"toni.tester":["Foodora"]}`)
+				return
+			}
+			_, _ = fmt.Fprint(w, `{"toni.tester":["Foodora"],"tina.tester":["Adobe"]}`)
+		case "/api/v3/breacheddomain/broken.tld":
+			if run.Load() == 1 {
+				_, _ = fmt.Fprint(w, `{"info":["PDL"]}`)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+	store := NewFileDomainStore(filepath.Join(t.TempDir(), "domains.json"))
+
+	run.Store(1)
+	diff, _, err := hc.BreachAPI.DiffDomains(store, WithDomainSearchRPM(60000), WithDomainSearchConcurrency(1))
+	if err != nil {
+		t.Fatalf("failed to diff domains: %s", err)
+	}
+	if len(diff.NewAliases) != 2 {
+		t.Errorf("expected all aliases to be new on first run, got %v", diff.NewAliases)
+	}
+
+	run.Store(2)
+	diff, result, err := hc.BreachAPI.DiffDomains(store, WithDomainSearchRPM(60000), WithDomainSearchConcurrency(1))
+	if err != nil {
+		t.Fatalf("failed to diff domains: %s", err)
+	}
+	if len(diff.NewAliases) != 1 || diff.NewAliases[0].Alias != "tina.tester" || len(diff.RemovedBreaches) != 0 {
+		t.Errorf("unexpected diff of second run: %v", diff)
+	}
+	if _, ok := result.Errors["broken.tld"]; !ok {
+		t.Errorf("expected search of broken.tld to fail, got %v", result.Errors)
+	}
+	snapshot, err := store.Load()
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %s", err)
+	}
+	if len(snapshot.Domains["broken.tld"]) != 1 {
+		t.Errorf("expected failed domain to be taken over from previous snapshot, got %v", snapshot.Domains)
+	}
+}
+
+func TestBreachAPI_DiffDomains_skipped(t *testing.T) {
+	var skipped atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/subscribeddomains":
+			if skipped.Load() {
+				_, _ = fmt.Fprint(w, `[{"DomainName":"domain.tld","PwnCount":null}]`)
+				return
+			}
+			_, _ = fmt.Fprint(w, `[{"DomainName":"domain.tld","PwnCount":1}]`)
+		case "/api/v3/breacheddomain/domain.tld":
+			_, _ = fmt.Fprint(w, `{"toni.tester":["Foodora"]}`)
+		}
+	}))
+	defer server.Close()
+	hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+	store := NewFileDomainStore(filepath.Join(t.TempDir(), "domains.json"))
+
+	if _, _, err := hc.BreachAPI.DiffDomains(store, WithDomainSearchRPM(60000)); err != nil {
+		t.Fatalf("failed to diff domains: %s", err)
+	}
+
+	skipped.Store(true)
+	diff, result, err := hc.BreachAPI.DiffDomains(store, WithDomainSearchRPM(60000))
+	if err != nil {
+		t.Fatalf("failed to diff domains: %s", err)
+	}
+	if len(result.Skipped) != 1 || !diff.Empty() {
+		t.Errorf("expected skipped domain not to be reported as changed, got %v with skipped %v", diff,
+			result.Skipped)
+	}
+	snapshot, err := store.Load()
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %s", err)
+	}
+	if len(snapshot.Domains["domain.tld"]) != 1 {
+		t.Errorf("expected skipped domain to be taken over from previous snapshot, got %v", snapshot.Domains)
+	}
+
+	skipped.Store(false)
+	diff, _, err = hc.BreachAPI.DiffDomains(store, WithDomainSearchRPM(60000))
+	if err != nil {
+		t.Fatalf("failed to diff domains: %s", err)
+	}
+	if !diff.Empty() {
+		t.Errorf("expected domain that is searched again not to be reported as changed, got %v", diff)
+	}
+}
@@ -0,0 +1,203 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultDomainSearchConcurrency is the default number of concurrent requests of a domain search
+const DefaultDomainSearchConcurrency = 2
+
+// errDomainSearchStopped is the error of the domains that were not searched, because the domain
+// search was stopped
+var errDomainSearchStopped = errors.New("domain search stopped")
+
+// DomainSearchResult is the aggregated result of a search of all subscribed domains
+type DomainSearchResult struct {
+	// Domains maps the searched domains to their breached aliases. Each alias is mapped to the names
+	// of the breaches it appeared in. Domains without breached aliases are included with an empty map
+	Domains map[string]map[string][]string
+
+	// Skipped holds the subscribed domains that were not searched, because their PwnCount is null,
+	// i. e. they have never been searched in the domain search dashboard
+	Skipped []string
+
+	// Errors maps the domains whose search failed to the error of the search
+	Errors map[string]error
+}
+
+// Breaches returns the names of the breaches the given alias of the given domain appeared in. It
+// returns nil if the alias has not been found in any breach or the domain was not searched
+func (r *DomainSearchResult) Breaches(domain, alias string) []string {
+	return r.Domains[domain][alias]
+}
+
+// Accounts returns the number of breached aliases of all searched domains
+func (r *DomainSearchResult) Accounts() int {
+	count := 0
+	for _, aliases := range r.Domains {
+		count += len(aliases)
+	}
+	return count
+}
+
+// domainSearchOpts holds the options for a domain search
+type domainSearchOpts struct {
+	rpm         int // Requests per minute, taken from the subscription if not set
+	retries     int // Retries of rate limited requests
+	concurrency int // Concurrent requests
+}
+
+// DomainSearchOption is a function that sets options for a domain search
+type DomainSearchOption func(*domainSearchOpts)
+
+// WithDomainSearchRPM sets the number of requests per minute of a domain search. By default, the Rpm
+// of the SubscriptionStatus is used. Values below 1 are ignored
+func WithDomainSearchRPM(rpm int) DomainSearchOption {
+	return func(o *domainSearchOpts) {
+		if rpm > 0 {
+			o.rpm = rpm
+		}
+	}
+}
+
+// WithDomainSearchRetries sets the number of retries of a rate limited request of a domain search.
+// The default is DefaultBulkRetries. Negative values are ignored
+func WithDomainSearchRetries(retries int) DomainSearchOption {
+	return func(o *domainSearchOpts) {
+		if retries >= 0 {
+			o.retries = retries
+		}
+	}
+}
+
+// WithDomainSearchConcurrency sets the number of concurrent requests of a domain search. The
+// default is DefaultDomainSearchConcurrency. Values below 1 are ignored
+func WithDomainSearchConcurrency(concurrency int) DomainSearchOption {
+	return func(o *domainSearchOpts) {
+		if concurrency > 0 {
+			o.concurrency = concurrency
+		}
+	}
+}
+
+// SearchAllDomains searches the breached aliases of all domains returned by SubscribedDomains with
+// the BreachedDomain method and aggregates the results by domain and alias. Domains whose PwnCount
+// is null have never been searched and are skipped. The requests are scheduled within the rate
+// limit of the subscription, which is fetched with the SubscriptionAPI unless WithDomainSearchRPM
+// is set. Rate limited requests are retried after the delay of the Retry-After header.
+// This API is authenticated and requires a valid API key.
+//
+// Failed searches are reported in the Errors of the DomainSearchResult and do not stop the domain
+// search. If the API rejects the API key, the domain search stops and returns the results of the
+// domains that were searched so far together with the error.
+func (b *BreachAPI) SearchAllDomains(options ...DomainSearchOption) (*DomainSearchResult, error) {
+	if err := requiresAPIKey(b.hibp); err != nil {
+		return nil, err
+	}
+	opts := domainSearchOpts{retries: DefaultBulkRetries, concurrency: DefaultDomainSearchConcurrency}
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+		option(&opts)
+	}
+	if opts.rpm < 1 {
+		rpm, err := b.subscriptionRPM()
+		if err != nil {
+			return nil, err
+		}
+		opts.rpm = rpm
+	}
+
+	done := make(chan struct{})
+	pacer := &requestPacer{interval: time.Minute / time.Duration(opts.rpm), done: done}
+	if !pacer.wait() {
+		return nil, errDomainSearchStopped
+	}
+	subscribed, _, err := b.SubscribedDomains()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscribed domains: %w", err)
+	}
+
+	result := &DomainSearchResult{
+		Domains: make(map[string]map[string][]string),
+		Errors:  make(map[string]error),
+	}
+	domains := make(chan string)
+	go func() {
+		defer close(domains)
+		for _, sd := range subscribed {
+			if sd.PwnCount.IsNil() {
+				result.Skipped = append(result.Skipped, sd.DomainName)
+				continue
+			}
+			select {
+			case domains <- sd.DomainName:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	var fatalErr error
+	wg := sync.WaitGroup{}
+	for i := 0; i < opts.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for domain := range domains {
+				aliases, err := b.searchDomain(domain, pacer, opts)
+				mu.Lock()
+				switch {
+				case errors.Is(err, errDomainSearchStopped):
+				case fatalAPIError(err):
+					if fatalErr == nil {
+						fatalErr = fmt.Errorf("failed to search domain %q: %w", domain, err)
+						close(done)
+					}
+				case err != nil:
+					result.Errors[domain] = err
+				default:
+					if aliases == nil {
+						aliases = make(map[string][]string)
+					}
+					result.Domains[domain] = aliases
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	sort.Strings(result.Skipped)
+
+	return result, fatalErr
+}
+
+// searchDomain searches the breached aliases of a single domain of a domain search and retries
+// rate limited requests
+func (b *BreachAPI) searchDomain(domain string, pacer *requestPacer, opts domainSearchOpts,
+) (map[string][]string, error) {
+	for attempts := 1; ; attempts++ {
+		if !pacer.wait() {
+			return nil, errDomainSearchStopped
+		}
+		aliases, hr, err := b.BreachedDomain(domain)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests &&
+			attempts <= opts.retries {
+			pacer.backoff(retryAfter(hr, attempts))
+			continue
+		}
+		return aliases, err
+	}
+}
@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testSubscribedDomains = `[
+{"DomainName":"domain.tld","PwnCount":6,"PwnCountExcludingSpamLists":null,"PwnCountExcludingSpamListsAtLastSubscriptionRenewal":5,"NextSubscriptionRenewal":"2025-03-07T12:30:38"},
+{"DomainName":"never-searched.tld","PwnCount":null,"PwnCountExcludingSpamLists":null,"PwnCountExcludingSpamListsAtLastSubscriptionRenewal":null,"NextSubscriptionRenewal":"2025-03-07T12:30:38"},
+{"DomainName":"clean.tld","PwnCount":0,"PwnCountExcludingSpamLists":0,"PwnCountExcludingSpamListsAtLastSubscriptionRenewal":0,"NextSubscriptionRenewal":"2025-03-07T12:30:38"},
+{"DomainName":"limited.tld","PwnCount":1,"PwnCountExcludingSpamLists":1,"PwnCountExcludingSpamListsAtLastSubscriptionRenewal":1,"NextSubscriptionRenewal":"2025-03-07T12:30:38"},
+{"DomainName":"broken.tld","PwnCount":1,"PwnCountExcludingSpamLists":1,"PwnCountExcludingSpamListsAtLastSubscriptionRenewal":1,"NextSubscriptionRenewal":"2025-03-07T12:30:38"}
+]`
+
+func TestBreachAPI_SearchAllDomains(t *testing.T) {
+	t.Run("all subscribed domains are searched", func(t *testing.T) {
+		var mu sync.Mutex
+		hits := make(map[string]int)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			hits[r.URL.Path]++
+			count := hits[r.URL.Path]
+			mu.Unlock()
+			switch r.URL.Path {
+			case "/api/v3/subscribeddomains":
+				_, _ = fmt.Fprint(w, testSubscribedDomains)
+			case "/api/v3/breacheddomain/domain.tld":
+				_, _ = fmt.Fprint(w, `{"toni.tester":["Foodora"],"tina.tester":["Adobe","Lastfm"]}`)
+			case "/api/v3/breacheddomain/clean.tld":
+				w.WriteHeader(http.StatusNotFound)
+			case "/api/v3/breacheddomain/limited.tld":
+				if count == 1 {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				_, _ = fmt.Fprint(w, `{"info":["PDL"]}`)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+
+		result, err := hc.BreachAPI.SearchAllDomains(WithDomainSearchRPM(60000), WithDomainSearchConcurrency(3))
+		if err != nil {
+			t.Fatalf("failed to search all domains: %s", err)
+		}
+		if len(result.Domains) != 3 {
+			t.Errorf("expected 3 searched domains, got %d", len(result.Domains))
+		}
+		if breaches := result.Breaches("domain.tld", "tina.tester"); len(breaches) != 2 || breaches[0] != "Adobe" {
+			t.Errorf("unexpected breaches of tina.tester@domain.tld: %v", breaches)
+		}
+		if aliases, ok := result.Domains["clean.tld"]; !ok || len(aliases) != 0 {
+			t.Errorf("expected clean.tld to be searched without breached aliases, got %v", aliases)
+		}
+		if breaches := result.Breaches("limited.tld", "info"); len(breaches) != 1 {
+			t.Errorf("expected rate limited domain to be retried, got %v", breaches)
+		}
+		if result.Accounts() != 3 {
+			t.Errorf("expected 3 breached accounts, got %d", result.Accounts())
+		}
+		if len(result.Skipped) != 1 || result.Skipped[0] != "never-searched.tld" {
+			t.Errorf("expected never-searched.tld to be skipped, got %v", result.Skipped)
+		}
+		if hits["/api/v3/breacheddomain/never-searched.tld"] != 0 {
+			t.Error("expected domain with null PwnCount not to be searched")
+		}
+		var apiErr *APIError
+		if len(result.Errors) != 1 || !errors.As(result.Errors["broken.tld"], &apiErr) {
+			t.Errorf("expected search of broken.tld to fail, got %v", result.Errors)
+		}
+	})
+	t.Run("rate limit is taken from the subscription", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/api/v3/subscription/status":
+				_, _ = fmt.Fprint(w, `{"Rpm":60000}`)
+			case r.URL.Path == "/api/v3/subscribeddomains":
+				_, _ = fmt.Fprint(w, testSubscribedDomains)
+			default:
+				_, _ = fmt.Fprint(w, `{"info":["PDL"]}`)
+			}
+		}))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+		result, err := hc.BreachAPI.SearchAllDomains(nil)
+		if err != nil {
+			t.Fatalf("failed to search all domains: %s", err)
+		}
+		if len(result.Domains) != 4 {
+			t.Errorf("expected 4 searched domains, got %d", len(result.Domains))
+		}
+	})
+	t.Run("search stops if the API key is rejected", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/v3/subscribeddomains" {
+				_, _ = fmt.Fprint(w, testSubscribedDomains)
+				return
+			}
+			if strings.HasSuffix(r.URL.Path, "/limited.tld") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = fmt.Fprint(w, `{}`)
+		}))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+		result, err := hc.BreachAPI.SearchAllDomains(WithDomainSearchRPM(60000), WithDomainSearchConcurrency(1))
+		if err == nil {
+			t.Fatal("expected domain search to fail on rejected API key")
+		}
+		if result == nil || len(result.Domains) != 2 {
+			t.Errorf("expected the results of the domains searched before to be returned, got %v", result)
+		}
+	})
+	t.Run("search fails without API key", func(t *testing.T) {
+		hc := New()
+		if _, err := hc.BreachAPI.SearchAllDomains(); !errors.Is(err, ErrMethodRequiresAPIKey) {
+			t.Errorf("expected error to be %s, got %v", ErrMethodRequiresAPIKey, err)
+		}
+	})
+	t.Run("search fails if subscribed domains can not be fetched", func(t *testing.T) {
+		server := httptest.NewServer(newTestFailureHandler(t, http.StatusInternalServerError))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+		if _, err := hc.BreachAPI.SearchAllDomains(WithDomainSearchRPM(60000)); err == nil {
+			t.Error("expected domain search to fail")
+		}
+	})
+}
@@ -4,6 +4,6 @@
 
 module github.com/wneessen/go-hibp
 
-go 1.18
+go 1.21
 
 require github.com/wneessen/niljson v0.1.1
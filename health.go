@@ -0,0 +1,301 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultHealthExpiryDays is the default number of days before the end of the subscription, from
+	// which on a health check warns about the expiring subscription
+	DefaultHealthExpiryDays = 30
+
+	// DefaultHealthCapacityThreshold is the default ratio of the breached accounts of a domain to the
+	// search capacity of the subscription, from which on a health check warns about the domain
+	DefaultHealthCapacityThreshold = 0.8
+)
+
+// HealthWarningKind is the kind of a HealthWarning
+type HealthWarningKind int
+
+const (
+	// HealthSubscriptionExpiring warns that the subscription expires soon or has expired
+	HealthSubscriptionExpiring HealthWarningKind = iota
+
+	// HealthDomainCapacity warns that a domain has grown close to or beyond the largest domain the
+	// subscription can search and will exceed it at the next renewal of the subscription
+	HealthDomainCapacity
+
+	// HealthDomainNeverSearched warns that a domain has never been searched
+	HealthDomainNeverSearched
+
+	// HealthDomainNotSearchable warns that a domain already exceeded the largest domain the
+	// subscription can search at the last renewal of the subscription and cannot be searched
+	HealthDomainNotSearchable
+)
+
+// String satisfies the fmt.Stringer interface for the HealthWarningKind type
+func (k HealthWarningKind) String() string {
+	switch k {
+	case HealthSubscriptionExpiring:
+		return "subscription_expiring"
+	case HealthDomainCapacity:
+		return "domain_capacity"
+	case HealthDomainNeverSearched:
+		return "domain_never_searched"
+	case HealthDomainNotSearchable:
+		return "domain_not_searchable"
+	default:
+		return "unknown"
+	}
+}
+
+// HealthWarning is a single warning of a SubscriptionHealth
+type HealthWarning struct {
+	// Kind is the kind of the warning
+	Kind HealthWarningKind
+
+	// Domain is the subscribed domain the warning refers to. It is empty for warnings about the
+	// subscription
+	Domain string
+
+	// Message is a human readable description of the warning
+	Message string
+}
+
+// DomainHealth holds the health details of a single subscribed domain
+type DomainHealth struct {
+	// Domain is the name of the subscribed domain
+	Domain string
+
+	// BreachedAccounts is the number of breached accounts of the domain, excluding those that appear
+	// solely in spam lists. It is 0 if the domain has never been searched
+	BreachedAccounts int
+
+	// BreachedAccountsAtRenewal is the number of breached accounts of the domain, excluding those that
+	// appear solely in spam lists, at the last renewal of the subscription. It is locked in until the
+	// next renewal and decides whether the domain can be searched until then. It equals
+	// BreachedAccounts if the API did not report a count at the last renewal
+	BreachedAccountsAtRenewal int
+
+	// Capacity is the ratio of BreachedAccounts to the largest domain the subscription can search,
+	// i. e. the capacity the domain will take at the next renewal of the subscription. It is 0 if the
+	// subscription has no limit
+	Capacity float64
+
+	// Searchable is true if the domain can be searched until the next renewal of the subscription,
+	// i. e. if BreachedAccountsAtRenewal does not exceed the largest domain the subscription can search
+	Searchable bool
+
+	// NeverSearched is true if the domain has never been searched
+	NeverSearched bool
+
+	// NextRenewal is the date of the next renewal of the subscription of the domain
+	NextRenewal time.Time
+}
+
+// Growth returns the number of breached accounts of the domain that were added since the last
+// renewal of the subscription
+func (d DomainHealth) Growth() int {
+	return d.BreachedAccounts - d.BreachedAccountsAtRenewal
+}
+
+// SubscriptionHealth is the result of a health check of the subscription and its domains
+type SubscriptionHealth struct {
+	// Checked is the time of the health check
+	Checked time.Time
+
+	// SubscriptionName is the name of the subscription
+	SubscriptionName string
+
+	// SubscribedUntil is the time the subscription ends
+	SubscribedUntil time.Time
+
+	// MaxBreachedAccounts is the size of the largest domain the subscription can search. It is 0 if
+	// the subscription has no limit
+	MaxBreachedAccounts int
+
+	// Domains holds the health details of the subscribed domains
+	Domains []DomainHealth
+
+	// Warnings holds the warnings of the health check. It is empty if the subscription is healthy
+	Warnings []HealthWarning
+}
+
+// Remaining returns the time until the subscription ends. It is negative if the subscription has
+// expired
+func (h *SubscriptionHealth) Remaining() time.Duration {
+	return h.SubscribedUntil.Sub(h.Checked)
+}
+
+// DaysRemaining returns the number of days until the subscription ends. It is negative if the
+// subscription has expired
+func (h *SubscriptionHealth) DaysRemaining() float64 {
+	return h.Remaining().Hours() / 24
+}
+
+// Healthy checks if the health check did not return any warnings
+func (h *SubscriptionHealth) Healthy() bool {
+	return len(h.Warnings) == 0
+}
+
+// healthOpts holds the options for a health check
+type healthOpts struct {
+	expiryDays        int     // Days before the end of the subscription to warn about the expiry
+	capacityThreshold float64 // Ratio of the search capacity to warn about a domain
+}
+
+// HealthOption is a function that sets options for a health check
+type HealthOption func(*healthOpts)
+
+// WithHealthExpiryDays sets the number of days before the end of the subscription, from which on
+// the health check warns about the expiring subscription. The default is DefaultHealthExpiryDays.
+// Negative values are ignored
+func WithHealthExpiryDays(days int) HealthOption {
+	return func(o *healthOpts) {
+		if days >= 0 {
+			o.expiryDays = days
+		}
+	}
+}
+
+// WithHealthCapacityThreshold sets the ratio of the breached accounts of a domain to the search
+// capacity of the subscription, from which on the health check warns about the domain. The default
+// is DefaultHealthCapacityThreshold. Values outside of (0, 1] are ignored
+func WithHealthCapacityThreshold(threshold float64) HealthOption {
+	return func(o *healthOpts) {
+		if threshold > 0 && threshold <= 1 {
+			o.capacityThreshold = threshold
+		}
+	}
+}
+
+// Health checks the health of the subscription and the subscribed domains with the Status of the
+// subscription and the SubscribedDomains of the BreachAPI. See CheckSubscriptionHealth for the
+// warnings of the health check.
+// This API is authenticated and requires a valid API key.
+func (s *SubscriptionAPI) Health(options ...HealthOption) (*SubscriptionHealth, error) {
+	status, _, err := s.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription status: %w", err)
+	}
+	domains, _, err := s.hibp.BreachAPI.SubscribedDomains()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscribed domains: %w", err)
+	}
+	return CheckSubscriptionHealth(status, domains, time.Now(), options...), nil
+}
+
+// CheckSubscriptionHealth checks the health of the given subscription and subscribed domains at
+// the given time. It warns if the subscription expires within the expiry days, if a domain cannot
+// be searched because its breached accounts at the last renewal exceed the largest domain the
+// subscription can search, if the current breached accounts of a domain reach the capacity
+// threshold, i. e. the domain will exceed the capacity at the next renewal, and if a domain has
+// never been searched.
+func CheckSubscriptionHealth(status SubscriptionStatus, domains []SubscribedDomains, now time.Time,
+	options ...HealthOption,
+) *SubscriptionHealth {
+	opts := healthOpts{expiryDays: DefaultHealthExpiryDays, capacityThreshold: DefaultHealthCapacityThreshold}
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+		option(&opts)
+	}
+
+	health := &SubscriptionHealth{
+		Checked:          now,
+		SubscriptionName: status.SubscriptionName,
+		SubscribedUntil:  status.SubscribedUntil.Time,
+		Domains:          make([]DomainHealth, 0, len(domains)),
+	}
+	if status.DomainSearchMaxBreachedAccounts.NotNil() {
+		health.MaxBreachedAccounts = status.DomainSearchMaxBreachedAccounts.Value()
+	}
+
+	if remaining := health.Remaining(); remaining <= 0 {
+		health.Warnings = append(health.Warnings, HealthWarning{
+			Kind:    HealthSubscriptionExpiring,
+			Message: fmt.Sprintf("subscription %q has expired", status.SubscriptionName),
+		})
+	} else if remaining <= time.Duration(opts.expiryDays)*24*time.Hour {
+		health.Warnings = append(health.Warnings, HealthWarning{
+			Kind: HealthSubscriptionExpiring,
+			Message: fmt.Sprintf("subscription %q expires in %.0f days", status.SubscriptionName,
+				health.DaysRemaining()),
+		})
+	}
+
+	for _, sd := range domains {
+		domain := DomainHealth{
+			Domain:      sd.DomainName,
+			Searchable:  true,
+			NextRenewal: sd.NextSubscriptionRenewal.Time,
+		}
+		if sd.PwnCount.IsNil() {
+			domain.NeverSearched = true
+			health.Warnings = append(health.Warnings, HealthWarning{
+				Kind:    HealthDomainNeverSearched,
+				Domain:  sd.DomainName,
+				Message: fmt.Sprintf("domain %q has never been searched", sd.DomainName),
+			})
+			health.Domains = append(health.Domains, domain)
+			continue
+		}
+
+		// The search capacity is measured without spam lists, fall back to the total count if the
+		// count without spam lists is not available
+		domain.BreachedAccounts = sd.PwnCount.Value()
+		if sd.PwnCountExcludingSpamLists.NotNil() {
+			domain.BreachedAccounts = sd.PwnCountExcludingSpamLists.Value()
+		}
+		domain.BreachedAccountsAtRenewal = domain.BreachedAccounts
+		if sd.PwnCountExcludingSpamListsAtLastSubscriptionRenewal.NotNil() {
+			domain.BreachedAccountsAtRenewal = sd.PwnCountExcludingSpamListsAtLastSubscriptionRenewal.Value()
+		}
+		if health.MaxBreachedAccounts > 0 {
+			domain.Capacity = float64(domain.BreachedAccounts) / float64(health.MaxBreachedAccounts)
+			domain.Searchable = domain.BreachedAccountsAtRenewal <= health.MaxBreachedAccounts
+		}
+
+		// The count at the last renewal is locked in until the next renewal and decides whether the
+		// domain can be searched now. The current count only applies from the next renewal on
+		if !domain.Searchable {
+			health.Warnings = append(health.Warnings, HealthWarning{
+				Kind:   HealthDomainNotSearchable,
+				Domain: sd.DomainName,
+				Message: fmt.Sprintf("domain %q had %d breached accounts at the last renewal, more than the %d "+
+					"the subscription can search", sd.DomainName, domain.BreachedAccountsAtRenewal,
+					health.MaxBreachedAccounts),
+			})
+		}
+		renewal := "the next renewal"
+		if !domain.NextRenewal.IsZero() {
+			renewal = "renewal on " + domain.NextRenewal.Format(time.DateOnly)
+		}
+		switch {
+		case domain.Capacity > 1:
+			health.Warnings = append(health.Warnings, HealthWarning{
+				Kind:   HealthDomainCapacity,
+				Domain: sd.DomainName,
+				Message: fmt.Sprintf("domain %q has %d breached accounts and will exceed the capacity of %d at %s "+
+					"(%+d since last renewal)", sd.DomainName, domain.BreachedAccounts, health.MaxBreachedAccounts,
+					renewal, domain.Growth()),
+			})
+		case domain.Capacity >= opts.capacityThreshold:
+			health.Warnings = append(health.Warnings, HealthWarning{
+				Kind:   HealthDomainCapacity,
+				Domain: sd.DomainName,
+				Message: fmt.Sprintf("domain %q has %d breached accounts and will take %.0f%% of the capacity of %d "+
+					"at %s (%+d since last renewal)", sd.DomainName, domain.BreachedAccounts, domain.Capacity*100,
+					health.MaxBreachedAccounts, renewal, domain.Growth()),
+			})
+		}
+		health.Domains = append(health.Domains, domain)
+	}
+	return health
+}
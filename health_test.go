@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckSubscriptionHealth(t *testing.T) {
+	var status SubscriptionStatus
+	if err := json.Unmarshal([]byte(`{"SubscriptionName":"Pwned 1","SubscribedUntil":"2025-03-07T12:30:38",
+		"DomainSearchMaxBreachedAccounts":25,"Rpm":10}`), &status); err != nil {
+		t.Fatalf("failed to unmarshal subscription status: %s", err)
+	}
+	var domains []SubscribedDomains
+	if err := json.Unmarshal([]byte(`[
+		{"DomainName":"small.tld","PwnCount":4,"PwnCountExcludingSpamLists":3,"PwnCountExcludingSpamListsAtLastSubscriptionRenewal":2},
+		{"DomainName":"growing.tld","PwnCount":22,"PwnCountExcludingSpamLists":21,"PwnCountExcludingSpamListsAtLastSubscriptionRenewal":15},
+		{"DomainName":"large.tld","PwnCount":30,"PwnCountExcludingSpamLists":null,"PwnCountExcludingSpamListsAtLastSubscriptionRenewal":24,"NextSubscriptionRenewal":"2025-03-07T12:30:38"},
+		{"DomainName":"full.tld","PwnCount":28,"PwnCountExcludingSpamLists":27,"PwnCountExcludingSpamListsAtLastSubscriptionRenewal":26},
+		{"DomainName":"new.tld","PwnCount":null,"PwnCountExcludingSpamLists":null,"PwnCountExcludingSpamListsAtLastSubscriptionRenewal":null}
+	]`), &domains); err != nil {
+		t.Fatalf("failed to unmarshal subscribed domains: %s", err)
+	}
+
+	t.Run("warnings are reported", func(t *testing.T) {
+		now := status.SubscribedUntil.Add(-10 * 24 * time.Hour)
+		health := CheckSubscriptionHealth(status, domains, now)
+		if health.Healthy() {
+			t.Fatal("expected health check to report warnings")
+		}
+		want := []struct {
+			kind   HealthWarningKind
+			domain string
+		}{
+			{HealthSubscriptionExpiring, ""},
+			{HealthDomainCapacity, "growing.tld"},
+			{HealthDomainCapacity, "large.tld"},
+			{HealthDomainNotSearchable, "full.tld"},
+			{HealthDomainCapacity, "full.tld"},
+			{HealthDomainNeverSearched, "new.tld"},
+		}
+		if len(health.Warnings) != len(want) {
+			t.Fatalf("expected %d warnings, got %d: %v", len(want), len(health.Warnings), health.Warnings)
+		}
+		for i, w := range want {
+			if health.Warnings[i].Kind != w.kind || health.Warnings[i].Domain != w.domain {
+				t.Errorf("expected warning %d to be %s for %q, got %s for %q", i, w.kind, w.domain,
+					health.Warnings[i].Kind, health.Warnings[i].Domain)
+			}
+		}
+		if days := health.DaysRemaining(); days != 10 {
+			t.Errorf("expected 10 days remaining, got %f", days)
+		}
+		if health.MaxBreachedAccounts != 25 || len(health.Domains) != 5 {
+			t.Errorf("unexpected health details: %+v", health)
+		}
+		growing := health.Domains[1]
+		if growing.BreachedAccounts != 21 || growing.Growth() != 6 || growing.Capacity != 0.84 {
+			t.Errorf("unexpected health of growing.tld: %+v", growing)
+		}
+		large := health.Domains[2]
+		if large.BreachedAccounts != 30 {
+			t.Errorf("expected total count to be used without count excluding spam lists, got %d",
+				large.BreachedAccounts)
+		}
+		if !large.Searchable {
+			t.Error("expected large.tld to be searchable with the count at the last renewal")
+		}
+		msg := health.Warnings[2].Message
+		if !strings.Contains(msg, "will exceed the capacity of 25 at renewal on 2025-03-07") {
+			t.Errorf("expected warning about exceeding the capacity at renewal, got %q", msg)
+		}
+		if health.Domains[3].Searchable {
+			t.Error("expected full.tld not to be searchable with the count at the last renewal")
+		}
+		if !health.Domains[4].NeverSearched || !health.Domains[4].Searchable {
+			t.Error("expected new.tld to be never searched")
+		}
+	})
+	t.Run("thresholds are configurable", func(t *testing.T) {
+		now := status.SubscribedUntil.Add(-10 * 24 * time.Hour)
+		health := CheckSubscriptionHealth(status, domains[:2], now, WithHealthExpiryDays(7),
+			WithHealthCapacityThreshold(0.9), WithHealthCapacityThreshold(2), nil)
+		if !health.Healthy() {
+			t.Errorf("expected health check without warnings, got %v", health.Warnings)
+		}
+	})
+	t.Run("expired subscription", func(t *testing.T) {
+		health := CheckSubscriptionHealth(status, nil, status.SubscribedUntil.Add(time.Hour))
+		if len(health.Warnings) != 1 || health.Warnings[0].Kind != HealthSubscriptionExpiring ||
+			health.DaysRemaining() >= 0 {
+			t.Errorf("expected expired subscription warning, got %v", health.Warnings)
+		}
+	})
+	t.Run("subscription without search limit", func(t *testing.T) {
+		unlimited := status
+		unlimited.DomainSearchMaxBreachedAccounts.Reset()
+		health := CheckSubscriptionHealth(unlimited, domains[:3], status.SubscribedUntil.Add(-365*24*time.Hour))
+		if !health.Healthy() || health.MaxBreachedAccounts != 0 || health.Domains[2].Capacity != 0 {
+			t.Errorf("expected no capacity warnings without search limit, got %v", health.Warnings)
+		}
+	})
+}
+
+func TestSubscriptionAPI_Health(t *testing.T) {
+	server := httptest.NewServer(newTestRouteHandler(t, map[string]string{
+		"/api/v3/subscription/status": ServerResponseSubscriptionStatus,
+		"/api/v3/subscribeddomains":   "testdata/breach-subscribeddomains.txt",
+	}))
+	defer server.Close()
+	hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey))
+	health, err := hc.SubscriptionAPI.Health()
+	if err != nil {
+		t.Fatalf("failed to check subscription health: %s", err)
+	}
+	if health.SubscriptionName != "Pwned 1" || len(health.Domains) != 1 {
+		t.Errorf("unexpected subscription health: %+v", health)
+	}
+	if health.Domains[0].BreachedAccounts != 6 {
+		t.Errorf("expected 6 breached accounts, got %d", health.Domains[0].BreachedAccounts)
+	}
+
+	failing := httptest.NewServer(newTestFailureHandler(t, http.StatusInternalServerError))
+	defer failing.Close()
+	hc = New(WithHTTPClient(newTestRouteClient(t, failing.URL)), WithAPIKey(TestAPIKey))
+	if _, err = hc.SubscriptionAPI.Health(); err == nil {
+		t.Error("expected health check to fail")
+	}
+}
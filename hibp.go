@@ -7,9 +7,11 @@ package hibp
 
 import (
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
@@ -49,6 +51,9 @@ var (
 	// expected length
 	ErrPrefixLengthMismatch = errors.New("password hash prefix must be 5 characters long")
 
+	// ErrPrefixInvalid should be used if a given string does not represent a valid password hash prefix
+	ErrPrefixInvalid = errors.New("not a valid password hash prefix")
+
 	// ErrSHA1LengthMismatch should be used if a given SHA1 checksum does not match the
 	// expected length
 	ErrSHA1LengthMismatch = errors.New("SHA1 hash size needs to be 160 bits")
@@ -71,8 +76,43 @@ var (
 
 	// ErrMethodRequiresAPIKey indicates that the invoked method cannot proceed without providing a valid API key.
 	ErrMethodRequiresAPIKey = errors.New("this method requires an API key")
+
+	// ErrNotFound is matched by an APIError for a HTTP 404 response of the API
+	ErrNotFound = errors.New("not found")
 )
 
+// APIError is returned if the API responds with a non HTTP-200 status. It wraps
+// ErrNonPositiveResponse and matches ErrNotFound for HTTP 404 responses, so it can be checked
+// with errors.Is
+type APIError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+
+	// Status is the HTTP status of the response, i. e. "404 Not Found"
+	Status string
+}
+
+// newAPIError returns a new APIError for the given HTTP response
+func newAPIError(hr *http.Response) *APIError {
+	return &APIError{StatusCode: hr.StatusCode, Status: hr.Status}
+}
+
+// Error satisfies the error interface for the APIError type
+func (e *APIError) Error() string {
+	return fmt.Sprintf("HTTP %s: %s", e.Status, ErrNonPositiveResponse)
+}
+
+// Unwrap returns ErrNonPositiveResponse, which every APIError wraps
+func (e *APIError) Unwrap() error {
+	return ErrNonPositiveResponse
+}
+
+// Is reports whether the APIError matches the given target error. A HTTP 404 APIError matches
+// ErrNotFound
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
 // HTTPClient is an interface representing an HTTP client capable of executing HTTP requests and
 // returning responses.
 type HTTPClient interface {
@@ -91,6 +131,12 @@ type Client struct {
 	rlSleep bool
 	logger  io.Writer // The custom logger.
 
+	slog          *slog.Logger    // The structured logger for request events
+	logUnredacted bool            // If set to true, sensitive values are not redacted in the structured log
+	observer      Observer        // The observer for request events
+	transport     *transport      // The middlewares composed around the HTTP client
+	breaker       *circuitBreaker // The circuit breaker for failing fast during API outages
+
 	PwnedPassAPI     *PwnedPassAPI         // Reference to the PwnedPassAPI API
 	PwnedPassAPIOpts *PwnedPasswordOptions // Additional options for the PwnedPassAPI API
 
@@ -126,6 +172,7 @@ func New(options ...Option) Client {
 		// Add a http client to the Client object
 		c.hc = httpClient(c.to)
 	}
+	c.buildTransport()
 
 	// Associate the different HIBP service APIs with the Client
 	c.PwnedPassAPI = &PwnedPassAPI{
@@ -160,6 +207,16 @@ func WithPwnedPadding() Option {
 	}
 }
 
+// WithPwnedPaddingEntries enables padding-mode for the PwnedPasswords API client and keeps the
+// padding entries in the results of the PwnedPassAPI methods. Padding entries have a count of 0
+// and are marked by the Match.IsPadding method
+func WithPwnedPaddingEntries() Option {
+	return func(c *Client) {
+		c.PwnedPassAPIOpts.WithPadding = true
+		c.PwnedPassAPIOpts.KeepPadding = true
+	}
+}
+
 // WithUserAgent sets a custom user agent string for the HTTP client
 func WithUserAgent(a string) Option {
 	if a == "" {
@@ -203,6 +260,24 @@ func WithLogger(w io.Writer) Option {
 	}
 }
 
+// WithStructuredLogger sets a structured logger that receives events for the start and the end of
+// each API request, including the HTTP method, the endpoint template, the HTTP status, the duration,
+// the retry count and the rate limit waits. Sensitive values like the API key, the account ID or the
+// password hash prefix are redacted by default.
+func WithStructuredLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		c.slog = l
+	}
+}
+
+// WithoutLogRedaction disables the redaction of sensitive values in the structured log output. This
+// should only be used for debugging purposes.
+func WithoutLogRedaction() Option {
+	return func(c *Client) {
+		c.logUnredacted = true
+	}
+}
+
 // HTTPReq prepares a HTTP request to the corresponding API
 func (c *Client) HTTPReq(m, p string, q map[string]string) (*http.Request, error) {
 	u, err := url.Parse(p)
@@ -242,23 +317,19 @@ func (c *Client) HTTPResBody(m string, p string, q map[string]string) ([]byte, *
 	if err != nil {
 		return nil, nil, err
 	}
-	hr, err := c.hc.Do(hreq)
-	if err != nil {
-		return nil, hr, err
-	}
-	defer func() {
-		_ = hr.Body.Close()
-	}()
 
-	hb, err := io.ReadAll(hr.Body)
-	if err != nil {
-		return nil, hr, err
-	}
+	rt, hreq := c.startRequest(hreq)
+	for {
+		hb, hr, err := c.doHTTPResBody(hreq)
+		if err != nil || hr.StatusCode != http.StatusTooManyRequests || !c.rlSleep {
+			rt.end(hr, err)
+			return hb, hr, err
+		}
 
-	if hr.StatusCode == 429 && c.rlSleep {
 		headerDelay := hr.Header.Get("Retry-After")
 		delayTime, err := time.ParseDuration(headerDelay + "s")
 		if err != nil {
+			rt.end(hr, err)
 			return nil, hr, err
 		}
 		// Wait for one additional second to ensure that we don't retry too early due to integer rounding issues.
@@ -266,17 +337,74 @@ func (c *Client) HTTPResBody(m string, p string, q map[string]string) ([]byte, *
 		if c.logger != nil {
 			_, _ = fmt.Fprintf(c.logger, "API rate limit hit. Retrying request in %s\n", delayTime.String())
 		}
+		rt.retry(delayTime)
 		time.Sleep(delayTime)
-		return c.HTTPResBody(m, p, q)
+
+		if hreq, err = c.HTTPReq(m, p, q); err != nil {
+			rt.end(nil, err)
+			return nil, nil, err
+		}
+		hreq = rt.prepare(hreq)
+	}
+}
+
+// doHTTPResBody performs the given HTTP request and returns the response body as byte array. A HTTP
+// 429 response is returned without error, so that the caller can decide whether to retry the request
+func (c *Client) doHTTPResBody(hreq *http.Request) ([]byte, *http.Response, error) {
+	hr, err := c.do(hreq)
+	if err != nil {
+		return nil, hr, err
+	}
+	defer func() {
+		_ = hr.Body.Close()
+	}()
+
+	hb, err := io.ReadAll(hr.Body)
+	if err != nil {
+		return nil, hr, err
 	}
 
+	if hr.StatusCode == http.StatusTooManyRequests && c.rlSleep {
+		return nil, hr, nil
+	}
 	if hr.StatusCode != 200 {
-		return nil, hr, fmt.Errorf("HTTP %s: %w", hr.Status, ErrNonPositiveResponse)
+		return nil, hr, newAPIError(hr)
 	}
 
 	return hb, hr, nil
 }
 
+// apiRequest describes a GET request to a JSON endpoint of the HIBP API
+type apiRequest struct {
+	url      string            // URL of the endpoint
+	query    map[string]string // Query parameters of the request
+	auth     bool              // Whether the endpoint requires an API key
+	notFound bool              // Whether a HTTP 404 response means that nothing was found, instead of an error
+}
+
+// getJSON is the shared request path for the JSON endpoints of the HIBP API. It enforces the API key
+// for authenticated endpoints, performs the request and decodes the JSON response body into v.
+// Non HTTP-200 responses fail with an APIError. If notFound is set for the apiRequest, a HTTP 404
+// response is an empty result: v is left unchanged and the response is returned without error
+func (c *Client) getJSON(r apiRequest, v any) (*http.Response, error) {
+	if r.auth {
+		if err := requiresAPIKey(c); err != nil {
+			return nil, err
+		}
+	}
+	hb, hr, err := c.HTTPResBody(http.MethodGet, r.url, r.query)
+	if err != nil {
+		if r.notFound && errors.Is(err, ErrNotFound) {
+			return hr, nil
+		}
+		return hr, err
+	}
+	if err = json.Unmarshal(hb, v); err != nil {
+		return hr, err
+	}
+	return hr, nil
+}
+
 // httpClient returns a custom http client for the HIBP Client object
 func httpClient(to time.Duration) *http.Client {
 	tc := &tls.Config{
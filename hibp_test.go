@@ -15,6 +15,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -62,6 +63,13 @@ func TestNew(t *testing.T) {
 				true, hc.PwnedPassAPIOpts.WithPadding)
 		}
 	})
+	t.Run("return a HIBP client with PwnedPassword padding entries kept", func(t *testing.T) {
+		hc := New(WithPwnedPaddingEntries())
+		if !hc.PwnedPassAPIOpts.WithPadding || !hc.PwnedPassAPIOpts.KeepPadding {
+			t.Errorf("hibp client pwned padding entries option was not set properly. Expected %t, got: %t/%t",
+				true, hc.PwnedPassAPIOpts.WithPadding, hc.PwnedPassAPIOpts.KeepPadding)
+		}
+	})
 	t.Run("return a HIBP client with PwnedPassword with NTLM hashes instead of SHA-1", func(t *testing.T) {
 		hc := New(WithPwnedNTLMHash())
 		if hc.PwnedPassAPIOpts.HashMode != HashModeNTLM {
@@ -287,6 +295,128 @@ func TestClient_HTTPReq(t *testing.T) {
 	})
 }
 
+func TestClient_endpoints(t *testing.T) {
+	// endpointCall calls an endpoint and returns the number of returned items
+	type endpointCall func(c *Client) (int, *http.Response, error)
+	tests := []struct {
+		name     string
+		auth     bool // Whether the endpoint requires an API key
+		notFound bool // Whether a HTTP 404 response is an empty result
+		call     endpointCall
+	}{
+		{"Breaches", false, false, func(c *Client) (int, *http.Response, error) {
+			b, hr, err := c.BreachAPI.Breaches()
+			return len(b), hr, err
+		}},
+		{"BreachByName", false, false, func(c *Client) (int, *http.Response, error) {
+			b, hr, err := c.BreachAPI.BreachByName("Adobe")
+			return boolToInt(b.Present()), hr, err
+		}},
+		{"LatestBreach", false, false, func(c *Client) (int, *http.Response, error) {
+			b, hr, err := c.BreachAPI.LatestBreach()
+			return boolToInt(b.Present()), hr, err
+		}},
+		{"DataClasses", false, false, func(c *Client) (int, *http.Response, error) {
+			d, hr, err := c.BreachAPI.DataClasses()
+			return len(d), hr, err
+		}},
+		{"BreachedAccount", true, true, func(c *Client) (int, *http.Response, error) {
+			b, hr, err := c.BreachAPI.BreachedAccount("toni.tester@domain.tld")
+			return len(b), hr, err
+		}},
+		{"SubscribedDomains", true, false, func(c *Client) (int, *http.Response, error) {
+			d, hr, err := c.BreachAPI.SubscribedDomains()
+			return len(d), hr, err
+		}},
+		{"BreachedDomain", true, true, func(c *Client) (int, *http.Response, error) {
+			d, hr, err := c.BreachAPI.BreachedDomain("domain.tld")
+			return len(d), hr, err
+		}},
+		{"PastedAccount", true, true, func(c *Client) (int, *http.Response, error) {
+			p, hr, err := c.PasteAPI.PastedAccount("toni.tester@domain.tld")
+			return len(p), hr, err
+		}},
+		{"SubscriptionStatus", true, false, func(c *Client) (int, *http.Response, error) {
+			s, hr, err := c.SubscriptionAPI.Status()
+			return boolToInt(s.Present()), hr, err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Run("API key is enforced", func(t *testing.T) {
+				requests := 0
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					requests++
+					w.WriteHeader(http.StatusUnauthorized)
+				}))
+				defer server.Close()
+				hc := New(WithHTTPClient(newTestClient(t, server.URL)))
+				_, hr, err := tt.call(&hc)
+				if !tt.auth {
+					if errors.Is(err, ErrMethodRequiresAPIKey) {
+						t.Errorf("expected unauthenticated endpoint not to require an API key")
+					}
+					return
+				}
+				if !errors.Is(err, ErrMethodRequiresAPIKey) {
+					t.Errorf("expected error to be %s, got %v", ErrMethodRequiresAPIKey, err)
+				}
+				if hr != nil || requests != 0 {
+					t.Errorf("expected no request to be performed without API key, got %d", requests)
+				}
+			})
+			t.Run("HTTP 404 response", func(t *testing.T) {
+				server := httptest.NewServer(newTestFailureHandler(t, http.StatusNotFound))
+				defer server.Close()
+				hc := New(WithHTTPClient(newTestClient(t, server.URL)), WithAPIKey(TestAPIKey))
+				items, hr, err := tt.call(&hc)
+				if hr == nil || hr.StatusCode != http.StatusNotFound {
+					t.Errorf("expected HTTP 404 response to be returned, got %v", hr)
+				}
+				if items != 0 {
+					t.Errorf("expected no items, got %d", items)
+				}
+				if tt.notFound {
+					if err != nil {
+						t.Errorf("expected HTTP 404 response to be an empty result, got error: %s", err)
+					}
+					return
+				}
+				if !errors.Is(err, ErrNotFound) {
+					t.Errorf("expected error to match %s, got %v", ErrNotFound, err)
+				}
+			})
+			t.Run("HTTP error returns APIError", func(t *testing.T) {
+				server := httptest.NewServer(newTestFailureHandler(t, http.StatusUnauthorized))
+				defer server.Close()
+				hc := New(WithHTTPClient(newTestClient(t, server.URL)), WithAPIKey(TestAPIKey))
+				_, _, err := tt.call(&hc)
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("expected error to be an APIError, got %v", err)
+				}
+				if apiErr.StatusCode != http.StatusUnauthorized {
+					t.Errorf("expected status code %d, got %d", http.StatusUnauthorized, apiErr.StatusCode)
+				}
+				if !errors.Is(err, ErrNonPositiveResponse) {
+					t.Errorf("expected error to wrap %s", ErrNonPositiveResponse)
+				}
+				if errors.Is(err, ErrNotFound) {
+					t.Errorf("expected HTTP 401 error not to match %s", ErrNotFound)
+				}
+			})
+		})
+	}
+}
+
+// boolToInt returns 1 for true and 0 for false
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 func TestClient_HTTPResBody(t *testing.T) {
 	t.Run("normal HTTP GET request succeeds", func(t *testing.T) {
 		server := httptest.NewServer(newTestStringHandler(t, "test"))
@@ -393,6 +523,74 @@ func (c *testClient) Do(req *http.Request) (*http.Response, error) {
 	return c.Client.Do(req)
 }
 
+// testRouteClient is a HTTP client that satisfies the HTTPClient interface. Other than the testClient,
+// it only replaces the scheme and host of the request URL, so that the test server can route the
+// request based on its path.
+type testRouteClient struct {
+	*http.Client
+	url *url.URL
+}
+
+// Do satisfies the HTTPClient interface for the testRouteClient type. It replaces the scheme and the
+// host in the HTTP request with the ones of the given url in the testRouteClient.
+func (c *testRouteClient) Do(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = c.url.Scheme
+	req.URL.Host = c.url.Host
+	return c.Client.Do(req)
+}
+
+// newTestRouteClient creates a mock HTTP client for testing purposes that keeps the request path intact.
+func newTestRouteClient(t *testing.T, serverURL string) *testRouteClient {
+	t.Helper()
+	testURL, err := url.Parse(serverURL)
+	if err != nil {
+		t.Fatalf("failed to parse test server URL: %s", err)
+	}
+	return &testRouteClient{httpClient(DefaultTimeout), testURL}
+}
+
+// testRouteHandler is an HTTP handler that serves the content of test files based on the request path
+// and counts the requests per path.
+type testRouteHandler struct {
+	t      *testing.T
+	routes map[string]string
+	mu     sync.Mutex
+	hits   map[string]int
+}
+
+// newTestRouteHandler creates a testRouteHandler for the given map of request paths to test files.
+func newTestRouteHandler(t *testing.T, routes map[string]string) *testRouteHandler {
+	t.Helper()
+	return &testRouteHandler{t: t, routes: routes, hits: make(map[string]int)}
+}
+
+// ServeHTTP satisfies the http.Handler interface for the testRouteHandler type.
+func (h *testRouteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.hits[r.URL.Path]++
+	h.mu.Unlock()
+
+	filename, ok := h.routes[r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		h.t.Errorf("failed to read test file: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	_, _ = w.Write(data)
+}
+
+// Hits returns the number of requests the testRouteHandler has served for the given path.
+func (h *testRouteHandler) Hits(path string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.hits[path]
+}
+
 // newTestClient creates a mock HTTP client for testing purposes with a specified URL and default timeout.
 func newTestClient(t *testing.T, url string) *testClient {
 	t.Helper()
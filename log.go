@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RedactedValue is the placeholder that replaces sensitive values in the structured log output
+const RedactedValue = "[REDACTED]"
+
+// endpointParams maps the API endpoints that take a path parameter to the name of the parameter and
+// whether the parameter holds a sensitive value
+var endpointParams = map[string]struct {
+	name      string
+	sensitive bool
+}{
+	"breach":          {"name", false},
+	"breachedaccount": {"account", true},
+	"breacheddomain":  {"domain", false},
+	"pasteaccount":    {"account", true},
+	"range":           {"prefix", true},
+	"PwnedLogos":      {"logo", false},
+}
+
+// endpointTemplate returns the path of the given URL with the path parameter replaced by a
+// placeholder, as well as the name and the value of the replaced path parameter
+func endpointTemplate(u *url.URL) (tpl, name, value string, sensitive bool) {
+	// The escaped path is split, so that escaped slashes in the path parameter are kept
+	path := u.EscapedPath()
+	idx := strings.LastIndex(path, "/")
+	if idx <= 0 {
+		return u.Path, "", "", false
+	}
+	prefix := path[:idx]
+	endpoint := prefix[strings.LastIndex(prefix, "/")+1:]
+	param, ok := endpointParams[endpoint]
+	if !ok {
+		return u.Path, "", "", false
+	}
+	value, err := url.PathUnescape(path[idx+1:])
+	if err != nil {
+		value = path[idx+1:]
+	}
+	return prefix + "/{" + param.name + "}", param.name, value, param.sensitive
+}
+
+// redact returns the given value or the RedactedValue placeholder, depending on whether the value
+// is sensitive and log redaction is enabled for the Client
+func (c *Client) redact(value string, sensitive bool) string {
+	if sensitive && !c.logUnredacted {
+		return RedactedValue
+	}
+	return value
+}
+
+// requestLogAttrs returns the structured log attributes that describe the given HTTP request
+func (c *Client) requestLogAttrs(hr *http.Request) []any {
+	tpl, name, value, sensitive := endpointTemplate(hr.URL)
+	attrs := []any{
+		slog.String("method", hr.Method),
+		slog.String("endpoint", tpl),
+	}
+	if name != "" {
+		attrs = append(attrs, slog.String(name, c.redact(value, sensitive)))
+	}
+	if hr.URL.RawQuery != "" {
+		attrs = append(attrs, slog.String("query", hr.URL.RawQuery))
+	}
+	if c.ak != "" {
+		attrs = append(attrs, slog.String("api_key", c.redact(c.ak, true)))
+	}
+	return attrs
+}
+
+// logRequestStart logs the start of a HTTP request with the given request attributes to the
+// structured logger
+func (c *Client) logRequestStart(reqAttrs []any) {
+	if c.slog == nil {
+		return
+	}
+	c.slog.Debug("HIBP API request started", reqAttrs...)
+}
+
+// logRateLimit logs a rate limit wait for a HTTP request with the given request attributes to the
+// structured logger
+func (c *Client) logRateLimit(reqAttrs []any, retry int, wait time.Duration) {
+	if c.slog == nil {
+		return
+	}
+	attrs := append(append([]any{}, reqAttrs...),
+		slog.Int("retry", retry),
+		slog.Duration("wait", wait),
+	)
+	c.slog.Warn("HIBP API rate limit hit, retrying request", attrs...)
+}
+
+// logRequestEnd logs the end of a HTTP request with the given request attributes to the structured
+// logger
+func (c *Client) logRequestEnd(reqAttrs []any, res *http.Response, start time.Time, retries int,
+	waited time.Duration, err error,
+) {
+	if c.slog == nil {
+		return
+	}
+	attrs := append([]any{}, reqAttrs...)
+	if res != nil {
+		attrs = append(attrs, slog.Int("status", res.StatusCode))
+	}
+	attrs = append(attrs,
+		slog.Duration("duration", time.Since(start)),
+		slog.Int("retries", retries),
+		slog.Duration("rate_limit_wait", waited),
+	)
+	if err != nil {
+		attrs = append(attrs, slog.String("error", c.redactError(err)))
+		c.slog.Error("HIBP API request failed", attrs...)
+		return
+	}
+	c.slog.Info("HIBP API request finished", attrs...)
+}
+
+// redactError returns the message of the given error. A url.Error holds the full request URL, which
+// can contain sensitive values, therefore only its operation and the underlying error are returned
+// if log redaction is enabled for the Client
+func (c *Client) redactError(err error) string {
+	var ue *url.Error
+	if !c.logUnredacted && errors.As(err, &ue) {
+		return ue.Op + ": " + ue.Err.Error()
+	}
+	return err.Error()
+}
@@ -0,0 +1,239 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestEndpointTemplate(t *testing.T) {
+	tests := []struct {
+		url       string
+		tpl       string
+		name      string
+		value     string
+		sensitive bool
+	}{
+		{BaseURL + "/breaches", "/api/v3/breaches", "", "", false},
+		{BaseURL + "/breach/Adobe", "/api/v3/breach/{name}", "name", "Adobe", false},
+		{
+			BaseURL + "/breachedaccount/toni.tester@domain.tld", "/api/v3/breachedaccount/{account}",
+			"account", "toni.tester@domain.tld", true,
+		},
+		{BaseURL + "/breacheddomain/domain.tld", "/api/v3/breacheddomain/{domain}", "domain", "domain.tld", false},
+		{
+			BaseURL + "/pasteaccount/toni.tester@domain.tld", "/api/v3/pasteaccount/{account}",
+			"account", "toni.tester@domain.tld", true,
+		},
+		{BaseURL + "/subscription/status", "/api/v3/subscription/status", "", "", false},
+		{PasswdBaseURL + "/range/a94a8", "/range/{prefix}", "prefix", "a94a8", true},
+		{"https://example.com", "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatalf("failed to parse URL: %s", err)
+			}
+			tpl, name, value, sensitive := endpointTemplate(u)
+			if tpl != tt.tpl {
+				t.Errorf("expected endpoint template to be %q, got %q", tt.tpl, tpl)
+			}
+			if name != tt.name {
+				t.Errorf("expected parameter name to be %q, got %q", tt.name, name)
+			}
+			if value != tt.value {
+				t.Errorf("expected parameter value to be %q, got %q", tt.value, value)
+			}
+			if sensitive != tt.sensitive {
+				t.Errorf("expected parameter sensitivity to be %t, got %t", tt.sensitive, sensitive)
+			}
+		})
+	}
+}
+
+func TestClient_WithStructuredLogger(t *testing.T) {
+	email := "toni.tester@domain.tld"
+	t.Run("request events are logged with redacted values", func(t *testing.T) {
+		server := httptest.NewServer(newTestRouteHandler(t, map[string]string{
+			"/api/v3/breachedaccount/" + email: fmt.Sprintf(ServerResponseBreachAccount, email),
+		}))
+		defer server.Close()
+		buffer := bytes.NewBuffer(nil)
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey),
+			WithStructuredLogger(newTestStructuredLogger(buffer)))
+		if _, _, err := hc.BreachAPI.BreachedAccount(email); err != nil {
+			t.Fatalf("failed to get breached account: %s", err)
+		}
+		if strings.Contains(buffer.String(), email) {
+			t.Error("expected account ID to be redacted in the log output")
+		}
+		if strings.Contains(buffer.String(), TestAPIKey) {
+			t.Error("expected API key to be redacted in the log output")
+		}
+
+		events := parseTestLogEvents(t, buffer)
+		if len(events) != 2 {
+			t.Fatalf("expected %d log events, got %d", 2, len(events))
+		}
+		if events[0]["msg"] != "HIBP API request started" {
+			t.Errorf("expected request start event, got %q", events[0]["msg"])
+		}
+		end := events[1]
+		if end["msg"] != "HIBP API request finished" {
+			t.Errorf("expected request end event, got %q", end["msg"])
+		}
+		if end["method"] != http.MethodGet {
+			t.Errorf("expected method to be %q, got %q", http.MethodGet, end["method"])
+		}
+		if end["endpoint"] != "/api/v3/breachedaccount/{account}" {
+			t.Errorf("expected endpoint template, got %q", end["endpoint"])
+		}
+		if end["account"] != RedactedValue {
+			t.Errorf("expected account to be redacted, got %q", end["account"])
+		}
+		if end["api_key"] != RedactedValue {
+			t.Errorf("expected API key to be redacted, got %q", end["api_key"])
+		}
+		if end["status"] != float64(http.StatusOK) {
+			t.Errorf("expected status to be %d, got %v", http.StatusOK, end["status"])
+		}
+		if end["retries"] != float64(0) {
+			t.Errorf("expected retries to be %d, got %v", 0, end["retries"])
+		}
+		if _, ok := end["duration"]; !ok {
+			t.Error("expected duration to be logged")
+		}
+	})
+	t.Run("request events are logged without redaction", func(t *testing.T) {
+		server := httptest.NewServer(newTestRouteHandler(t, map[string]string{
+			"/api/v3/breachedaccount/" + email: fmt.Sprintf(ServerResponseBreachAccount, email),
+		}))
+		defer server.Close()
+		buffer := bytes.NewBuffer(nil)
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey),
+			WithStructuredLogger(newTestStructuredLogger(buffer)), WithoutLogRedaction())
+		if _, _, err := hc.BreachAPI.BreachedAccount(email); err != nil {
+			t.Fatalf("failed to get breached account: %s", err)
+		}
+		events := parseTestLogEvents(t, buffer)
+		if len(events) != 2 {
+			t.Fatalf("expected %d log events, got %d", 2, len(events))
+		}
+		if events[1]["account"] != email {
+			t.Errorf("expected account to be %q, got %q", email, events[1]["account"])
+		}
+		if events[1]["api_key"] != TestAPIKey {
+			t.Errorf("expected API key to be %q, got %q", TestAPIKey, events[1]["api_key"])
+		}
+	})
+	t.Run("password range requests redact the hash prefix", func(t *testing.T) {
+		server := httptest.NewServer(newTestFileHandler(t, ServerResponsePwnedPassInsecure))
+		defer server.Close()
+		buffer := bytes.NewBuffer(nil)
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)),
+			WithStructuredLogger(newTestStructuredLogger(buffer)))
+		if _, _, err := hc.PwnedPassAPI.CheckPassword(PwStringInsecure); err != nil {
+			t.Fatalf("failed to check password: %s", err)
+		}
+		events := parseTestLogEvents(t, buffer)
+		if len(events) != 2 {
+			t.Fatalf("expected %d log events, got %d", 2, len(events))
+		}
+		if events[1]["endpoint"] != "/range/{prefix}" {
+			t.Errorf("expected endpoint template, got %q", events[1]["endpoint"])
+		}
+		if events[1]["prefix"] != RedactedValue {
+			t.Errorf("expected prefix to be redacted, got %q", events[1]["prefix"])
+		}
+	})
+	t.Run("failed requests are logged as errors", func(t *testing.T) {
+		server := httptest.NewServer(newTestFailureHandler(t, http.StatusInternalServerError))
+		defer server.Close()
+		buffer := bytes.NewBuffer(nil)
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)),
+			WithStructuredLogger(newTestStructuredLogger(buffer)))
+		if _, _, err := hc.BreachAPI.Breaches(); err == nil {
+			t.Fatal("expected request to fail")
+		}
+		events := parseTestLogEvents(t, buffer)
+		if len(events) != 2 {
+			t.Fatalf("expected %d log events, got %d", 2, len(events))
+		}
+		if events[1]["level"] != slog.LevelError.String() {
+			t.Errorf("expected log level to be %q, got %q", slog.LevelError.String(), events[1]["level"])
+		}
+		if events[1]["status"] != float64(http.StatusInternalServerError) {
+			t.Errorf("expected status to be %d, got %v", http.StatusInternalServerError, events[1]["status"])
+		}
+	})
+	t.Run("rate limit waits are logged", func(t *testing.T) {
+		run := 0
+		server := httptest.NewServer(newTestRetryHandler(t, &run, true))
+		defer server.Close()
+		buffer := bytes.NewBuffer(nil)
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)), WithRateLimitSleep(),
+			WithStructuredLogger(newTestStructuredLogger(buffer)))
+		if _, _, err := hc.BreachAPI.Breaches(); err != nil {
+			t.Fatalf("failed to get breaches: %s", err)
+		}
+		events := parseTestLogEvents(t, buffer)
+		if len(events) != 3 {
+			t.Fatalf("expected %d log events, got %d", 3, len(events))
+		}
+		if events[1]["msg"] != "HIBP API rate limit hit, retrying request" {
+			t.Errorf("expected rate limit event, got %q", events[1]["msg"])
+		}
+		if events[2]["retries"] != float64(1) {
+			t.Errorf("expected retries to be %d, got %v", 1, events[2]["retries"])
+		}
+		if events[2]["rate_limit_wait"] == float64(0) {
+			t.Error("expected rate limit wait to be logged")
+		}
+	})
+}
+
+func TestClient_redactError(t *testing.T) {
+	err := &url.Error{Op: "Get", URL: BaseURL + "/breachedaccount/toni.tester@domain.tld", Err: errors.New("failed")}
+	hc := New()
+	if msg := hc.redactError(err); strings.Contains(msg, "toni.tester") {
+		t.Errorf("expected URL to be redacted from error, got %q", msg)
+	}
+	hc = New(WithoutLogRedaction())
+	if msg := hc.redactError(err); !strings.Contains(msg, "toni.tester") {
+		t.Errorf("expected URL not to be redacted from error, got %q", msg)
+	}
+}
+
+// newTestStructuredLogger returns a structured logger that writes JSON log events of all levels to
+// the given buffer.
+func newTestStructuredLogger(buffer *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(buffer, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+// parseTestLogEvents parses the JSON log events written to the given buffer.
+func parseTestLogEvents(t *testing.T, buffer *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var events []map[string]any
+	scanner := bufio.NewScanner(buffer)
+	for scanner.Scan() {
+		event := make(map[string]any)
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("failed to parse log event: %s", err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
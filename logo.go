@@ -0,0 +1,363 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultLogoMaxSize is the default maximum size of a breach logo in bytes
+	DefaultLogoMaxSize = 1 << 20
+
+	// LogoContentType is the content type of breach logos
+	LogoContentType = "image/png"
+)
+
+var (
+	// ErrNoLogo is returned if a Breach does not provide a LogoPath
+	ErrNoLogo = errors.New("breach does not provide a logo")
+
+	// ErrLogoContentType is returned if a breach logo is not a PNG image
+	ErrLogoContentType = errors.New("breach logo is not a PNG image")
+
+	// ErrLogoTooLarge is returned if a breach logo exceeds the maximum size of the LogoStore
+	ErrLogoTooLarge = errors.New("breach logo exceeds the maximum size")
+
+	// ErrLogoName is returned if a breach name can not be used as key for a LogoCache
+	ErrLogoName = errors.New("breach name is not a valid logo cache key")
+)
+
+// LogoCache is the storage a LogoStore uses to cache breach logos. The logos are keyed by the
+// name of the breach. Implementations must be safe for concurrent use.
+type LogoCache interface {
+	// Get returns the cached logo for the given breach name. The bool return value is false if
+	// there is no cached logo for the breach name
+	Get(name string) ([]byte, bool, error)
+
+	// Set stores the given logo for the given breach name
+	Set(name string, logo []byte) error
+}
+
+// MemoryLogoCache is a LogoCache that keeps the breach logos in memory
+type MemoryLogoCache struct {
+	mu    sync.RWMutex
+	logos map[string][]byte
+}
+
+// NewMemoryLogoCache returns a new, empty MemoryLogoCache
+func NewMemoryLogoCache() *MemoryLogoCache {
+	return &MemoryLogoCache{logos: make(map[string][]byte)}
+}
+
+// Get satisfies the LogoCache interface for the MemoryLogoCache type
+func (m *MemoryLogoCache) Get(name string) ([]byte, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	logo, ok := m.logos[name]
+	return logo, ok, nil
+}
+
+// Set satisfies the LogoCache interface for the MemoryLogoCache type
+func (m *MemoryLogoCache) Set(name string, logo []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logos[name] = logo
+	return nil
+}
+
+// DiskLogoCache is a LogoCache that stores the breach logos as "<name>.png" files in a directory
+type DiskLogoCache struct {
+	dir string
+}
+
+// NewDiskLogoCache returns a new DiskLogoCache that stores the breach logos in the given directory.
+// The directory is created if it does not exist
+func NewDiskLogoCache(dir string) (*DiskLogoCache, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create logo cache directory: %w", err)
+	}
+	return &DiskLogoCache{dir: dir}, nil
+}
+
+// Get satisfies the LogoCache interface for the DiskLogoCache type
+func (d *DiskLogoCache) Get(name string) ([]byte, bool, error) {
+	file, err := d.file(name)
+	if err != nil {
+		return nil, false, err
+	}
+	logo, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return logo, true, nil
+}
+
+// Set satisfies the LogoCache interface for the DiskLogoCache type. The logo is written to a
+// temporary file first, so that concurrent readers never see a partially written logo
+func (d *DiskLogoCache) Set(name string, logo []byte) error {
+	file, err := d.file(name)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(d.dir, ".logo-*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(logo); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Rename(tmp.Name(), file); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// file returns the path of the logo file for the given breach name
+func (d *DiskLogoCache) file(name string) (string, error) {
+	if !validLogoName(name) {
+		return "", ErrLogoName
+	}
+	return filepath.Join(d.dir, name+".png"), nil
+}
+
+// LogoStore downloads the logos of breaches and caches them in a LogoCache, keyed by the name of
+// the breach. The logos are downloaded with the HTTP stack of the Client, so the logger, Observer
+// and middlewares of the Client apply. The API key is not sent with the requests. The logos are
+// served by a different host than the API, so failed downloads do not count towards the circuit
+// breaker of the Client and an open circuit breaker does not stop downloads.
+//
+// The LogoStore is an http.Handler that serves the logos by breach name, i. e. "/logos/Adobe.png",
+// when mounted at "/logos/" with http.StripPrefix.
+type LogoStore struct {
+	hibp    *Client
+	cache   LogoCache
+	maxSize int64
+	mu      sync.Mutex
+	loading map[string]*logoCall
+}
+
+// logoCall is an in-flight download of a breach logo, which concurrent callers wait for
+type logoCall struct {
+	done chan struct{}
+	logo []byte
+	err  error
+}
+
+// LogoStoreOption is a function that sets options on a LogoStore
+type LogoStoreOption func(*LogoStore)
+
+// WithLogoMaxSize sets the maximum size of a breach logo in bytes. Larger logos are rejected with
+// ErrLogoTooLarge. Values below 1 are ignored
+func WithLogoMaxSize(size int64) LogoStoreOption {
+	return func(s *LogoStore) {
+		if size > 0 {
+			s.maxSize = size
+		}
+	}
+}
+
+// NewLogoStore returns a new LogoStore that downloads breach logos with the given Client and caches
+// them in the given LogoCache. If the LogoCache is nil, a MemoryLogoCache is used
+func NewLogoStore(c *Client, cache LogoCache, options ...LogoStoreOption) *LogoStore {
+	if cache == nil {
+		cache = NewMemoryLogoCache()
+	}
+	s := &LogoStore{
+		hibp:    c,
+		cache:   cache,
+		maxSize: DefaultLogoMaxSize,
+		loading: make(map[string]*logoCall),
+	}
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+		option(s)
+	}
+	return s
+}
+
+// Logo returns the logo of the given Breach from the LogoCache. If the logo is not cached yet, it
+// is downloaded from the LogoPath of the Breach, validated and stored in the LogoCache. Concurrent
+// calls for the same breach share a single download
+func (s *LogoStore) Logo(b Breach) ([]byte, error) {
+	if !validLogoName(b.Name) {
+		return nil, ErrLogoName
+	}
+	logo, ok, err := s.cache.Get(b.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read logo from cache: %w", err)
+	}
+	if ok {
+		return logo, nil
+	}
+	if b.LogoPath == "" {
+		return nil, ErrNoLogo
+	}
+
+	s.mu.Lock()
+	if call, ok := s.loading[b.Name]; ok {
+		s.mu.Unlock()
+		<-call.done
+		return call.logo, call.err
+	}
+	// The logo might have been stored by a download that finished after the first cache lookup
+	if logo, ok, err = s.cache.Get(b.Name); err == nil && ok {
+		s.mu.Unlock()
+		return logo, nil
+	}
+	call := &logoCall{done: make(chan struct{})}
+	s.loading[b.Name] = call
+	s.mu.Unlock()
+
+	call.logo, call.err = s.download(b.LogoPath)
+	if call.err == nil {
+		if err = s.cache.Set(b.Name, call.logo); err != nil {
+			call.err = fmt.Errorf("failed to store logo in cache: %w", err)
+		}
+	}
+	s.mu.Lock()
+	delete(s.loading, b.Name)
+	s.mu.Unlock()
+	close(call.done)
+
+	return call.logo, call.err
+}
+
+// ServeHTTP satisfies the http.Handler interface for the LogoStore type. The last element of the
+// request path is the breach name, optionally with a ".png" extension. The breach is looked up in
+// the breach catalogue of the Client to determine its LogoPath
+func (s *LogoStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimSuffix(path.Base(r.URL.Path), ".png")
+	if !validLogoName(name) {
+		http.NotFound(w, r)
+		return
+	}
+
+	b := Breach{Name: name}
+	if _, ok, err := s.cache.Get(name); err != nil || !ok {
+		catalogue, err := s.hibp.BreachAPI.breachCatalogue()
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+			return
+		}
+		if b, ok = catalogue[name]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	logo, err := s.Logo(b)
+	switch {
+	case errors.Is(err, ErrNoLogo):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", LogoContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(logo)))
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, _ = w.Write(logo)
+}
+
+// download fetches the logo from the given URL and validates its content type and size
+func (s *LogoStore) download(logoURL string) ([]byte, error) {
+	hreq, err := http.NewRequest(http.MethodGet, logoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	hreq.Header.Set("Accept", LogoContentType)
+	hreq.Header.Set("user-agent", s.hibp.ua)
+
+	rt, hreq := s.hibp.startRequest(hreq)
+	hr, err := s.hibp.transport.client.Do(hreq)
+	if err != nil {
+		rt.end(hr, err)
+		return nil, err
+	}
+	defer func() {
+		_ = hr.Body.Close()
+	}()
+	logo, err := s.readLogo(hr)
+	rt.end(hr, err)
+
+	return logo, err
+}
+
+// readLogo reads and validates the logo from the given HTTP response
+func (s *LogoStore) readLogo(hr *http.Response) ([]byte, error) {
+	if hr.StatusCode != http.StatusOK {
+		return nil, newAPIError(hr)
+	}
+	if ct, _, err := mime.ParseMediaType(hr.Header.Get("Content-Type")); err != nil || ct != LogoContentType {
+		return nil, fmt.Errorf("%w: %q", ErrLogoContentType, hr.Header.Get("Content-Type"))
+	}
+	if hr.ContentLength > s.maxSize {
+		return nil, ErrLogoTooLarge
+	}
+	logo, err := io.ReadAll(io.LimitReader(hr.Body, s.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(logo)) > s.maxSize {
+		return nil, ErrLogoTooLarge
+	}
+	if !bytes.HasPrefix(logo, pngSignature) {
+		return nil, ErrLogoContentType
+	}
+	return logo, nil
+}
+
+// pngSignature is the signature every PNG image starts with
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// validLogoName checks that the given breach name only consists of characters that are safe to
+// use as file name and URL path element
+func validLogoName(name string) bool {
+	if name == "" || len(name) > 255 || strings.HasPrefix(name, ".") {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
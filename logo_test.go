@@ -0,0 +1,236 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	// TestLogoAdobe is the path of the logo test file of the Adobe breach
+	TestLogoAdobe = "testdata/logo-adobe.png"
+
+	// TestLogoPathAdobe is the path of the logo of the Adobe breach as returned by the API
+	TestLogoPathAdobe = "/Content/Images/PwnedLogos/Adobe.png"
+)
+
+func TestLogoStore_Logo(t *testing.T) {
+	adobe := Breach{Name: "Adobe", LogoPath: "https://haveibeenpwned.com" + TestLogoPathAdobe}
+	want, err := os.ReadFile(TestLogoAdobe)
+	if err != nil {
+		t.Fatalf("failed to read logo test file: %s", err)
+	}
+	t.Run("logo is downloaded once and cached", func(t *testing.T) {
+		var apiKey string
+		routes := newTestRouteHandler(t, map[string]string{TestLogoPathAdobe: TestLogoAdobe})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apiKey = r.Header.Get("hibp-api-key")
+			routes.ServeHTTP(w, r)
+		}))
+		defer server.Close()
+		observer := &testObserver{}
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey), WithObserver(observer))
+		store := NewLogoStore(&hc, nil, nil)
+		for i := 0; i < 2; i++ {
+			logo, err := store.Logo(adobe)
+			if err != nil {
+				t.Fatalf("failed to get logo: %s", err)
+			}
+			if !bytes.Equal(logo, want) {
+				t.Errorf("expected logo to match test file")
+			}
+		}
+		if routes.Hits(TestLogoPathAdobe) != 1 {
+			t.Errorf("expected logo to be downloaded %d time, got %d", 1, routes.Hits(TestLogoPathAdobe))
+		}
+		if apiKey != "" {
+			t.Error("expected API key not to be sent with logo requests")
+		}
+		if len(observer.responses) != 1 {
+			t.Fatalf("expected %d observed response, got %d", 1, len(observer.responses))
+		}
+		if observer.responses[0].Endpoint != "/Content/Images/PwnedLogos/{logo}" {
+			t.Errorf("expected endpoint to be %q, got %q", "/Content/Images/PwnedLogos/{logo}",
+				observer.responses[0].Endpoint)
+		}
+	})
+	t.Run("concurrent calls share a single download", func(t *testing.T) {
+		routes := newTestRouteHandler(t, map[string]string{TestLogoPathAdobe: TestLogoAdobe})
+		server := httptest.NewServer(routes)
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)))
+		store := NewLogoStore(&hc, NewMemoryLogoCache())
+		wg := sync.WaitGroup{}
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := store.Logo(adobe); err != nil {
+					t.Errorf("failed to get logo: %s", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if routes.Hits(TestLogoPathAdobe) != 1 {
+			t.Errorf("expected concurrent calls to share a single download, got %d", routes.Hits(TestLogoPathAdobe))
+		}
+	})
+	t.Run("logo validation", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			contentType string
+			body        []byte
+			status      int
+			options     []LogoStoreOption
+			wantErr     error
+		}{
+			{"wrong content type", "image/svg+xml", want, http.StatusOK, nil, ErrLogoContentType},
+			{"missing content type", "", want, http.StatusOK, nil, ErrLogoContentType},
+			{"content is not a PNG", "image/png", []byte("<html></html>"), http.StatusOK, nil, ErrLogoContentType},
+			{"logo too large", "image/png", want, http.StatusOK, []LogoStoreOption{WithLogoMaxSize(10)}, ErrLogoTooLarge},
+			{"HTTP error", "image/png", nil, http.StatusNotFound, nil, ErrNonPositiveResponse},
+			{"content type with parameters", "image/png; charset=binary", want, http.StatusOK, nil, nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header()["Content-Type"] = []string{tt.contentType}
+					w.WriteHeader(tt.status)
+					_, _ = w.Write(tt.body)
+				}))
+				defer server.Close()
+				hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)))
+				cache := NewMemoryLogoCache()
+				_, err := NewLogoStore(&hc, cache, tt.options...).Logo(adobe)
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error to be %v, got %v", tt.wantErr, err)
+				}
+				if _, ok, _ := cache.Get(adobe.Name); ok != (tt.wantErr == nil) {
+					t.Errorf("expected logo to be cached: %t, got %t", tt.wantErr == nil, ok)
+				}
+			})
+		}
+	})
+	t.Run("failed downloads do not open the circuit breaker", func(t *testing.T) {
+		server := httptest.NewServer(newTestRouteHandler(t, map[string]string{
+			"/api/v3/breaches": ServerResponseBreachesAllNonTruncatedVerifiedOnly,
+		}))
+		defer server.Close()
+		fault := func(next HTTPClient) HTTPClient {
+			return HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+				if strings.HasPrefix(req.URL.Path, "/Content/Images/PwnedLogos/") {
+					return nil, errors.New("timeout")
+				}
+				return next.Do(req)
+			})
+		}
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithMiddleware(fault),
+			WithCircuitBreaker(1, time.Minute))
+		store := NewLogoStore(&hc, nil)
+		for i := 0; i < 3; i++ {
+			if _, err := store.Logo(adobe); err == nil {
+				t.Fatal("expected logo download to fail")
+			}
+		}
+		if hc.CircuitState() != CircuitClosed {
+			t.Errorf("expected circuit state to be %s, got %s", CircuitClosed, hc.CircuitState())
+		}
+		if _, _, err := hc.BreachAPI.Breaches(); err != nil {
+			t.Errorf("expected API request to succeed after failed logo downloads, got %s", err)
+		}
+	})
+	t.Run("breaches without logo and invalid names fail", func(t *testing.T) {
+		hc := New()
+		store := NewLogoStore(&hc, nil)
+		if _, err := store.Logo(Breach{Name: "Adobe"}); !errors.Is(err, ErrNoLogo) {
+			t.Errorf("expected error to be %s, got %v", ErrNoLogo, err)
+		}
+		if _, err := store.Logo(Breach{Name: "../Adobe", LogoPath: adobe.LogoPath}); !errors.Is(err, ErrLogoName) {
+			t.Errorf("expected error to be %s, got %v", ErrLogoName, err)
+		}
+	})
+}
+
+func TestDiskLogoCache(t *testing.T) {
+	cache, err := NewDiskLogoCache(t.TempDir() + "/logos")
+	if err != nil {
+		t.Fatalf("failed to create disk logo cache: %s", err)
+	}
+	if _, ok, err := cache.Get("Adobe"); ok || err != nil {
+		t.Errorf("expected empty cache, got ok: %t, err: %v", ok, err)
+	}
+	if err = cache.Set("Adobe", []byte("logo")); err != nil {
+		t.Fatalf("failed to store logo: %s", err)
+	}
+	logo, ok, err := cache.Get("Adobe")
+	if err != nil || !ok || string(logo) != "logo" {
+		t.Errorf("expected cached logo, got %q, ok: %t, err: %v", logo, ok, err)
+	}
+	for _, name := range []string{"", "../Adobe", ".hidden", "Adobe/Photoshop"} {
+		if err = cache.Set(name, []byte("logo")); !errors.Is(err, ErrLogoName) {
+			t.Errorf("expected error for name %q to be %s, got %v", name, ErrLogoName, err)
+		}
+		if _, _, err = cache.Get(name); !errors.Is(err, ErrLogoName) {
+			t.Errorf("expected error for name %q to be %s, got %v", name, ErrLogoName, err)
+		}
+	}
+}
+
+func TestLogoStore_ServeHTTP(t *testing.T) {
+	routes := newTestRouteHandler(t, map[string]string{
+		"/api/v3/breaches": ServerResponseBreachesAllNonTruncatedUnverified,
+		TestLogoPathAdobe:  TestLogoAdobe,
+	})
+	server := httptest.NewServer(routes)
+	defer server.Close()
+	hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)))
+	handler := http.StripPrefix("/logos/", NewLogoStore(&hc, nil))
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{"logo is served", http.MethodGet, "/logos/Adobe.png", http.StatusOK},
+		{"cached logo is served without extension", http.MethodGet, "/logos/Adobe", http.StatusOK},
+		{"HEAD request", http.MethodHead, "/logos/Adobe.png", http.StatusOK},
+		{"unknown breach", http.MethodGet, "/logos/NotABreach.png", http.StatusNotFound},
+		{"invalid breach name", http.MethodGet, "/logos/%2e%2e.png", http.StatusNotFound},
+		{"logo download fails", http.MethodGet, "/logos/Xiaomi.png", http.StatusBadGateway},
+		{"unsupported method", http.MethodPost, "/logos/Adobe.png", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("expected status code %d, got %d", tt.status, rec.Code)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if rec.Header().Get("Content-Type") != LogoContentType {
+				t.Errorf("expected content type %q, got %q", LogoContentType, rec.Header().Get("Content-Type"))
+			}
+			if tt.method == http.MethodGet && !strings.HasPrefix(rec.Body.String(), string(pngSignature)) {
+				t.Error("expected response to be a PNG image")
+			}
+		})
+	}
+	if routes.Hits(TestLogoPathAdobe) != 1 {
+		t.Errorf("expected logo to be downloaded %d time, got %d", 1, routes.Hits(TestLogoPathAdobe))
+	}
+	if routes.Hits("/api/v3/breaches") != 1 {
+		t.Errorf("expected breach catalogue to be fetched %d time, got %d", 1, routes.Hits("/api/v3/breaches"))
+	}
+}
@@ -22,6 +22,16 @@ func init() {
 	crypto.RegisterHash(crypto.MD4, New)
 }
 
+// Sum returns the MD4 checksum of the data.
+func Sum(data []byte) [Size]byte {
+	var d digest
+	d.Reset()
+	_, _ = d.Write(data)
+	var sum [Size]byte
+	d.Sum(sum[:0])
+	return sum
+}
+
 // Size is the size of an MD4 checksum in bytes.
 const Size = 16
 
@@ -92,8 +102,7 @@ func (d *digest) Write(p []byte) (nn int, err error) {
 
 func (d *digest) Sum(in []byte) []byte {
 	// Make a copy of d0, so that caller can keep writing and summing.
-	dc := new(digest)
-	*dc = *d
+	dc := *d
 
 	// Padding.  Add a 1 bit and 0 bits until 56 bytes mod 64.
 	plen := dc.len
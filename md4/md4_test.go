@@ -73,3 +73,39 @@ func TestGolden(t *testing.T) {
 		}
 	}
 }
+
+func TestSum(t *testing.T) {
+	for _, g := range golden {
+		s := fmt.Sprintf("%x", Sum([]byte(g.in)))
+		if s != g.out {
+			t.Errorf("Sum(%s) = %s want %s", g.in, s, g.out)
+		}
+	}
+}
+
+var (
+	bench = New()
+	buf   = make([]byte, 8192)
+)
+
+func benchmarkSize(b *testing.B, size int) {
+	b.SetBytes(int64(size))
+	sum := make([]byte, bench.Size())
+	for i := 0; i < b.N; i++ {
+		bench.Reset()
+		_, _ = bench.Write(buf[:size])
+		bench.Sum(sum[:0])
+	}
+}
+
+func BenchmarkHash8Bytes(b *testing.B) {
+	benchmarkSize(b, 8)
+}
+
+func BenchmarkHash1K(b *testing.B) {
+	benchmarkSize(b, 1024)
+}
+
+func BenchmarkHash8K(b *testing.B) {
+	benchmarkSize(b, 8192)
+}
@@ -12,75 +12,99 @@
 
 package md4
 
-import "math/bits"
-
-var (
-	shift1 = []int{3, 7, 11, 19}
-	shift2 = []int{3, 5, 9, 13}
-	shift3 = []int{3, 9, 11, 15}
-)
-
-var (
-	xIndex2 = []uint{0, 4, 8, 12, 1, 5, 9, 13, 2, 6, 10, 14, 3, 7, 11, 15}
-	xIndex3 = []uint{0, 8, 4, 12, 2, 10, 6, 14, 1, 9, 5, 13, 3, 11, 7, 15}
+import (
+	"encoding/binary"
+	"math/bits"
 )
 
+// _Block processes all complete 64 byte chunks of p and returns the
+// number of bytes processed.
+//
+// The three rounds are fully unrolled, so that the shift amounts and
+// message word indices are constants and the a, b, c, d rotation of
+// the generic loop is replaced by variable renaming. The message words
+// are kept in local variables instead of an array, which lets the
+// compiler keep them in registers.
 func _Block(dig *digest, p []byte) int {
 	a := dig.s[0]
 	b := dig.s[1]
 	c := dig.s[2]
 	d := dig.s[3]
 	n := 0
-	var X [16]uint32
 	for len(p) >= _Chunk {
 		aa, bb, cc, dd := a, b, c, d
 
-		j := 0
-		for i := 0; i < 16; i++ {
-			X[i] = uint32(p[j]) | uint32(p[j+1])<<8 | uint32(p[j+2])<<16 | uint32(p[j+3])<<24
-			j += 4
-		}
-
-		// If this needs to be made faster in the future,
-		// the usual trick is to unroll each of these
-		// loops by a factor of 4; that lets you replace
-		// the shift[] lookups with constants and,
-		// with suitable variable renaming in each
-		// unrolled body, delete the a, b, c, d = d, a, b, c
-		// (or you can let the optimizer do the renaming).
-		//
-		// The index variables are uint so that % by a power
-		// of two can be optimized easily by a compiler.
+		_ = p[_Chunk-1] // bounds check hint to compiler
+		x0 := binary.LittleEndian.Uint32(p[0:])
+		x1 := binary.LittleEndian.Uint32(p[4:])
+		x2 := binary.LittleEndian.Uint32(p[8:])
+		x3 := binary.LittleEndian.Uint32(p[12:])
+		x4 := binary.LittleEndian.Uint32(p[16:])
+		x5 := binary.LittleEndian.Uint32(p[20:])
+		x6 := binary.LittleEndian.Uint32(p[24:])
+		x7 := binary.LittleEndian.Uint32(p[28:])
+		x8 := binary.LittleEndian.Uint32(p[32:])
+		x9 := binary.LittleEndian.Uint32(p[36:])
+		x10 := binary.LittleEndian.Uint32(p[40:])
+		x11 := binary.LittleEndian.Uint32(p[44:])
+		x12 := binary.LittleEndian.Uint32(p[48:])
+		x13 := binary.LittleEndian.Uint32(p[52:])
+		x14 := binary.LittleEndian.Uint32(p[56:])
+		x15 := binary.LittleEndian.Uint32(p[60:])
 
 		// Round 1.
-		for i := uint(0); i < 16; i++ {
-			x := i
-			s := shift1[i%4]
-			f := ((c ^ d) & b) ^ d
-			a += f + X[x]
-			a = bits.RotateLeft32(a, s)
-			a, b, c, d = d, a, b, c
-		}
+		a = bits.RotateLeft32(a+(((c^d)&b)^d)+x0, 3)
+		d = bits.RotateLeft32(d+(((b^c)&a)^c)+x1, 7)
+		c = bits.RotateLeft32(c+(((a^b)&d)^b)+x2, 11)
+		b = bits.RotateLeft32(b+(((d^a)&c)^a)+x3, 19)
+		a = bits.RotateLeft32(a+(((c^d)&b)^d)+x4, 3)
+		d = bits.RotateLeft32(d+(((b^c)&a)^c)+x5, 7)
+		c = bits.RotateLeft32(c+(((a^b)&d)^b)+x6, 11)
+		b = bits.RotateLeft32(b+(((d^a)&c)^a)+x7, 19)
+		a = bits.RotateLeft32(a+(((c^d)&b)^d)+x8, 3)
+		d = bits.RotateLeft32(d+(((b^c)&a)^c)+x9, 7)
+		c = bits.RotateLeft32(c+(((a^b)&d)^b)+x10, 11)
+		b = bits.RotateLeft32(b+(((d^a)&c)^a)+x11, 19)
+		a = bits.RotateLeft32(a+(((c^d)&b)^d)+x12, 3)
+		d = bits.RotateLeft32(d+(((b^c)&a)^c)+x13, 7)
+		c = bits.RotateLeft32(c+(((a^b)&d)^b)+x14, 11)
+		b = bits.RotateLeft32(b+(((d^a)&c)^a)+x15, 19)
 
 		// Round 2.
-		for i := uint(0); i < 16; i++ {
-			x := xIndex2[i]
-			s := shift2[i%4]
-			g := (b & c) | (b & d) | (c & d)
-			a += g + X[x] + 0x5a827999
-			a = bits.RotateLeft32(a, s)
-			a, b, c, d = d, a, b, c
-		}
+		a = bits.RotateLeft32(a+((b&c)|((b|c)&d))+x0+0x5a827999, 3)
+		d = bits.RotateLeft32(d+((a&b)|((a|b)&c))+x4+0x5a827999, 5)
+		c = bits.RotateLeft32(c+((d&a)|((d|a)&b))+x8+0x5a827999, 9)
+		b = bits.RotateLeft32(b+((c&d)|((c|d)&a))+x12+0x5a827999, 13)
+		a = bits.RotateLeft32(a+((b&c)|((b|c)&d))+x1+0x5a827999, 3)
+		d = bits.RotateLeft32(d+((a&b)|((a|b)&c))+x5+0x5a827999, 5)
+		c = bits.RotateLeft32(c+((d&a)|((d|a)&b))+x9+0x5a827999, 9)
+		b = bits.RotateLeft32(b+((c&d)|((c|d)&a))+x13+0x5a827999, 13)
+		a = bits.RotateLeft32(a+((b&c)|((b|c)&d))+x2+0x5a827999, 3)
+		d = bits.RotateLeft32(d+((a&b)|((a|b)&c))+x6+0x5a827999, 5)
+		c = bits.RotateLeft32(c+((d&a)|((d|a)&b))+x10+0x5a827999, 9)
+		b = bits.RotateLeft32(b+((c&d)|((c|d)&a))+x14+0x5a827999, 13)
+		a = bits.RotateLeft32(a+((b&c)|((b|c)&d))+x3+0x5a827999, 3)
+		d = bits.RotateLeft32(d+((a&b)|((a|b)&c))+x7+0x5a827999, 5)
+		c = bits.RotateLeft32(c+((d&a)|((d|a)&b))+x11+0x5a827999, 9)
+		b = bits.RotateLeft32(b+((c&d)|((c|d)&a))+x15+0x5a827999, 13)
 
 		// Round 3.
-		for i := uint(0); i < 16; i++ {
-			x := xIndex3[i]
-			s := shift3[i%4]
-			h := b ^ c ^ d
-			a += h + X[x] + 0x6ed9eba1
-			a = bits.RotateLeft32(a, s)
-			a, b, c, d = d, a, b, c
-		}
+		a = bits.RotateLeft32(a+(b^c^d)+x0+0x6ed9eba1, 3)
+		d = bits.RotateLeft32(d+(a^b^c)+x8+0x6ed9eba1, 9)
+		c = bits.RotateLeft32(c+(d^a^b)+x4+0x6ed9eba1, 11)
+		b = bits.RotateLeft32(b+(c^d^a)+x12+0x6ed9eba1, 15)
+		a = bits.RotateLeft32(a+(b^c^d)+x2+0x6ed9eba1, 3)
+		d = bits.RotateLeft32(d+(a^b^c)+x10+0x6ed9eba1, 9)
+		c = bits.RotateLeft32(c+(d^a^b)+x6+0x6ed9eba1, 11)
+		b = bits.RotateLeft32(b+(c^d^a)+x14+0x6ed9eba1, 15)
+		a = bits.RotateLeft32(a+(b^c^d)+x1+0x6ed9eba1, 3)
+		d = bits.RotateLeft32(d+(a^b^c)+x9+0x6ed9eba1, 9)
+		c = bits.RotateLeft32(c+(d^a^b)+x5+0x6ed9eba1, 11)
+		b = bits.RotateLeft32(b+(c^d^a)+x13+0x6ed9eba1, 15)
+		a = bits.RotateLeft32(a+(b^c^d)+x3+0x6ed9eba1, 3)
+		d = bits.RotateLeft32(d+(a^b^c)+x11+0x6ed9eba1, 9)
+		c = bits.RotateLeft32(c+(d^a^b)+x7+0x6ed9eba1, 11)
+		b = bits.RotateLeft32(b+(c^d^a)+x15+0x6ed9eba1, 15)
 
 		a += aa
 		b += bb
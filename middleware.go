@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"net/http"
+)
+
+// Middleware wraps the HTTPClient that performs the HTTP requests of the Client. It can be used to
+// modify requests and responses, i. e. for authenticating proxies, request signing, audit logging or
+// fault injection in tests.
+//
+// The middlewares are composed in the order they are added to the Client, the first Middleware being
+// the outermost one. They are placed between the built-in layers of the Client and the HTTPClient,
+// so that a single API call passes through the layers in the following order:
+//
+//  1. The breach catalogue cache, which might serve the result without any HTTP request
+//  2. The rate limit retry of the WithRateLimitSleep option
+//  3. The structured logger and the Observer, which report the API call as a whole
+//  4. The circuit breaker of the WithCircuitBreaker option, which is checked for each attempt
+//  5. The middlewares, which are called for each attempt, including the retries
+//  6. The HTTPClient
+type Middleware func(next HTTPClient) HTTPClient
+
+// HTTPClientFunc is an adapter to allow the use of ordinary functions as HTTPClient. It is useful
+// to implement a Middleware.
+type HTTPClientFunc func(req *http.Request) (*http.Response, error)
+
+// Do satisfies the HTTPClient interface for the HTTPClientFunc type
+func (f HTTPClientFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// transport holds the middlewares of the Client and the HTTPClient they are composed to
+type transport struct {
+	middlewares []Middleware
+	client      HTTPClient
+}
+
+// WithMiddleware adds a Middleware to the HTTP transport of the Client. If the option is used
+// multiple times, the middlewares are composed in the order they were added, the first one being
+// the outermost.
+func WithMiddleware(m Middleware) Option {
+	if m == nil {
+		return nil
+	}
+	return func(c *Client) {
+		if c.transport == nil {
+			c.transport = &transport{}
+		}
+		c.transport.middlewares = append(c.transport.middlewares, m)
+	}
+}
+
+// buildTransport composes the middlewares of the Client around its HTTPClient
+func (c *Client) buildTransport() {
+	if c.transport == nil {
+		c.transport = &transport{}
+	}
+	client := c.hc
+	for i := len(c.transport.middlewares) - 1; i >= 0; i-- {
+		client = c.transport.middlewares[i](client)
+	}
+	c.transport.client = client
+}
+
+// do performs the given HTTP request through the circuit breaker and the middlewares of the Client
+func (c *Client) do(hreq *http.Request) (*http.Response, error) {
+	if c.breaker == nil {
+		return c.transport.client.Do(hreq)
+	}
+	gen, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	hr, err := c.transport.client.Do(hreq)
+	c.breaker.record(gen, hr, err)
+	return hr, err
+}
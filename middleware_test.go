@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestClient_WithMiddleware(t *testing.T) {
+	t.Run("nil middleware is ignored", func(t *testing.T) {
+		hc := New(WithMiddleware(nil))
+		if len(hc.transport.middlewares) != 0 {
+			t.Errorf("expected no middlewares, got %d", len(hc.transport.middlewares))
+		}
+	})
+	t.Run("middlewares are called in the order they were added", func(t *testing.T) {
+		server := httptest.NewServer(newTestFileHandler(t, ServerResponseBreachesAllTruncatedUnverified))
+		defer server.Close()
+		var calls []string
+		hc := New(WithMiddleware(newTestRecordingMiddleware("first", &calls)),
+			WithMiddleware(newTestRecordingMiddleware("second", &calls)),
+			WithHTTPClient(newTestClient(t, server.URL)))
+		if _, _, err := hc.BreachAPI.Breaches(); err != nil {
+			t.Fatalf("failed to get breaches: %s", err)
+		}
+		expected := []string{"first:before", "second:before", "second:after", "first:after"}
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("expected middleware calls to be %v, got %v", expected, calls)
+		}
+	})
+	t.Run("middleware can modify the request", func(t *testing.T) {
+		var header string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header.Get("X-Signature")
+			_, _ = w.Write([]byte(`[]`))
+		}))
+		defer server.Close()
+		signer := func(next HTTPClient) HTTPClient {
+			return HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+				req.Header.Set("X-Signature", "signed")
+				return next.Do(req)
+			})
+		}
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)), WithMiddleware(signer))
+		if _, _, err := hc.BreachAPI.Breaches(); err != nil {
+			t.Fatalf("failed to get breaches: %s", err)
+		}
+		if header != "signed" {
+			t.Errorf("expected request to be signed by middleware, got %q", header)
+		}
+	})
+	t.Run("middleware can inject faults", func(t *testing.T) {
+		errFault := errors.New("injected fault")
+		fault := func(HTTPClient) HTTPClient {
+			return HTTPClientFunc(func(*http.Request) (*http.Response, error) {
+				return nil, errFault
+			})
+		}
+		observer := &testObserver{}
+		hc := New(WithMiddleware(fault), WithObserver(observer))
+		_, _, err := hc.BreachAPI.Breaches()
+		if !errors.Is(err, errFault) {
+			t.Errorf("expected error to be %s, got %s", errFault, err)
+		}
+		_, _, err = hc.PwnedPassAPI.CheckPassword(PwStringInsecure)
+		if !errors.Is(err, errFault) {
+			t.Errorf("expected error to be %s, got %s", errFault, err)
+		}
+		if len(observer.responses) != 2 {
+			t.Errorf("expected injected faults to be observed, got %d responses", len(observer.responses))
+		}
+	})
+	t.Run("middlewares are called for each retry", func(t *testing.T) {
+		run := 0
+		server := httptest.NewServer(newTestRetryHandler(t, &run, true))
+		defer server.Close()
+		var calls []string
+		observer := &testObserver{}
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)), WithRateLimitSleep(), WithObserver(observer),
+			WithMiddleware(newTestRecordingMiddleware("mw", &calls)))
+		if _, _, err := hc.BreachAPI.Breaches(); err != nil {
+			t.Fatalf("failed to get breaches: %s", err)
+		}
+		if len(calls) != 4 {
+			t.Errorf("expected middleware to be called for %d attempts, got %d calls", 2, len(calls)/2)
+		}
+		if len(observer.starts) != 1 || len(observer.responses) != 1 {
+			t.Error("expected observer to report the API call only once")
+		}
+	})
+}
+
+// newTestRecordingMiddleware returns a Middleware that records its calls before and after the next
+// HTTPClient under the given name.
+func newTestRecordingMiddleware(name string, calls *[]string) Middleware {
+	var mu sync.Mutex
+	record := func(call string) {
+		mu.Lock()
+		defer mu.Unlock()
+		*calls = append(*calls, name+":"+call)
+	}
+	return func(next HTTPClient) HTTPClient {
+		return HTTPClientFunc(func(req *http.Request) (*http.Response, error) {
+			record("before")
+			defer record("after")
+			return next.Do(req)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+// Package ntlm implements the NT hash of Windows passwords, as it is used by the NTLM mode of the
+// Pwned Passwords API. The NT hash is the MD4 checksum of the UTF-16 little-endian encoded
+// password, as defined by the NTOWFv1 function of MS-NLMP.
+//
+// NOTE: The NT hash is cryptographically broken and should only be used for compatibility with
+// Windows systems and password audits.
+//
+// Reference: https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-nlmp/464551a8-9fc4-428e-b3d3-bc5bfb2e73a5
+package ntlm
+
+import (
+	"encoding/hex"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/wneessen/go-hibp/md4"
+)
+
+// Size is the size of an NT hash in bytes
+const Size = md4.Size
+
+// NTHash returns the NT hash of the given password
+func NTHash(password string) [Size]byte {
+	return NTHashBytes([]byte(password))
+}
+
+// NTHashBytes returns the NT hash of the given password byte slice. The password is neither
+// modified nor retained and the intermediate UTF-16 encoded copy of the password is zeroed before
+// returning, so that the caller can zero the password buffer once the hash is computed
+func NTHashBytes(password []byte) [Size]byte {
+	buf := UTF16LE(password)
+	sum := md4.Sum(buf)
+	for i := range buf {
+		buf[i] = 0
+	}
+	return sum
+}
+
+// NTHashHex returns the lower case, hex encoded NT hash of the given password, as it is used by
+// the Pwned Passwords API
+func NTHashHex(password string) string {
+	sum := NTHash(password)
+	return hex.EncodeToString(sum[:])
+}
+
+// UTF16LE converts the given UTF-8 encoded byte slice to a UTF-16 little-endian encoded byte slice.
+// Characters outside the Basic Multilingual Plane are encoded as surrogate pairs. Invalid UTF-8
+// sequences are replaced by the Unicode replacement character U+FFFD
+func UTF16LE(b []byte) []byte {
+	u := make([]byte, 0, len(b)*2)
+	var units [2]uint16
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		b = b[size:]
+		for _, e := range utf16.AppendRune(units[:0], r) {
+			u = append(u, byte(e), byte(e>>8))
+		}
+	}
+	return u
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package ntlm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/wneessen/go-hibp/md4"
+)
+
+func TestNTHash(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     string
+	}{
+		// MS-NLMP 4.2.2.1.2 NTOWFv1() with the password of MS-NLMP 4.2.1 Common Values
+		{"MS-NLMP NTOWFv1", "Password", "a4f49c406510bdcab6824ee7c30fd852"},
+		{"empty password", "", "31d6cfe0d16ae931b73c59d7e0c089c0"},
+		{"ASCII password", "password", "8846f7eaee8fb117ad06bdd830b7586c"},
+		{"HIBP test password", "test", "0cb6948805f797bf2a82807973b89537"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sum := NTHash(tt.password)
+			if hex.EncodeToString(sum[:]) != tt.want {
+				t.Errorf("NTHash failed, expected: %s, got: %x", tt.want, sum)
+			}
+			if NTHashHex(tt.password) != tt.want {
+				t.Errorf("NTHashHex failed, expected: %s, got: %s", tt.want, NTHashHex(tt.password))
+			}
+		})
+	}
+	t.Run("non-ASCII passwords are hashed as UTF-16LE", func(t *testing.T) {
+		for _, password := range []string{"Päßwörd", "пароль", "密码", "p😀ss"} {
+			want := md4.Sum(UTF16LE([]byte(password)))
+			if NTHash(password) != want {
+				t.Errorf("NTHash of %q failed, expected: %x, got: %x", password, want, NTHash(password))
+			}
+		}
+	})
+}
+
+func TestNTHashBytes(t *testing.T) {
+	password := []byte("Password")
+	sum := NTHashBytes(password)
+	if sum != NTHash("Password") {
+		t.Errorf("NTHashBytes failed, expected: %x, got: %x", NTHash("Password"), sum)
+	}
+	if string(password) != "Password" {
+		t.Errorf("NTHashBytes was not supposed to modify the password, got: %q", password)
+	}
+}
+
+func TestUTF16LE(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []byte
+	}{
+		{"ASCII", "test", []byte{'t', 0, 'e', 0, 's', 0, 't', 0}},
+		{"Latin-1", "ä", []byte{0xe4, 0x00}},
+		{"BMP", "€", []byte{0xac, 0x20}},
+		{"surrogate pair", "😀", []byte{0x3d, 0xd8, 0x00, 0xde}},
+		{"mixed", "a𝄞b", []byte{'a', 0, 0x34, 0xd8, 0x1e, 0xdd, 'b', 0}},
+		{"invalid UTF-8", "\xff", []byte{0xfd, 0xff}},
+		{"empty", "", []byte{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UTF16LE([]byte(tt.input)); !bytes.Equal(got, tt.want) {
+				t.Errorf("UTF16LE failed, expected: %x, got: %x", tt.want, got)
+			}
+		})
+	}
+}
+
+func BenchmarkNTHash(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = NTHash("Password")
+	}
+}
+
+func BenchmarkNTHashBytes(b *testing.B) {
+	password := []byte(strings.Repeat("p", 64))
+	b.ReportAllocs()
+	b.SetBytes(int64(len(password)))
+	for i := 0; i < b.N; i++ {
+		_ = NTHashBytes(password)
+	}
+}
+
+func BenchmarkNTHashHex(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = NTHashHex("Password")
+	}
+}
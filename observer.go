@@ -0,0 +1,224 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// CacheBreachCatalogue is the name of the cache that holds the breach catalogue used for the
+// hydration of truncated breaches
+const CacheBreachCatalogue = "breach_catalogue"
+
+// Observer is an interface for receiving events about the API requests performed by the Client. It
+// can be used to collect metrics or to create tracing spans for the API requests.
+//
+// All methods of an Observer are called synchronously and must be safe for concurrent use. Embed
+// NopObserver in an Observer implementation to only implement a subset of the methods.
+type Observer interface {
+	// RequestStart is called before an API request is performed. The returned context is attached to
+	// the HTTP request and passed to all further callbacks for the same API request
+	RequestStart(ctx context.Context, ev RequestEvent) context.Context
+
+	// Response is called once the API request has finished, including all of its retries
+	Response(ctx context.Context, ev ResponseEvent)
+
+	// Retry is called before an API request is retried after the rate limit was hit
+	Retry(ctx context.Context, ev RetryEvent)
+
+	// CacheHit is called if a result was served from a cache of the Client instead of the API. A
+	// cache hit is not part of an API request and the methods of the Client take no context, so the
+	// given context is always context.Background() and holds no values of RequestStart
+	CacheHit(ctx context.Context, ev CacheEvent)
+}
+
+// RequestEvent describes an API request
+type RequestEvent struct {
+	// Method is the HTTP method of the API request
+	Method string
+
+	// Endpoint is the path of the API endpoint with the path parameters replaced by placeholders,
+	// i. e. "/api/v3/breachedaccount/{account}"
+	Endpoint string
+}
+
+// ResponseEvent describes the result of an API request
+type ResponseEvent struct {
+	RequestEvent
+
+	// StatusCode is the HTTP status code of the last response. It is 0 if no response was received
+	StatusCode int
+
+	// Duration is the total duration of the API request, including all retries and rate limit waits
+	Duration time.Duration
+
+	// Retries is the number of times the API request was retried
+	Retries int
+
+	// Err is the error the API request failed with, if any
+	Err error
+}
+
+// RetryEvent describes a retry of an API request
+type RetryEvent struct {
+	RequestEvent
+
+	// Attempt is the number of the upcoming retry, starting at 1
+	Attempt int
+
+	// Wait is the duration the Client waits before retrying the API request
+	Wait time.Duration
+}
+
+// CacheEvent describes a cache lookup of the Client
+type CacheEvent struct {
+	// Cache is the name of the cache that served the result, i. e. CacheBreachCatalogue
+	Cache string
+}
+
+// NopObserver is an Observer that ignores all events. It can be embedded in custom Observer
+// implementations that only want to implement a subset of the Observer methods.
+type NopObserver struct{}
+
+// RequestStart satisfies the Observer interface for the NopObserver type
+func (NopObserver) RequestStart(ctx context.Context, _ RequestEvent) context.Context { return ctx }
+
+// Response satisfies the Observer interface for the NopObserver type
+func (NopObserver) Response(context.Context, ResponseEvent) {}
+
+// Retry satisfies the Observer interface for the NopObserver type
+func (NopObserver) Retry(context.Context, RetryEvent) {}
+
+// CacheHit satisfies the Observer interface for the NopObserver type
+func (NopObserver) CacheHit(context.Context, CacheEvent) {}
+
+// multiObserver is an Observer that forwards all events to a list of Observers
+type multiObserver struct {
+	observers []Observer
+}
+
+// RequestStart satisfies the Observer interface for the multiObserver type
+func (m *multiObserver) RequestStart(ctx context.Context, ev RequestEvent) context.Context {
+	for _, o := range m.observers {
+		ctx = o.RequestStart(ctx, ev)
+	}
+	return ctx
+}
+
+// Response satisfies the Observer interface for the multiObserver type
+func (m *multiObserver) Response(ctx context.Context, ev ResponseEvent) {
+	for _, o := range m.observers {
+		o.Response(ctx, ev)
+	}
+}
+
+// Retry satisfies the Observer interface for the multiObserver type
+func (m *multiObserver) Retry(ctx context.Context, ev RetryEvent) {
+	for _, o := range m.observers {
+		o.Retry(ctx, ev)
+	}
+}
+
+// CacheHit satisfies the Observer interface for the multiObserver type
+func (m *multiObserver) CacheHit(ctx context.Context, ev CacheEvent) {
+	for _, o := range m.observers {
+		o.CacheHit(ctx, ev)
+	}
+}
+
+// WithObserver adds an Observer to the Client that receives events about the API requests. If the
+// option is used multiple times, the events are passed to all Observers in the order they were added.
+func WithObserver(o Observer) Option {
+	if o == nil {
+		return nil
+	}
+	return func(c *Client) {
+		switch existing := c.observer.(type) {
+		case nil:
+			c.observer = o
+		case *multiObserver:
+			existing.observers = append(existing.observers, o)
+		default:
+			c.observer = &multiObserver{observers: []Observer{existing, o}}
+		}
+	}
+}
+
+// requestTrace keeps track of a single API request, including its retries, and reports its
+// progress to the structured logger and the Observer of the Client
+type requestTrace struct {
+	c       *Client
+	ctx     context.Context //nolint:containedctx // The context returned by the Observer for the request
+	attrs   []any
+	ev      RequestEvent
+	start   time.Time
+	retries int
+	waited  time.Duration
+}
+
+// startRequest reports the start of the given HTTP request and returns the requestTrace for it. The
+// returned HTTP request carries the context returned by the Observer and should be used for the
+// API call
+func (c *Client) startRequest(hreq *http.Request) (*requestTrace, *http.Request) {
+	tpl, _, _, _ := endpointTemplate(hreq.URL)
+	rt := &requestTrace{
+		c:     c,
+		ctx:   hreq.Context(),
+		ev:    RequestEvent{Method: hreq.Method, Endpoint: tpl},
+		start: time.Now(),
+	}
+	if c.slog != nil {
+		rt.attrs = c.requestLogAttrs(hreq)
+	}
+	c.logRequestStart(rt.attrs)
+	if c.observer != nil {
+		rt.ctx = c.observer.RequestStart(rt.ctx, rt.ev)
+		hreq = hreq.WithContext(rt.ctx)
+	}
+	return rt, hreq
+}
+
+// prepare attaches the context of the requestTrace to the given HTTP request of a retry
+func (rt *requestTrace) prepare(hreq *http.Request) *http.Request {
+	return hreq.WithContext(rt.ctx)
+}
+
+// retry reports an upcoming retry of the API request after waiting for the given duration
+func (rt *requestTrace) retry(wait time.Duration) {
+	rt.retries++
+	rt.waited += wait
+	rt.c.logRateLimit(rt.attrs, rt.retries, wait)
+	if rt.c.observer != nil {
+		rt.c.observer.Retry(rt.ctx, RetryEvent{RequestEvent: rt.ev, Attempt: rt.retries, Wait: wait})
+	}
+}
+
+// end reports the end of the API request with the given HTTP response and error
+func (rt *requestTrace) end(hr *http.Response, err error) {
+	rt.c.logRequestEnd(rt.attrs, hr, rt.start, rt.retries, rt.waited, err)
+	if rt.c.observer == nil {
+		return
+	}
+	ev := ResponseEvent{
+		RequestEvent: rt.ev,
+		Duration:     time.Since(rt.start),
+		Retries:      rt.retries,
+		Err:          err,
+	}
+	if hr != nil {
+		ev.StatusCode = hr.StatusCode
+	}
+	rt.c.observer.Response(rt.ctx, ev)
+}
+
+// cacheHit reports that a result was served from the given cache of the Client. There is no request
+// context to pass to the Observer, see Observer.CacheHit
+func (c *Client) cacheHit(cache string) {
+	if c.observer != nil {
+		c.observer.CacheHit(context.Background(), CacheEvent{Cache: cache})
+	}
+}
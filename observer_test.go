@@ -0,0 +1,220 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// testObserverKey is the context key the testObserver uses to mark the request context.
+type testObserverKey struct{}
+
+func TestClient_WithObserver(t *testing.T) {
+	email := "toni.tester@domain.tld"
+	t.Run("nil observer is ignored", func(t *testing.T) {
+		hc := New(WithObserver(nil))
+		if hc.observer != nil {
+			t.Errorf("expected observer to be nil, got: %T", hc.observer)
+		}
+	})
+	t.Run("request start and response are observed", func(t *testing.T) {
+		server := httptest.NewServer(newTestFileHandler(t, ServerResponseBreachesAllTruncatedUnverified))
+		defer server.Close()
+		observer := &testObserver{}
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)), WithObserver(observer))
+		if _, _, err := hc.BreachAPI.Breaches(); err != nil {
+			t.Fatalf("failed to get breaches: %s", err)
+		}
+		if len(observer.starts) != 1 {
+			t.Fatalf("expected %d request start, got %d", 1, len(observer.starts))
+		}
+		if observer.starts[0].Endpoint != "/api/v3/breaches" {
+			t.Errorf("expected endpoint to be %q, got %q", "/api/v3/breaches", observer.starts[0].Endpoint)
+		}
+		if len(observer.responses) != 1 {
+			t.Fatalf("expected %d response, got %d", 1, len(observer.responses))
+		}
+		response := observer.responses[0]
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("expected status code to be %d, got %d", http.StatusOK, response.StatusCode)
+		}
+		if response.Err != nil {
+			t.Errorf("expected no error, got %s", response.Err)
+		}
+		if response.Method != http.MethodGet {
+			t.Errorf("expected method to be %q, got %q", http.MethodGet, response.Method)
+		}
+	})
+	t.Run("password range requests are observed", func(t *testing.T) {
+		server := httptest.NewServer(newTestFileHandler(t, ServerResponsePwnedPassInsecure))
+		defer server.Close()
+		observer := &testObserver{}
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)), WithObserver(observer))
+		if _, _, err := hc.PwnedPassAPI.CheckPassword(PwStringInsecure); err != nil {
+			t.Fatalf("failed to check password: %s", err)
+		}
+		if len(observer.responses) != 1 {
+			t.Fatalf("expected %d response, got %d", 1, len(observer.responses))
+		}
+		if observer.responses[0].Endpoint != "/range/{prefix}" {
+			t.Errorf("expected endpoint to be %q, got %q", "/range/{prefix}", observer.responses[0].Endpoint)
+		}
+	})
+	t.Run("failed requests are observed with error", func(t *testing.T) {
+		server := httptest.NewServer(newTestFailureHandler(t, http.StatusInternalServerError))
+		defer server.Close()
+		observer := &testObserver{}
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)), WithObserver(observer))
+		if _, _, err := hc.BreachAPI.Breaches(); err == nil {
+			t.Fatal("expected request to fail")
+		}
+		if len(observer.responses) != 1 {
+			t.Fatalf("expected %d response, got %d", 1, len(observer.responses))
+		}
+		if observer.responses[0].Err == nil {
+			t.Error("expected response to hold an error")
+		}
+		if observer.responses[0].StatusCode != http.StatusInternalServerError {
+			t.Errorf("expected status code to be %d, got %d", http.StatusInternalServerError,
+				observer.responses[0].StatusCode)
+		}
+	})
+	t.Run("retries are observed", func(t *testing.T) {
+		run := 0
+		server := httptest.NewServer(newTestRetryHandler(t, &run, true))
+		defer server.Close()
+		observer := &testObserver{}
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)), WithRateLimitSleep(), WithObserver(observer))
+		if _, _, err := hc.BreachAPI.Breaches(); err != nil {
+			t.Fatalf("failed to get breaches: %s", err)
+		}
+		if len(observer.retries) != 1 {
+			t.Fatalf("expected %d retry, got %d", 1, len(observer.retries))
+		}
+		if observer.retries[0].Attempt != 1 {
+			t.Errorf("expected retry attempt to be %d, got %d", 1, observer.retries[0].Attempt)
+		}
+		if observer.retries[0].Wait == 0 {
+			t.Error("expected retry wait to be set")
+		}
+		if len(observer.responses) != 1 {
+			t.Fatalf("expected %d response, got %d", 1, len(observer.responses))
+		}
+		if observer.responses[0].Retries != 1 {
+			t.Errorf("expected response retries to be %d, got %d", 1, observer.responses[0].Retries)
+		}
+	})
+	t.Run("breach catalogue cache hits are observed", func(t *testing.T) {
+		server := httptest.NewServer(newTestRouteHandler(t, map[string]string{
+			"/api/v3/breaches":                 ServerResponseBreachesAllNonTruncatedUnverified,
+			"/api/v3/breachedaccount/" + email: fmt.Sprintf(ServerResponseBreachAccount, email),
+		}))
+		defer server.Close()
+		observer := &testObserver{}
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)), WithAPIKey(TestAPIKey),
+			WithObserver(observer))
+		for i := 0; i < 2; i++ {
+			if _, _, err := hc.BreachAPI.BreachedAccount(email, WithHydration()); err != nil {
+				t.Fatalf("failed to get breached account: %s", err)
+			}
+		}
+		if len(observer.cacheHits) != 1 {
+			t.Fatalf("expected %d cache hit, got %d", 1, len(observer.cacheHits))
+		}
+		if observer.cacheHits[0].Cache != CacheBreachCatalogue {
+			t.Errorf("expected cache to be %q, got %q", CacheBreachCatalogue, observer.cacheHits[0].Cache)
+		}
+	})
+	t.Run("observer context is attached to the request", func(t *testing.T) {
+		server := httptest.NewServer(newTestFileHandler(t, ServerResponseBreachesAllTruncatedUnverified))
+		defer server.Close()
+		client := &testContextClient{testClient: newTestClient(t, server.URL)}
+		hc := New(WithHTTPClient(client), WithObserver(&testObserver{}))
+		if _, _, err := hc.BreachAPI.Breaches(); err != nil {
+			t.Fatalf("failed to get breaches: %s", err)
+		}
+		if client.value != "observed" {
+			t.Errorf("expected request context to carry the observer value, got %v", client.value)
+		}
+	})
+	t.Run("multiple observers receive all events", func(t *testing.T) {
+		server := httptest.NewServer(newTestFileHandler(t, ServerResponseBreachesAllTruncatedUnverified))
+		defer server.Close()
+		first, second, third := &testObserver{}, &testObserver{}, &testObserver{}
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)), WithObserver(first), WithObserver(second),
+			WithObserver(third))
+		if _, _, err := hc.BreachAPI.Breaches(); err != nil {
+			t.Fatalf("failed to get breaches: %s", err)
+		}
+		for i, observer := range []*testObserver{first, second, third} {
+			if len(observer.starts) != 1 || len(observer.responses) != 1 {
+				t.Errorf("expected observer %d to receive all events", i)
+			}
+		}
+	})
+	t.Run("NopObserver can be used as observer", func(t *testing.T) {
+		server := httptest.NewServer(newTestFileHandler(t, ServerResponseBreachesAllTruncatedUnverified))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)), WithObserver(NopObserver{}))
+		if _, _, err := hc.BreachAPI.Breaches(); err != nil {
+			t.Fatalf("failed to get breaches: %s", err)
+		}
+	})
+}
+
+// testObserver is an Observer that records all events.
+type testObserver struct {
+	mu        sync.Mutex
+	starts    []RequestEvent
+	responses []ResponseEvent
+	retries   []RetryEvent
+	cacheHits []CacheEvent
+}
+
+// RequestStart satisfies the Observer interface for the testObserver type.
+func (o *testObserver) RequestStart(ctx context.Context, ev RequestEvent) context.Context {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.starts = append(o.starts, ev)
+	return context.WithValue(ctx, testObserverKey{}, "observed")
+}
+
+// Response satisfies the Observer interface for the testObserver type.
+func (o *testObserver) Response(_ context.Context, ev ResponseEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.responses = append(o.responses, ev)
+}
+
+// Retry satisfies the Observer interface for the testObserver type.
+func (o *testObserver) Retry(_ context.Context, ev RetryEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.retries = append(o.retries, ev)
+}
+
+// CacheHit satisfies the Observer interface for the testObserver type.
+func (o *testObserver) CacheHit(_ context.Context, ev CacheEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.cacheHits = append(o.cacheHits, ev)
+}
+
+// testContextClient is a testClient that records the testObserver value of the request context.
+type testContextClient struct {
+	*testClient
+	value any
+}
+
+// Do satisfies the HTTPClient interface for the testContextClient type.
+func (c *testContextClient) Do(req *http.Request) (*http.Response, error) {
+	c.value = req.Context().Value(testObserverKey{})
+	return c.testClient.Do(req)
+}
@@ -5,16 +5,13 @@
 package hibp
 
 import (
-	"bufio"
 	"crypto/sha1"
 	"encoding/hex"
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
-	"unicode/utf16"
 
-	"github.com/wneessen/go-hibp/md4"
+	"github.com/wneessen/go-hibp/ntlm"
 )
 
 // PwnedPassAPI is a HIBP Pwned Passwords API client
@@ -33,6 +30,10 @@ type Match struct {
 	// present is an internal indicator. It is set to true if the Match was returned by the HIBP API.
 	// It can be used to make sure if a returned Match was empty or not.
 	present bool
+
+	// padding is an internal indicator. It is set to true if the Match is a padding entry of a
+	// padded API response.
+	padding bool
 }
 
 type HashMode int
@@ -54,6 +55,10 @@ type PwnedPasswordOptions struct {
 	// WithPadding controls if the PwnedPassword API returns with padding or not
 	// See: https://haveibeenpwned.com/API/v3#PwnedPasswordsPadding
 	WithPadding bool
+
+	// KeepPadding controls if the padding entries of a padded response are returned as Match,
+	// marked as padding, instead of being discarded
+	KeepPadding bool
 }
 
 // CheckPassword checks the Pwned Passwords database against a given password string
@@ -63,15 +68,22 @@ type PwnedPasswordOptions struct {
 //
 // Reference: https://haveibeenpwned.com/API/v3#SearchingPwnedPasswordsByRange
 func (p *PwnedPassAPI) CheckPassword(pw string) (Match, *http.Response, error) {
+	return p.CheckPasswordBytes([]byte(pw))
+}
+
+// CheckPasswordBytes checks the Pwned Passwords database against a given password byte slice
+//
+// Other than CheckPassword, this method allows the caller to zero the password buffer once the
+// check is done. The byte slice is neither modified nor retained.
+//
+// This method will automatically decide whether the hash is in SHA-1 or NTLM format based on
+// the Option when the Client was initialized
+func (p *PwnedPassAPI) CheckPasswordBytes(pw []byte) (Match, *http.Response, error) {
 	switch p.hibp.PwnedPassAPIOpts.HashMode {
 	case HashModeSHA1:
-		shaSum := fmt.Sprintf("%x", sha1.Sum([]byte(pw)))
-		return p.CheckSHA1(shaSum)
+		return p.CheckSHA1Sum(sha1.Sum(pw))
 	case HashModeNTLM:
-		d := md4.New()
-		d.Write(stringToUTF16(pw))
-		md4Sum := fmt.Sprintf("%x", d.Sum(nil))
-		return p.CheckNTLM(md4Sum)
+		return p.CheckNTLMSum(ntlm.NTHashBytes(pw))
 	default:
 		return Match{}, nil, ErrUnsupportedHashMode
 	}
@@ -79,46 +91,33 @@ func (p *PwnedPassAPI) CheckPassword(pw string) (Match, *http.Response, error) {
 
 // CheckSHA1 checks the Pwned Passwords database against a given SHA1 checksum of a password string
 func (p *PwnedPassAPI) CheckSHA1(h string) (Match, *http.Response, error) {
-	if len(h) != 40 {
-		return Match{}, nil, ErrSHA1LengthMismatch
+	if err := validateHash(h, 40, ErrSHA1LengthMismatch, ErrSHA1Invalid); err != nil {
+		return Match{}, nil, err
 	}
 
 	p.hibp.PwnedPassAPIOpts.HashMode = HashModeSHA1
-	pwMatches, hr, err := p.ListHashesPrefix(h[:5])
-	if err != nil {
-		return Match{}, hr, err
-	}
+	return p.checkHash(h)
+}
 
-	for i := range pwMatches {
-		match := pwMatches[i]
-		if match.Hash == strings.ToLower(h) {
-			match.present = true
-			return match, hr, nil
-		}
-	}
-	return Match{}, hr, nil
+// CheckSHA1Sum checks the Pwned Passwords database against a given raw SHA1 checksum of a
+// password, as returned by sha1.Sum
+func (p *PwnedPassAPI) CheckSHA1Sum(sum [sha1.Size]byte) (Match, *http.Response, error) {
+	return p.CheckSHA1(hex.EncodeToString(sum[:]))
 }
 
 // CheckNTLM checks the Pwned Passwords database against a given NTLM hash of a password string
 func (p *PwnedPassAPI) CheckNTLM(h string) (Match, *http.Response, error) {
-	if len(h) != 32 {
-		return Match{}, nil, ErrNTLMLengthMismatch
+	if err := validateHash(h, 32, ErrNTLMLengthMismatch, ErrNTLMInvalid); err != nil {
+		return Match{}, nil, err
 	}
 
 	p.hibp.PwnedPassAPIOpts.HashMode = HashModeNTLM
-	pwMatches, hr, err := p.ListHashesPrefix(h[:5])
-	if err != nil {
-		return Match{}, hr, err
-	}
+	return p.checkHash(h)
+}
 
-	for i := range pwMatches {
-		match := pwMatches[i]
-		if match.Hash == strings.ToLower(h) {
-			match.present = true
-			return match, hr, nil
-		}
-	}
-	return Match{}, hr, nil
+// CheckNTLMSum checks the Pwned Passwords database against a given raw NTLM hash of a password
+func (p *PwnedPassAPI) CheckNTLMSum(sum [ntlm.Size]byte) (Match, *http.Response, error) {
+	return p.CheckNTLM(hex.EncodeToString(sum[:]))
 }
 
 // ListHashesPassword checks the Pwned Password API endpoint for all hashes based on a given
@@ -127,58 +126,62 @@ func (p *PwnedPassAPI) CheckNTLM(h string) (Match, *http.Response, error) {
 // This method will automatically decide whether the hash is in SHA-1 or NTLM format based on
 // the Option when the Client was initialized
 //
-// NOTE: If the `WithPwnedPadding` option is set to true, the returned list will be padded and might
-// contain junk data
+// NOTE: If the `WithPwnedPadding` option is set, the padding entries of the padded response are
+// filtered out. Use the `WithPwnedPaddingEntries` option, i. e. set `KeepPadding`, to keep them in
+// the returned list, marked by Match.IsPadding
 //
 // References:
 // - https://haveibeenpwned.com/API/v3#SearchingPwnedPasswordsByRange
 // - https://haveibeenpwned.com/API/v3#PwnedPasswordsPadding
 func (p *PwnedPassAPI) ListHashesPassword(pw string) ([]Match, *http.Response, error) {
-	switch p.hibp.PwnedPassAPIOpts.HashMode {
-	case HashModeSHA1:
-		shaSum := fmt.Sprintf("%x", sha1.Sum([]byte(pw)))
-		return p.ListHashesSHA1(shaSum)
-	case HashModeNTLM:
-		d := md4.New()
-		d.Write(stringToUTF16(pw))
-		md4Sum := fmt.Sprintf("%x", d.Sum(nil))
-		return p.ListHashesNTLM(md4Sum)
-	default:
-		return nil, nil, ErrUnsupportedHashMode
+	return p.ListHashesPasswordBytes([]byte(pw))
+}
+
+// ListHashesPasswordBytes checks the Pwned Password API endpoint for all hashes based on a given
+// password byte slice and returns the a slice of Match as well as the http.Response
+//
+// Other than ListHashesPassword, this method allows the caller to zero the password buffer once
+// the check is done. The byte slice is neither modified nor retained.
+//
+// NOTE: If the `WithPwnedPadding` option is set, the padding entries of the padded response are
+// filtered out. Use the `WithPwnedPaddingEntries` option, i. e. set `KeepPadding`, to keep them in
+// the returned list, marked by Match.IsPadding
+func (p *PwnedPassAPI) ListHashesPasswordBytes(pw []byte) ([]Match, *http.Response, error) {
+	h, err := HashPassword(pw, p.hibp.PwnedPassAPIOpts.HashMode)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.hibp.PwnedPassAPIOpts.HashMode == HashModeNTLM {
+		return p.ListHashesNTLM(h)
 	}
+	return p.ListHashesSHA1(h)
 }
 
 // ListHashesSHA1 checks the Pwned Password API endpoint for all hashes based on a given
 // SHA1 checksum and returns the a slice of Match as well as the http.Response
 //
-// NOTE: If the `WithPwnedPadding` option is set to true, the returned list will be padded and might
-// contain junk data
+// NOTE: If the `WithPwnedPadding` option is set, the padding entries of the padded response are
+// filtered out. Use the `WithPwnedPaddingEntries` option, i. e. set `KeepPadding`, to keep them in
+// the returned list, marked by Match.IsPadding
 func (p *PwnedPassAPI) ListHashesSHA1(h string) ([]Match, *http.Response, error) {
-	if len(h) != 40 {
-		return nil, nil, ErrSHA1LengthMismatch
+	if err := validateHash(h, 40, ErrSHA1LengthMismatch, ErrSHA1Invalid); err != nil {
+		return nil, nil, err
 	}
 	p.hibp.PwnedPassAPIOpts.HashMode = HashModeSHA1
-	dst := make([]byte, hex.DecodedLen(len(h)))
-	if _, err := hex.Decode(dst, []byte(h)); err != nil {
-		return nil, nil, ErrSHA1Invalid
-	}
 	return p.ListHashesPrefix(h[:5])
 }
 
 // ListHashesNTLM checks the Pwned Password API endpoint for all hashes based on a given
 // NTLM hash and returns the a slice of Match as well as the http.Response
 //
-// NOTE: If the `WithPwnedPadding` option is set to true, the returned list will be padded and might
-// contain junk data
+// NOTE: If the `WithPwnedPadding` option is set, the padding entries of the padded response are
+// filtered out. Use the `WithPwnedPaddingEntries` option, i. e. set `KeepPadding`, to keep them in
+// the returned list, marked by Match.IsPadding
 func (p *PwnedPassAPI) ListHashesNTLM(h string) ([]Match, *http.Response, error) {
-	if len(h) != 32 {
-		return nil, nil, ErrNTLMLengthMismatch
+	if err := validateHash(h, 32, ErrNTLMLengthMismatch, ErrNTLMInvalid); err != nil {
+		return nil, nil, err
 	}
 	p.hibp.PwnedPassAPIOpts.HashMode = HashModeNTLM
-	dst := make([]byte, hex.DecodedLen(len(h)))
-	if _, err := hex.Decode(dst, []byte(h)); err != nil {
-		return nil, nil, ErrNTLMInvalid
-	}
 	return p.ListHashesPrefix(h[:5])
 }
 
@@ -188,78 +191,140 @@ func (p *PwnedPassAPI) ListHashesNTLM(h string) ([]Match, *http.Response, error)
 // To decide which HashType is queried for, make sure to set the appropriate HashMode in
 // the PwnedPassAPI struct
 //
-// NOTE: If the `WithPwnedPadding` option is set to true, the returned list will be padded and might
-// contain junk data
+// NOTE: If the `WithPwnedPadding` option is set, the padding entries of the padded response are
+// filtered out. Use the `WithPwnedPaddingEntries` option, i. e. set `KeepPadding`, to keep them in
+// the returned list, marked by Match.IsPadding
 func (p *PwnedPassAPI) ListHashesPrefix(pf string) ([]Match, *http.Response, error) {
-	if len(pf) != 5 {
-		return nil, nil, ErrPrefixLengthMismatch
+	var pm []Match
+	hr, err := p.StreamHashesPrefix(pf, func(m Match) error {
+		pm = append(pm, m)
+		return nil
+	})
+	if err != nil {
+		return nil, hr, err
 	}
 
-	switch p.hibp.PwnedPassAPIOpts.HashMode {
-	case HashModeSHA1:
-		delete(p.ParamMap, "mode")
-	case HashModeNTLM:
-		p.ParamMap["mode"] = "ntlm"
-	default:
-		delete(p.ParamMap, "mode")
+	return pm, hr, nil
+}
+
+// StreamHashesPrefix checks the Pwned Password API endpoint for all hashes based on a given
+// SHA-1 or NTLM hash prefix and calls the given function for each Match, while the response
+// is read. If the function returns an error, reading the response stops and the error is
+// returned. Other than ListHashesPrefix, this method does not hold the whole range in memory.
+//
+// By default, malformed lines of the response are skipped. Use the WithStrictParsing option
+// to fail on malformed lines instead. See RangeScanner for details.
+//
+// To decide which HashType is queried for, make sure to set the appropriate HashMode in
+// the PwnedPassAPI struct
+func (p *PwnedPassAPI) StreamHashesPrefix(pf string, fn func(Match) error, options ...RangeScannerOption,
+) (*http.Response, error) {
+	return p.StreamHashesPrefixMode(p.hibp.PwnedPassAPIOpts.HashMode, pf, fn, options...)
+}
+
+// StreamHashesPrefixMode works like StreamHashesPrefix, but queries for the given HashMode instead
+// of the HashMode of the Client. Other than the CheckSHA1 and CheckNTLM methods, it does not change
+// the HashMode of the Client, so it is safe to use it with a Client that is shared with other
+// callers.
+func (p *PwnedPassAPI) StreamHashesPrefixMode(mode HashMode, pf string, fn func(Match) error,
+	options ...RangeScannerOption,
+) (*http.Response, error) {
+	if err := validateHash(pf, 5, ErrPrefixLengthMismatch, ErrPrefixInvalid); err != nil {
+		return nil, err
+	}
+
+	params := make(map[string]string, len(p.ParamMap)+1)
+	for k, v := range p.ParamMap {
+		params[k] = v
+	}
+	delete(params, "mode")
+	if mode == HashModeNTLM {
+		params["mode"] = "ntlm"
 	}
 	au := fmt.Sprintf("%s/range/%s", PasswdBaseURL, pf)
-	hreq, err := p.hibp.HTTPReq(http.MethodGet, au, p.ParamMap)
+	hreq, err := p.hibp.HTTPReq(http.MethodGet, au, params)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	hr, err := p.hibp.hc.Do(hreq)
+	rt, hreq := p.hibp.startRequest(hreq)
+	hr, err := p.hibp.do(hreq)
 	if err != nil {
-		return nil, hr, err
+		rt.end(hr, err)
+		return hr, err
 	}
 	defer func() {
 		_ = hr.Body.Close()
 	}()
 	if hr.StatusCode != 200 {
-		return nil, hr, fmt.Errorf("HTTP %s: %w", hr.Status, ErrNonPositiveResponse)
+		err = newAPIError(hr)
+		rt.end(hr, err)
+		return hr, err
 	}
 
-	var pm []Match
-	so := bufio.NewScanner(hr.Body)
-	for so.Scan() {
-		hp := strings.SplitN(so.Text(), ":", 2)
-		if len(hp) != 2 {
-			continue
-		}
-		fh := fmt.Sprintf("%s%s", strings.ToLower(pf), strings.ToLower(hp[0]))
-		hc, err := strconv.ParseInt(hp[1], 10, 64)
-		if err != nil {
-			continue
-		}
-		if hc == 0 {
-			continue
-		}
-		pm = append(pm, Match{
-			Hash:    fh,
-			Count:   hc,
-			present: true,
-		})
+	if p.hibp.PwnedPassAPIOpts.KeepPadding {
+		options = append([]RangeScannerOption{WithPaddingEntries()}, options...)
 	}
+	err = ParseRange(hr.Body, pf, fn, options...)
+	rt.end(hr, err)
 
-	if err = so.Err(); err != nil {
-		return nil, hr, err
+	return hr, err
+}
+
+// HashPassword returns the lower case, hex encoded hash of the given password in the given HashMode,
+// as it is used by the Pwned Passwords API
+func HashPassword(pw []byte, mode HashMode) (string, error) {
+	switch mode {
+	case HashModeSHA1:
+		sum := sha1.Sum(pw)
+		return hex.EncodeToString(sum[:]), nil
+	case HashModeNTLM:
+		sum := ntlm.NTHashBytes(pw)
+		return hex.EncodeToString(sum[:]), nil
+	default:
+		return "", ErrUnsupportedHashMode
 	}
+}
 
-	return pm, hr, nil
+// checkHash looks up the given, already validated hash in the range of its prefix and returns the
+// matching entry
+func (p *PwnedPassAPI) checkHash(h string) (Match, *http.Response, error) {
+	h = strings.ToLower(h)
+	var match Match
+	hr, err := p.StreamHashesPrefix(h[:5], func(m Match) error {
+		if !m.padding && m.Hash == h {
+			match = m
+		}
+		return nil
+	})
+	if err != nil {
+		return Match{}, hr, err
+	}
+	return match, hr, nil
 }
 
-// stringToUTF16 converts a given string to a UTF-16 little-endian encoded byte slice
-func stringToUTF16(s string) []byte {
-	e := utf16.Encode([]rune(s))
-	r := make([]byte, len(e)*2)
-	for i := 0; i < len(e); i++ {
-		r[i*2] = byte(e[i])
-		r[i*2+1] = byte(e[i] << 8)
+// validateHash checks that the given hash is a hex string of the given length. It returns the
+// given length error or invalid error otherwise
+func validateHash(h string, length int, errLength, errInvalid error) error {
+	if len(h) != length {
+		return errLength
 	}
-	return r
+	for i := 0; i < len(h); i++ {
+		switch c := h[i]; {
+		case '0' <= c && c <= '9', 'a' <= c && c <= 'f', 'A' <= c && c <= 'F':
+		default:
+			return errInvalid
+		}
+	}
+	return nil
 }
 
 // Present indicates whether the Match object has been returned by the HIBP API.
 func (m Match) Present() bool {
 	return m.present
 }
+
+// IsPadding indicates whether the Match object is a padding entry of a padded API response. Padding
+// entries are only returned if the WithPwnedPaddingEntries option is set.
+func (m Match) IsPadding() bool {
+	return m.padding
+}
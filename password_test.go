@@ -5,11 +5,17 @@
 package hibp
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
+
+	"github.com/wneessen/go-hibp/ntlm"
 )
 
 const (
@@ -132,6 +138,45 @@ func TestPwnedPassAPI_CheckPassword(t *testing.T) {
 	})
 }
 
+func TestPwnedPassAPI_CheckPasswordBytes(t *testing.T) {
+	t.Run("CheckPasswordBytes with SHA-1 hashes finds leaked password", func(t *testing.T) {
+		server := httptest.NewServer(newTestFileHandler(t, ServerResponsePwnedPassInsecure))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)))
+		pw := []byte(PwStringInsecure)
+		m, _, err := hc.PwnedPassAPI.CheckPasswordBytes(pw)
+		if err != nil {
+			t.Fatalf("CheckPasswordBytes was not supposed to fail, but did: %s", err)
+		}
+		if !m.Present() || m.Hash != PwHashInsecure {
+			t.Errorf("CheckPasswordBytes was supposed to find hash %s, but got: %+v", PwHashInsecure, m)
+		}
+		if string(pw) != PwStringInsecure {
+			t.Errorf("CheckPasswordBytes was not supposed to modify the password buffer, but got: %q", pw)
+		}
+	})
+	t.Run("CheckPasswordBytes with NTLM hashes finds leaked password", func(t *testing.T) {
+		server := httptest.NewServer(newTestFileHandler(t, ServerResponsePwnedPassInsecureNTLM))
+		defer server.Close()
+		hc := New(WithPwnedNTLMHash(), WithHTTPClient(newTestClient(t, server.URL)))
+		m, _, err := hc.PwnedPassAPI.CheckPasswordBytes([]byte(PwStringInsecure))
+		if err != nil {
+			t.Fatalf("CheckPasswordBytes was not supposed to fail, but did: %s", err)
+		}
+		if !m.Present() || m.Hash != PwHashInsecureNTLM {
+			t.Errorf("CheckPasswordBytes was supposed to find hash %s, but got: %+v", PwHashInsecureNTLM, m)
+		}
+	})
+	t.Run("CheckPasswordBytes fails with wrong hash mode", func(t *testing.T) {
+		hc := New(WithHTTPClient(newTestClient(t, "")))
+		hc.PwnedPassAPIOpts.HashMode = 99
+		_, _, err := hc.PwnedPassAPI.CheckPasswordBytes([]byte(PwStringInsecure))
+		if !errors.Is(err, ErrUnsupportedHashMode) {
+			t.Errorf("CheckPasswordBytes wrong error, expected: %s, got: %s", ErrUnsupportedHashMode, err)
+		}
+	})
+}
+
 func TestPwnedPassAPI_CheckSHA1(t *testing.T) {
 	t.Run("CheckSHA1 with invalid length hash should fail", func(t *testing.T) {
 		hc := New()
@@ -140,6 +185,37 @@ func TestPwnedPassAPI_CheckSHA1(t *testing.T) {
 			t.Errorf("CheckSHA1 with invalid length hash should fail")
 		}
 	})
+	t.Run("CheckSHA1 with invalid hash should fail", func(t *testing.T) {
+		hc := New()
+		_, _, err := hc.PwnedPassAPI.CheckSHA1(PwHashInsecure[:39] + "h")
+		if !errors.Is(err, ErrSHA1Invalid) {
+			t.Errorf("CheckSHA1 wrong error, expected: %s, got: %s", ErrSHA1Invalid, err)
+		}
+	})
+	t.Run("CheckSHA1 accepts upper case hashes", func(t *testing.T) {
+		server := httptest.NewServer(newTestFileHandler(t, ServerResponsePwnedPassInsecure))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)))
+		m, _, err := hc.PwnedPassAPI.CheckSHA1(strings.ToUpper(PwHashInsecure))
+		if err != nil {
+			t.Fatalf("CheckSHA1 was not supposed to fail, but did: %s", err)
+		}
+		if !m.Present() {
+			t.Error("CheckSHA1 was supposed to find the upper case hash")
+		}
+	})
+	t.Run("CheckSHA1Sum finds leaked password", func(t *testing.T) {
+		server := httptest.NewServer(newTestFileHandler(t, ServerResponsePwnedPassInsecure))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)))
+		m, _, err := hc.PwnedPassAPI.CheckSHA1Sum(sha1.Sum([]byte(PwStringInsecure)))
+		if err != nil {
+			t.Fatalf("CheckSHA1Sum was not supposed to fail, but did: %s", err)
+		}
+		if !m.Present() || m.Hash != PwHashInsecure {
+			t.Errorf("CheckSHA1Sum was supposed to find hash %s, but got: %+v", PwHashInsecure, m)
+		}
+	})
 	t.Run("CheckSHA1 with invalid URL should fail", func(t *testing.T) {
 		hc := New(WithHTTPClient(newTestClient(t, "")))
 		_, _, err := hc.PwnedPassAPI.CheckSHA1(PwHashInsecure)
@@ -157,6 +233,29 @@ func TestPwnedPassAPI_CheckNTLM(t *testing.T) {
 			t.Errorf("CheckNTLM with invalid length hash should fail")
 		}
 	})
+	t.Run("CheckNTLM with invalid hash should fail", func(t *testing.T) {
+		hc := New()
+		_, _, err := hc.PwnedPassAPI.CheckNTLM(PwHashInsecureNTLM[:31] + "h")
+		if !errors.Is(err, ErrNTLMInvalid) {
+			t.Errorf("CheckNTLM wrong error, expected: %s, got: %s", ErrNTLMInvalid, err)
+		}
+	})
+	t.Run("CheckNTLMSum finds leaked password", func(t *testing.T) {
+		server := httptest.NewServer(newTestFileHandler(t, ServerResponsePwnedPassInsecureNTLM))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)))
+		var sum [ntlm.Size]byte
+		if _, err := hex.Decode(sum[:], []byte(PwHashInsecureNTLM)); err != nil {
+			t.Fatalf("failed to decode NTLM hash: %s", err)
+		}
+		m, _, err := hc.PwnedPassAPI.CheckNTLMSum(sum)
+		if err != nil {
+			t.Fatalf("CheckNTLMSum was not supposed to fail, but did: %s", err)
+		}
+		if !m.Present() || m.Hash != PwHashInsecureNTLM {
+			t.Errorf("CheckNTLMSum was supposed to find hash %s, but got: %+v", PwHashInsecureNTLM, m)
+		}
+	})
 	t.Run("CheckNTLM with invalid URL should fail", func(t *testing.T) {
 		hc := New(WithHTTPClient(newTestClient(t, "")))
 		_, _, err := hc.PwnedPassAPI.CheckNTLM(PwHashInsecureNTLM)
@@ -203,6 +302,50 @@ func TestPwnedPassAPI_ListHashesPassword(t *testing.T) {
 			t.Errorf("ListHashesPassword was supposed to return 987 results, but got %d", len(m))
 		}
 	})
+	t.Run("ListHashesPassword in SHA-1 mode keeps padding entries if requested", func(t *testing.T) {
+		server := httptest.NewServer(newTestFileHandler(t, ServerResponsePwnedPassInsecurePadding))
+		defer server.Close()
+		hc := New(WithPwnedPaddingEntries(), WithHTTPClient(newTestClient(t, server.URL)))
+		m, _, err := hc.PwnedPassAPI.ListHashesPassword("test")
+		if err != nil {
+			t.Fatalf("ListHashesPassword was not supposed to fail, but did: %s", err)
+		}
+		if len(m) != 1068 {
+			t.Errorf("ListHashesPassword was supposed to return 1068 results, but got %d", len(m))
+		}
+		padding := 0
+		for _, match := range m {
+			if match.IsPadding() {
+				padding++
+				if match.Count != 0 {
+					t.Errorf("padding entry was supposed to have a count of 0, but got %d", match.Count)
+				}
+			}
+		}
+		if padding != 81 {
+			t.Errorf("ListHashesPassword was supposed to return 81 padding entries, but got %d", padding)
+		}
+	})
+	t.Run("StreamHashesPrefix reports range statistics for padded responses", func(t *testing.T) {
+		server := httptest.NewServer(newTestFileHandler(t, ServerResponsePwnedPassInsecurePadding))
+		defer server.Close()
+		hc := New(WithPwnedPadding(), WithHTTPClient(newTestClient(t, server.URL)))
+		var stats RangeStats
+		_, err := hc.PwnedPassAPI.StreamHashesPrefix(PwHashInsecure[:5], func(Match) error { return nil },
+			WithRangeStats(&stats))
+		if err != nil {
+			t.Fatalf("StreamHashesPrefix was not supposed to fail, but did: %s", err)
+		}
+		info, err := os.Stat(ServerResponsePwnedPassInsecurePadding)
+		if err != nil {
+			t.Fatalf("failed to stat test data: %s", err)
+		}
+		// The test handler terminates the response with an additional newline
+		expected := RangeStats{Real: 987, Padding: 81, Size: info.Size() + 1}
+		if stats != expected {
+			t.Errorf("StreamHashesPrefix was supposed to report %+v, but got %+v", expected, stats)
+		}
+	})
 	t.Run("ListHashesPassword in SHA-1 mode succeeds on non-leaked passwords and padding enabled", func(t *testing.T) {
 		server := httptest.NewServer(newTestFileHandler(t, ServerResponsePwnedPassSecurePadding))
 		defer server.Close()
@@ -295,6 +438,13 @@ func TestPwnedPassAPI_ListHashesPrefix(t *testing.T) {
 			t.Errorf("ListHashesPrefix wrong error, expected: %s, got: %s", ErrPrefixLengthMismatch, err)
 		}
 	})
+	t.Run("ListHashesPrefix fails with invalid prefix", func(t *testing.T) {
+		hc := New()
+		_, _, err := hc.PwnedPassAPI.ListHashesPrefix("a94ah")
+		if !errors.Is(err, ErrPrefixInvalid) {
+			t.Errorf("ListHashesPrefix wrong error, expected: %s, got: %s", ErrPrefixInvalid, err)
+		}
+	})
 	t.Run("ListHashesPrefix with unsupported hash mode should fallback to default", func(t *testing.T) {
 		server := httptest.NewServer(newTestFileHandler(t, ServerResponsePwnedPassInsecure))
 		defer server.Close()
@@ -328,6 +478,33 @@ func TestPwnedPassAPI_ListHashesPrefix(t *testing.T) {
 	})
 }
 
+func TestHashPassword(t *testing.T) {
+	tests := []struct {
+		name string
+		mode HashMode
+		want string
+	}{
+		{"SHA-1", HashModeSHA1, PwHashInsecure},
+		{"NTLM", HashModeNTLM, PwHashInsecureNTLM},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := HashPassword([]byte(PwStringInsecure), tt.mode)
+			if err != nil {
+				t.Fatalf("HashPassword failed: %s", err)
+			}
+			if h != tt.want {
+				t.Errorf("HashPassword failed, expected: %s, got: %s", tt.want, h)
+			}
+		})
+	}
+	t.Run("unsupported hash mode", func(t *testing.T) {
+		if _, err := HashPassword([]byte(PwStringInsecure), 99); !errors.Is(err, ErrUnsupportedHashMode) {
+			t.Errorf("HashPassword wrong error, expected: %s, got: %s", ErrUnsupportedHashMode, err)
+		}
+	})
+}
+
 // ExamplePwnedPassAPI_CheckPassword is a code example to show how to check a given password
 // against the HIBP passwords API
 func ExamplePwnedPassAPI_CheckPassword() {
@@ -5,12 +5,91 @@
 package hibp
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"sync"
 	"time"
 )
 
+var (
+	// ErrPasteOptOut is returned if the URL of a Paste is requested whose owner opted out of the
+	// display of the paste, or if an opt-out source is registered
+	ErrPasteOptOut = errors.New("paste source is an opt-out")
+
+	// ErrPasteSourceUnknown is returned if the URL of a Paste is requested for a source that is not
+	// registered with RegisterPasteSource
+	ErrPasteSourceUnknown = errors.New("unknown paste source")
+
+	// ErrPasteURLInvalid is returned if the ID of a Paste can not be resolved to a valid URL
+	ErrPasteURLInvalid = errors.New("invalid paste URL")
+)
+
+// PasteSource represents the paste service a Paste was retrieved from
+type PasteSource string
+
+const (
+	// PasteSourcePastebin represents pastes of pastebin.com
+	PasteSourcePastebin PasteSource = "Pastebin"
+	// PasteSourcePastie represents pastes of pastie.org
+	PasteSourcePastie PasteSource = "Pastie"
+	// PasteSourceSlexy represents pastes of slexy.org
+	PasteSourceSlexy PasteSource = "Slexy"
+	// PasteSourceGhostbin represents pastes of ghostbin.com
+	PasteSourceGhostbin PasteSource = "Ghostbin"
+	// PasteSourceQuickLeak represents pastes of quickleak.ir
+	PasteSourceQuickLeak PasteSource = "QuickLeak"
+	// PasteSourceJustPaste represents pastes of justpaste.it
+	PasteSourceJustPaste PasteSource = "JustPaste"
+	// PasteSourceAdHocURL represents pastes of other sites, whose ID is the URL of the paste
+	PasteSourceAdHocURL PasteSource = "AdHocUrl"
+	// PasteSourceOptOut is the source of a Paste whose owner opted out of the display of the paste
+	PasteSourceOptOut PasteSource = "OptOut"
+	// PasteSourcePermanentOptOut is the source of a Paste whose owner permanently opted out of the
+	// display of the paste
+	PasteSourcePermanentOptOut PasteSource = "PermanentOptOut"
+)
+
+// IsOptOut indicates whether the PasteSource is one of the opt-out sources, which do not refer to
+// an actual paste service
+func (s PasteSource) IsOptOut() bool {
+	return s == PasteSourceOptOut || s == PasteSourcePermanentOptOut
+}
+
+// PasteSortKey represents the attribute of a Paste the pastes of the PastedAccount method are
+// sorted by
+type PasteSortKey int
+
+const (
+	// PasteSortNone keeps the pastes in the order returned by the API
+	PasteSortNone PasteSortKey = iota
+	// PasteSortDate sorts the pastes by their Date. Pastes without a Date are sorted last
+	PasteSortDate
+	// PasteSortEmailCount sorts the pastes by their EmailCount
+	PasteSortEmailCount
+)
+
+// PasteURLFunc resolves the ID of a Paste to the URL of the paste at its source service
+type PasteURLFunc func(id string) (string, error)
+
+var (
+	// pasteSourcesMu protects the pasteSources registry
+	pasteSourcesMu sync.RWMutex
+
+	// pasteSources is the registry of PasteURLFunc, keyed by the Source of a Paste
+	pasteSources = map[PasteSource]PasteURLFunc{
+		PasteSourcePastebin:  PastePathURL("https://pastebin.com/"),
+		PasteSourcePastie:    PastePathURL("http://pastie.org/pastes/"),
+		PasteSourceSlexy:     PastePathURL("https://slexy.org/view/"),
+		PasteSourceGhostbin:  PastePathURL("https://ghostbin.com/paste/"),
+		PasteSourceQuickLeak: PastePathURL("http://www.quickleak.ir/"),
+		PasteSourceJustPaste: PastePathURL("https://justpaste.it/"),
+		PasteSourceAdHocURL:  adHocPasteURL,
+	}
+)
+
 // PasteAPI is a HIBP pastes API client
 type PasteAPI struct {
 	hibp *Client // References back to the parent HIBP client
@@ -20,10 +99,10 @@ type PasteAPI struct {
 type Paste struct {
 	// Source is the paste service the record was retrieved from. Current values are: Pastebin,
 	// Pastie, Slexy, Ghostbin, QuickLeak, JustPaste, AdHocUrl, PermanentOptOut, OptOut
-	Source string `json:"Source"`
+	Source PasteSource `json:"Source"`
 
 	// ID of the paste as it was given at the source service. Combined with the "Source" attribute, this
-	// can be used to resolve the URL of the paste
+	// is used by the URL method to resolve the URL of the paste
 	ID string `json:"ID"`
 
 	// Title of the paste as observed on the source site. This may be null and if so will be omitted from
@@ -43,36 +122,199 @@ type Paste struct {
 	present bool
 }
 
-// PastedAccount returns a single breached site based on its name
-// This API is authenticated and requires a valid API key.
+// pasteOpts holds the options for a single request to the pastes API
+type pasteOpts struct {
+	sources    []PasteSource // Only keep pastes of these sources
+	from, to   time.Time     // Only keep pastes posted in this date range
+	sortKey    PasteSortKey  // Sort the pastes by this attribute
+	descending bool          // Sort the pastes in descending order
+}
+
+// PasteOption is an additional option the can be set for a request to the pastes API
+type PasteOption func(*pasteOpts)
+
+// WithPasteSources only keeps the pastes of the given sources in the result of the PastedAccount
+// method. Without sources, the option is ignored
+func WithPasteSources(sources ...PasteSource) PasteOption {
+	return func(o *pasteOpts) {
+		if len(sources) > 0 {
+			o.sources = sources
+		}
+	}
+}
+
+// WithPasteDateRange only keeps the pastes that were posted between from and to, inclusively, in
+// the result of the PastedAccount method. A zero from or to leaves the range open on that end.
+// Pastes without a Date are removed if a range is set
+func WithPasteDateRange(from, to time.Time) PasteOption {
+	return func(o *pasteOpts) {
+		o.from = from
+		o.to = to
+	}
+}
+
+// WithPasteSort sorts the result of the PastedAccount method by the given PasteSortKey, in ascending
+// or descending order. Pastes with equal values keep the order returned by the API
+func WithPasteSort(key PasteSortKey, descending bool) PasteOption {
+	return func(o *pasteOpts) {
+		o.sortKey = key
+		o.descending = descending
+	}
+}
+
+// PastedAccount returns all pastes an account has been found in
+// This API is authenticated and requires a valid API key. If the account has not been found in any
+// paste, an empty list is returned without error. The account is normalized with NormalizeAccount
+// and path escaped for the request.
+//
+// The pastes can be filtered and sorted with the WithPasteSources, WithPasteDateRange and
+// WithPasteSort options.
 //
 // Reference: https://haveibeenpwned.com/API/v3#PastesForAccount
-func (p *PasteAPI) PastedAccount(a string) ([]Paste, *http.Response, error) {
-	if a == "" {
-		return nil, nil, ErrNoAccountID
+func (p *PasteAPI) PastedAccount(a string, options ...PasteOption) ([]Paste, *http.Response, error) {
+	if err := requiresAPIKey(p.hibp); err != nil {
+		return nil, nil, err
 	}
-
-	au := fmt.Sprintf("%s/pasteaccount/%s", BaseURL, a)
-	hb, hr, err := p.hibp.HTTPResBody(http.MethodGet, au, nil)
+	a, err := NormalizeAccount(a)
 	if err != nil {
-		if hr != nil && hr.StatusCode == http.StatusNotFound {
-			return nil, hr, nil
-		}
-		return nil, hr, err
+		return nil, nil, err
 	}
 
 	var pd []Paste
-	if err = json.Unmarshal(hb, &pd); err != nil {
+	au := fmt.Sprintf("%s/pasteaccount/%s", BaseURL, escapeAccount(a))
+	hr, err := p.hibp.getJSON(apiRequest{url: au, auth: true, notFound: true}, &pd)
+	if err != nil {
 		return nil, hr, err
 	}
 	for i := range pd {
 		pd[i].present = true
 	}
 
-	return pd, hr, nil
+	var opts pasteOpts
+	for _, opt := range options {
+		if opt == nil {
+			continue
+		}
+		opt(&opts)
+	}
+	return opts.apply(pd), hr, nil
+}
+
+// apply filters and sorts the given pastes according to the pasteOpts
+func (o pasteOpts) apply(pastes []Paste) []Paste {
+	if len(o.sources) > 0 || !o.from.IsZero() || !o.to.IsZero() {
+		filtered := make([]Paste, 0, len(pastes))
+		for _, paste := range pastes {
+			if o.keep(paste) {
+				filtered = append(filtered, paste)
+			}
+		}
+		pastes = filtered
+	}
+
+	switch o.sortKey {
+	case PasteSortDate:
+		sort.SliceStable(pastes, func(i, j int) bool {
+			a, b := pastes[i].Date, pastes[j].Date
+			if a.IsZero() || b.IsZero() {
+				return !a.IsZero() && b.IsZero()
+			}
+			if o.descending {
+				return a.After(b)
+			}
+			return a.Before(b)
+		})
+	case PasteSortEmailCount:
+		sort.SliceStable(pastes, func(i, j int) bool {
+			if o.descending {
+				return pastes[i].EmailCount > pastes[j].EmailCount
+			}
+			return pastes[i].EmailCount < pastes[j].EmailCount
+		})
+	}
+	return pastes
+}
+
+// keep checks if the given Paste matches the source and date filters of the pasteOpts
+func (o pasteOpts) keep(paste Paste) bool {
+	if len(o.sources) > 0 {
+		found := false
+		for _, source := range o.sources {
+			if paste.Source == source {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if o.from.IsZero() && o.to.IsZero() {
+		return true
+	}
+	if paste.Date.IsZero() {
+		return false
+	}
+	if !o.from.IsZero() && paste.Date.Before(o.from) {
+		return false
+	}
+	return o.to.IsZero() || !paste.Date.After(o.to)
 }
 
 // Present indicates whether the Paste object has been returned by the HIBP API.
 func (p Paste) Present() bool {
 	return p.present
 }
+
+// URL resolves the URL of the Paste from its Source and ID with the PasteURLFunc registered for
+// the Source. If the owner of the paste opted out, ErrPasteOptOut is returned. ErrPasteSourceUnknown
+// is returned for sources that are not registered
+func (p Paste) URL() (string, error) {
+	if p.Source.IsOptOut() {
+		return "", ErrPasteOptOut
+	}
+	pasteSourcesMu.RLock()
+	resolve, ok := pasteSources[p.Source]
+	pasteSourcesMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrPasteSourceUnknown, p.Source)
+	}
+	if p.ID == "" {
+		return "", fmt.Errorf("%w: empty paste ID", ErrPasteURLInvalid)
+	}
+	return resolve(p.ID)
+}
+
+// RegisterPasteSource registers the given PasteURLFunc for the given Source of a Paste. An already
+// registered source is replaced. A nil PasteURLFunc removes the source from the registry. The
+// opt-out sources can not be registered
+func RegisterPasteSource(source PasteSource, resolve PasteURLFunc) error {
+	if source.IsOptOut() {
+		return ErrPasteOptOut
+	}
+	pasteSourcesMu.Lock()
+	defer pasteSourcesMu.Unlock()
+	if resolve == nil {
+		delete(pasteSources, source)
+		return nil
+	}
+	pasteSources[source] = resolve
+	return nil
+}
+
+// PastePathURL returns a PasteURLFunc that appends the path escaped ID of a Paste to the given base
+// URL, i. e. "https://pastebin.com/" resolves to "https://pastebin.com/<ID>"
+func PastePathURL(base string) PasteURLFunc {
+	return func(id string) (string, error) {
+		return base + url.PathEscape(id), nil
+	}
+}
+
+// adHocPasteURL is the PasteURLFunc for the AdHocUrl source, whose ID is the URL of the paste
+func adHocPasteURL(id string) (string, error) {
+	u, err := url.Parse(id)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%w: %q", ErrPasteURLInvalid, id)
+	}
+	return u.String(), nil
+}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 const (
@@ -134,3 +135,131 @@ func ExamplePasteAPI_pastedAccount() {
 		fmt.Printf("Your account was part of the %q paste\n", p.Title)
 	}
 }
+
+func TestPaste_URL(t *testing.T) {
+	tests := []struct {
+		name    string
+		paste   Paste
+		want    string
+		wantErr error
+	}{
+		{"Pastebin", Paste{Source: PasteSourcePastebin, ID: "X5VHhh4q"}, "https://pastebin.com/X5VHhh4q", nil},
+		{"Pastie", Paste{Source: "Pastie", ID: "1234"}, "http://pastie.org/pastes/1234", nil},
+		{"Slexy", Paste{Source: "Slexy", ID: "abc"}, "https://slexy.org/view/abc", nil},
+		{"Ghostbin", Paste{Source: "Ghostbin", ID: "abc"}, "https://ghostbin.com/paste/abc", nil},
+		{"QuickLeak", Paste{Source: "QuickLeak", ID: "abc"}, "http://www.quickleak.ir/abc", nil},
+		{"JustPaste", Paste{Source: "JustPaste", ID: "abc"}, "https://justpaste.it/abc", nil},
+		{"ID is path escaped", Paste{Source: "Pastebin", ID: "../a b"}, "https://pastebin.com/..%2Fa%20b", nil},
+		{
+			"AdHocUrl", Paste{Source: "AdHocUrl", ID: "http://siph0n.in/exploits.php?id=4560"},
+			"http://siph0n.in/exploits.php?id=4560", nil,
+		},
+		{"AdHocUrl with invalid URL", Paste{Source: "AdHocUrl", ID: "javascript:alert(1)"}, "", ErrPasteURLInvalid},
+		{"OptOut", Paste{Source: "OptOut", ID: "abc"}, "", ErrPasteOptOut},
+		{"PermanentOptOut", Paste{Source: "PermanentOptOut"}, "", ErrPasteOptOut},
+		{"unknown source", Paste{Source: "Hastebin", ID: "abc"}, "", ErrPasteSourceUnknown},
+		{"empty ID", Paste{Source: "Pastebin"}, "", ErrPasteURLInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.paste.URL()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error to be %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected URL to be %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRegisterPasteSource(t *testing.T) {
+	paste := Paste{Source: "Hastebin", ID: "abc"}
+	if err := RegisterPasteSource("Hastebin", PastePathURL("https://hastebin.com/")); err != nil {
+		t.Fatalf("failed to register paste source: %s", err)
+	}
+	got, err := paste.URL()
+	if err != nil {
+		t.Fatalf("failed to resolve paste URL: %s", err)
+	}
+	if got != "https://hastebin.com/abc" {
+		t.Errorf("expected URL to be %q, got %q", "https://hastebin.com/abc", got)
+	}
+	if err = RegisterPasteSource("Hastebin", nil); err != nil {
+		t.Fatalf("failed to remove paste source: %s", err)
+	}
+	if _, err = paste.URL(); !errors.Is(err, ErrPasteSourceUnknown) {
+		t.Errorf("expected error to be %s, got %v", ErrPasteSourceUnknown, err)
+	}
+	if err = RegisterPasteSource(PasteSourceOptOut, PastePathURL("https://example.com/")); !errors.Is(err, ErrPasteOptOut) {
+		t.Errorf("expected error to be %s, got %v", ErrPasteOptOut, err)
+	}
+}
+
+func TestPasteSource_IsOptOut(t *testing.T) {
+	for _, source := range []PasteSource{PasteSourceOptOut, PasteSourcePermanentOptOut} {
+		if !source.IsOptOut() {
+			t.Errorf("expected %s to be an opt-out source", source)
+		}
+	}
+	for _, source := range []PasteSource{PasteSourcePastebin, PasteSourceAdHocURL, "optout", ""} {
+		if source.IsOptOut() {
+			t.Errorf("expected %q not to be an opt-out source", source)
+		}
+	}
+}
+
+func TestPasteAPI_PastedAccount_options(t *testing.T) {
+	response := `[
+{"Source":"Pastebin","Id":"a","Date":"2014-11-28T06:11:00Z","EmailCount":245},
+{"Source":"OptOut","Id":"b","Date":"2016-01-01T00:00:00Z","EmailCount":10},
+{"Source":"AdHocUrl","Id":"c","Date":null,"EmailCount":1000},
+{"Source":"Pastie","Id":"d","Date":"2012-05-01T12:00:00Z","EmailCount":10}
+]`
+	server := httptest.NewServer(newTestStringHandler(t, response))
+	defer server.Close()
+	hc := New(WithHTTPClient(newTestClient(t, server.URL)), WithAPIKey(TestAPIKey))
+	date := func(s string) time.Time {
+		d, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			t.Fatalf("failed to parse date: %s", err)
+		}
+		return d
+	}
+	tests := []struct {
+		name    string
+		options []PasteOption
+		want    string
+	}{
+		{"without options", nil, "abcd"},
+		{"filter by source", []PasteOption{WithPasteSources(PasteSourcePastebin, PasteSourcePastie)}, "ad"},
+		{"empty source filter is ignored", []PasteOption{WithPasteSources()}, "abcd"},
+		{"filter by date range", []PasteOption{WithPasteDateRange(date("2013-01-01"), date("2016-01-01"))}, "ab"},
+		{"filter by open date range", []PasteOption{WithPasteDateRange(time.Time{}, date("2015-01-01"))}, "ad"},
+		{"sort by date", []PasteOption{WithPasteSort(PasteSortDate, false)}, "dabc"},
+		{"sort by date descending", []PasteOption{WithPasteSort(PasteSortDate, true)}, "badc"},
+		{"sort by email count", []PasteOption{WithPasteSort(PasteSortEmailCount, false)}, "bdac"},
+		{"sort by email count descending", []PasteOption{WithPasteSort(PasteSortEmailCount, true)}, "cabd"},
+		{
+			"filter and sort", []PasteOption{
+				WithPasteSources(PasteSourcePastebin, PasteSourcePastie, PasteSourceAdHocURL),
+				WithPasteSort(PasteSortEmailCount, true), nil,
+			}, "cad",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pastes, _, err := hc.PasteAPI.PastedAccount("account-exists@hibp-integration-tests.com", tt.options...)
+			if err != nil {
+				t.Fatalf("failed to get pasted account details: %s", err)
+			}
+			var got string
+			for _, paste := range pastes {
+				got += paste.ID
+			}
+			if got != tt.want {
+				t.Errorf("expected pastes %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrMalformedRangeLine is returned by a strict RangeScanner if a line of the range data does
+// not represent a valid hash suffix and count pair
+var ErrMalformedRangeLine = errors.New("malformed range line")
+
+// RangeLineError describes a malformed line of the range data. It wraps ErrMalformedRangeLine
+type RangeLineError struct {
+	// Line is the number of the malformed line, starting at 1
+	Line int
+
+	// Text is the content of the malformed line
+	Text string
+}
+
+// Error satisfies the error interface for the RangeLineError type
+func (e *RangeLineError) Error() string {
+	return fmt.Sprintf("%s %d: %q", ErrMalformedRangeLine, e.Line, e.Text)
+}
+
+// Unwrap returns ErrMalformedRangeLine, so that errors.Is can be used on a RangeLineError
+func (e *RangeLineError) Unwrap() error {
+	return ErrMalformedRangeLine
+}
+
+// RangeStats holds statistics about the range data read by a RangeScanner. It can be used to
+// confirm that the range API applied padding to a response
+type RangeStats struct {
+	// Real is the number of entries with a count greater than 0
+	Real int
+
+	// Padding is the number of padding entries with a count of 0
+	Padding int
+
+	// Malformed is the number of malformed lines
+	Malformed int
+
+	// Size is the raw size of the range data in bytes
+	Size int64
+}
+
+// RangeScanner reads the response of the Pwned Passwords range API, or an offline dump file in the
+// same format, and yields the Match entries one at a time. Each line of the range data consists of a
+// hash suffix and the count, separated by a colon. The hash prefix given to the RangeScanner is
+// prepended to the suffix of each line. For dump files with complete hashes, an empty prefix can
+// be used.
+//
+// By default, malformed lines are skipped and counted. With the WithStrictParsing option, the
+// RangeScanner stops at the first malformed line and returns a RangeLineError. Lines with a count
+// of 0, which are returned by the range API in padding mode, are skipped, unless the
+// WithPaddingEntries option is set. In this case they are yielded as Match marked as padding.
+//
+// The usage of a RangeScanner is similar to a bufio.Scanner:
+//
+//	rs := hibp.NewRangeScanner(r, "a94a8")
+//	for rs.Scan() {
+//		m := rs.Match()
+//	}
+//	if err := rs.Err(); err != nil {
+//		// handle error
+//	}
+type RangeScanner struct {
+	so          *bufio.Scanner
+	cr          *countingReader
+	prefix      string
+	strict      bool
+	keepPadding bool
+	match       Match
+	err         error
+	line        int
+	stats       RangeStats
+	statsOut    *RangeStats
+}
+
+// RangeScannerOption is an additional option that can be set for the RangeScanner
+type RangeScannerOption func(*RangeScanner)
+
+// WithStrictParsing lets the RangeScanner fail with a RangeLineError on the first malformed line,
+// instead of skipping it
+func WithStrictParsing() RangeScannerOption {
+	return func(rs *RangeScanner) {
+		rs.strict = true
+	}
+}
+
+// WithPaddingEntries lets the RangeScanner yield the padding entries with a count of 0 as Match,
+// marked as padding, instead of skipping them
+func WithPaddingEntries() RangeScannerOption {
+	return func(rs *RangeScanner) {
+		rs.keepPadding = true
+	}
+}
+
+// WithRangeStats stores the statistics of the range data in the given RangeStats, while it is read.
+// This is useful in combination with ParseRange or the PwnedPassAPI.StreamHashesPrefix method, which
+// do not expose the RangeScanner
+func WithRangeStats(stats *RangeStats) RangeScannerOption {
+	return func(rs *RangeScanner) {
+		rs.statsOut = stats
+	}
+}
+
+// NewRangeScanner returns a new RangeScanner that reads the range data from the given io.Reader
+// and prepends the given hash prefix to the hash suffix of each line
+func NewRangeScanner(r io.Reader, prefix string, options ...RangeScannerOption) *RangeScanner {
+	cr := &countingReader{r: r}
+	rs := &RangeScanner{
+		so:     bufio.NewScanner(cr),
+		cr:     cr,
+		prefix: strings.ToLower(prefix),
+	}
+	for _, opt := range options {
+		if opt == nil {
+			continue
+		}
+		opt(rs)
+	}
+	return rs
+}
+
+// Scan advances the RangeScanner to the next Match, which will then be available through the Match
+// method. It returns false when the scan stops, either by reaching the end of the input or an error.
+// After Scan returns false, the Err method will return any error that occurred during scanning
+func (rs *RangeScanner) Scan() bool {
+	defer rs.updateStats()
+	if rs.err != nil {
+		return false
+	}
+	for rs.so.Scan() {
+		rs.line++
+		text := rs.so.Text()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		m, ok := rs.parseLine(text)
+		if !ok {
+			rs.stats.Malformed++
+			if rs.strict {
+				rs.err = &RangeLineError{Line: rs.line, Text: text}
+				return false
+			}
+			continue
+		}
+		if m.Count == 0 {
+			rs.stats.Padding++
+			if !rs.keepPadding {
+				continue
+			}
+			m.padding = true
+		} else {
+			rs.stats.Real++
+		}
+		rs.match = m
+		return true
+	}
+	rs.err = rs.so.Err()
+	return false
+}
+
+// updateStats updates the raw size in the statistics of the RangeScanner and copies them to
+// the RangeStats of the WithRangeStats option
+func (rs *RangeScanner) updateStats() {
+	rs.stats.Size = rs.cr.n
+	if rs.statsOut != nil {
+		*rs.statsOut = rs.stats
+	}
+}
+
+// Match returns the most recent Match generated by a call to Scan
+func (rs *RangeScanner) Match() Match {
+	return rs.match
+}
+
+// Err returns the first error that was encountered by the RangeScanner
+func (rs *RangeScanner) Err() error {
+	return rs.err
+}
+
+// Malformed returns the number of malformed lines the RangeScanner has encountered so far
+func (rs *RangeScanner) Malformed() int {
+	return rs.stats.Malformed
+}
+
+// Stats returns the statistics of the range data the RangeScanner has read so far
+func (rs *RangeScanner) Stats() RangeStats {
+	return rs.stats
+}
+
+// parseLine parses a single line of the range data into a Match
+func (rs *RangeScanner) parseLine(text string) (Match, bool) {
+	hp := strings.SplitN(strings.TrimSpace(text), ":", 2)
+	if len(hp) != 2 || hp[0] == "" {
+		return Match{}, false
+	}
+	fh := rs.prefix + strings.ToLower(hp[0])
+	if _, err := hex.DecodeString(fh); err != nil {
+		return Match{}, false
+	}
+	hc, err := strconv.ParseInt(hp[1], 10, 64)
+	if err != nil || hc < 0 {
+		return Match{}, false
+	}
+	return Match{Hash: fh, Count: hc, present: true}, true
+}
+
+// ParseRange reads the range data from the given io.Reader and calls the given function for each
+// Match. If the function returns an error, parsing stops and the error is returned. See RangeScanner
+// for details on the format and the available options
+func ParseRange(r io.Reader, prefix string, fn func(Match) error, options ...RangeScannerOption) error {
+	rs := NewRangeScanner(r, prefix, options...)
+	for rs.Scan() {
+		if err := fn(rs.Match()); err != nil {
+			return err
+		}
+	}
+	return rs.Err()
+}
+
+// countingReader is an io.Reader that counts the bytes read from the underlying io.Reader
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read satisfies the io.Reader interface for the countingReader type
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
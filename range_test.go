@@ -0,0 +1,240 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev> et al
+//
+// SPDX-License-Identifier: MIT
+
+package hibp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testRangeData is a small range response with a padding entry, an empty line and malformed lines.
+const testRangeData = "0018A45C4D1DEF81644B54AB7F969B88D65:10\r\n" +
+	"00D4F6E8FA6EECAD2A3AA415EEC418D38EC:2\r\n" +
+	"011053FD0102E94D6AE2F8B83D76FAF94F6:0\r\n" +
+	"\r\n" +
+	"not-a-valid-line\r\n" +
+	"012A7CA357541F0AC487871FEEC1891C49C:invalid\r\n" +
+	"ZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZ:3\r\n" +
+	"01330C689E5D64F660D6947A93AD634EF8F:1\r\n"
+
+func TestRangeScanner(t *testing.T) {
+	t.Run("range scanner yields valid matches and counts malformed lines", func(t *testing.T) {
+		rs := NewRangeScanner(strings.NewReader(testRangeData), "21BD1")
+		var matches []Match
+		for rs.Scan() {
+			matches = append(matches, rs.Match())
+		}
+		if err := rs.Err(); err != nil {
+			t.Fatalf("range scanner failed: %s", err)
+		}
+		if len(matches) != 3 {
+			t.Fatalf("expected %d matches, got %d", 3, len(matches))
+		}
+		if matches[0].Hash != "21bd10018a45c4d1def81644b54ab7f969b88d65" {
+			t.Errorf("unexpected hash: %s", matches[0].Hash)
+		}
+		if matches[0].Count != 10 {
+			t.Errorf("expected count to be %d, got %d", 10, matches[0].Count)
+		}
+		if !matches[0].Present() {
+			t.Error("expected match to be present")
+		}
+		if rs.Malformed() != 3 {
+			t.Errorf("expected %d malformed lines, got %d", 3, rs.Malformed())
+		}
+	})
+	t.Run("strict range scanner fails on the first malformed line", func(t *testing.T) {
+		rs := NewRangeScanner(strings.NewReader(testRangeData), "21BD1", WithStrictParsing(), nil)
+		count := 0
+		for rs.Scan() {
+			count++
+		}
+		if count != 2 {
+			t.Errorf("expected %d matches before the error, got %d", 2, count)
+		}
+		err := rs.Err()
+		if !errors.Is(err, ErrMalformedRangeLine) {
+			t.Fatalf("expected error to be %s, got %v", ErrMalformedRangeLine, err)
+		}
+		var lineErr *RangeLineError
+		if !errors.As(err, &lineErr) {
+			t.Fatalf("expected error to be a RangeLineError, got %T", err)
+		}
+		if lineErr.Line != 5 {
+			t.Errorf("expected malformed line to be %d, got %d", 5, lineErr.Line)
+		}
+		if lineErr.Text != "not-a-valid-line" {
+			t.Errorf("expected malformed line text to be %q, got %q", "not-a-valid-line", lineErr.Text)
+		}
+		if rs.Scan() {
+			t.Error("expected range scanner not to continue after an error")
+		}
+	})
+	t.Run("range scanner parses full hashes with empty prefix", func(t *testing.T) {
+		rs := NewRangeScanner(strings.NewReader(PwHashInsecure+":42\n"), "", WithStrictParsing())
+		if !rs.Scan() {
+			t.Fatalf("expected a match, got error: %v", rs.Err())
+		}
+		if rs.Match().Hash != PwHashInsecure || rs.Match().Count != 42 {
+			t.Errorf("unexpected match: %+v", rs.Match())
+		}
+	})
+	t.Run("range scanner yields padding entries and collects statistics", func(t *testing.T) {
+		data := testRangeData + "01F2DB8C3ECA6E8F4EE2B3FBFB1BCF8E6DA:0\r\n"
+		var stats RangeStats
+		rs := NewRangeScanner(strings.NewReader(data), "21BD1", WithPaddingEntries(), WithRangeStats(&stats))
+		var padding []Match
+		for rs.Scan() {
+			if rs.Match().IsPadding() {
+				padding = append(padding, rs.Match())
+			}
+		}
+		if err := rs.Err(); err != nil {
+			t.Fatalf("range scanner failed: %s", err)
+		}
+		if len(padding) != 2 {
+			t.Fatalf("expected %d padding entries, got %d", 2, len(padding))
+		}
+		if padding[0].Count != 0 || !padding[0].Present() {
+			t.Errorf("unexpected padding entry: %+v", padding[0])
+		}
+		expected := RangeStats{Real: 3, Padding: 2, Malformed: 3, Size: int64(len(data))}
+		if rs.Stats() != expected {
+			t.Errorf("expected range stats to be %+v, got %+v", expected, rs.Stats())
+		}
+		if stats != expected {
+			t.Errorf("expected WithRangeStats to hold %+v, got %+v", expected, stats)
+		}
+	})
+	t.Run("range scanner skips padding entries by default", func(t *testing.T) {
+		rs := NewRangeScanner(strings.NewReader(testRangeData), "21BD1")
+		for rs.Scan() {
+			if rs.Match().IsPadding() {
+				t.Errorf("expected padding entries to be skipped, got %+v", rs.Match())
+			}
+		}
+		if rs.Stats().Padding != 1 {
+			t.Errorf("expected %d padding entry to be counted, got %d", 1, rs.Stats().Padding)
+		}
+	})
+	t.Run("range scanner parses test data", func(t *testing.T) {
+		file, err := os.Open(ServerResponsePwnedPassInsecure)
+		if err != nil {
+			t.Fatalf("failed to open test data: %s", err)
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		rs := NewRangeScanner(file, PwHashInsecure[:5], WithStrictParsing())
+		found := false
+		for rs.Scan() {
+			if rs.Match().Hash == PwHashInsecure {
+				found = true
+			}
+		}
+		if err = rs.Err(); err != nil {
+			t.Fatalf("range scanner failed: %s", err)
+		}
+		if !found {
+			t.Error("expected insecure password hash to be found in test data")
+		}
+	})
+}
+
+func TestParseRange(t *testing.T) {
+	t.Run("parse range calls the function for each match", func(t *testing.T) {
+		count := 0
+		err := ParseRange(strings.NewReader(testRangeData), "21BD1", func(Match) error {
+			count++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("parse range failed: %s", err)
+		}
+		if count != 3 {
+			t.Errorf("expected %d matches, got %d", 3, count)
+		}
+	})
+	t.Run("parse range stops on callback error", func(t *testing.T) {
+		errStop := errors.New("stop")
+		count := 0
+		err := ParseRange(strings.NewReader(testRangeData), "21BD1", func(Match) error {
+			count++
+			return errStop
+		})
+		if !errors.Is(err, errStop) {
+			t.Errorf("expected error to be %s, got %v", errStop, err)
+		}
+		if count != 1 {
+			t.Errorf("expected parsing to stop after %d match, got %d", 1, count)
+		}
+	})
+}
+
+func TestPwnedPassAPI_StreamHashesPrefix(t *testing.T) {
+	t.Run("stream hashes yields all matches", func(t *testing.T) {
+		server := httptest.NewServer(newTestFileHandler(t, ServerResponsePwnedPassInsecure))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)))
+		count := 0
+		_, err := hc.PwnedPassAPI.StreamHashesPrefix(PwHashInsecure[:5], func(Match) error {
+			count++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("stream hashes failed: %s", err)
+		}
+		list, _, err := hc.PwnedPassAPI.ListHashesPrefix(PwHashInsecure[:5])
+		if err != nil {
+			t.Fatalf("list hashes failed: %s", err)
+		}
+		if count != len(list) {
+			t.Errorf("expected %d streamed matches, got %d", len(list), count)
+		}
+	})
+	t.Run("stream hashes fails with too short prefix", func(t *testing.T) {
+		hc := New()
+		_, err := hc.PwnedPassAPI.StreamHashesPrefix("123", func(Match) error { return nil })
+		if !errors.Is(err, ErrPrefixLengthMismatch) {
+			t.Errorf("expected error to be %s, got %v", ErrPrefixLengthMismatch, err)
+		}
+	})
+	t.Run("strict stream hashes fails on invalid response", func(t *testing.T) {
+		server := httptest.NewServer(newTestFileHandler(t, ServerResponsePwnedPassInvalid))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestClient(t, server.URL)))
+		_, err := hc.PwnedPassAPI.StreamHashesPrefix("a94a8", func(Match) error { return nil },
+			WithStrictParsing())
+		if !errors.Is(err, ErrMalformedRangeLine) {
+			t.Errorf("expected error to be %s, got %v", ErrMalformedRangeLine, err)
+		}
+	})
+	t.Run("stream hashes with hash mode leaves the client hash mode untouched", func(t *testing.T) {
+		var mode string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mode = r.URL.Query().Get("mode")
+		}))
+		defer server.Close()
+		hc := New(WithHTTPClient(newTestRouteClient(t, server.URL)))
+		_, err := hc.PwnedPassAPI.StreamHashesPrefixMode(HashModeNTLM, PwHashInsecureNTLM[:5],
+			func(Match) error { return nil })
+		if err != nil {
+			t.Fatalf("stream hashes failed: %s", err)
+		}
+		if mode != "ntlm" {
+			t.Errorf("expected request to query for NTLM hashes, got mode %q", mode)
+		}
+		if hc.PwnedPassAPIOpts.HashMode != HashModeSHA1 {
+			t.Errorf("expected hash mode of the client to be unchanged, got %d", hc.PwnedPassAPIOpts.HashMode)
+		}
+		if _, ok := hc.PwnedPassAPI.ParamMap["mode"]; ok {
+			t.Error("expected the query parameters of the client to be unchanged")
+		}
+	})
+}
@@ -5,7 +5,6 @@
 package hibp
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -48,18 +47,11 @@ type SubscriptionStatus struct {
 // Reference: https://haveibeenpwned.com/API/v3#SubscriptionStatus
 func (s *SubscriptionAPI) Status() (SubscriptionStatus, *http.Response, error) {
 	var status SubscriptionStatus
-	if err := requiresAPIKey(s.hibp); err != nil {
-		return status, nil, err
-	}
 	au := fmt.Sprintf("%s/subscription/status", BaseURL)
-	hb, hr, err := s.hibp.HTTPResBody(http.MethodGet, au, nil)
+	hr, err := s.hibp.getJSON(apiRequest{url: au, auth: true}, &status)
 	if err != nil {
 		return status, hr, err
 	}
-
-	if err = json.Unmarshal(hb, &status); err != nil {
-		return status, hr, err
-	}
 	status.present = true
 
 	return status, hr, nil